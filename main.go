@@ -7,7 +7,10 @@ import (
 	"github.com/vbehar/openshift-github-hooks/pkg/cmd"
 
 	// init all the commands
+	_ "github.com/vbehar/openshift-github-hooks/pkg/cmd/approve"
+	_ "github.com/vbehar/openshift-github-hooks/pkg/cmd/hook"
 	_ "github.com/vbehar/openshift-github-hooks/pkg/cmd/list"
+	_ "github.com/vbehar/openshift-github-hooks/pkg/cmd/relay"
 	_ "github.com/vbehar/openshift-github-hooks/pkg/cmd/sync"
 )
 
@@ -3,15 +3,49 @@ package api
 import (
 	"fmt"
 	"regexp"
+	"time"
 )
 
 // Hook is a very basic representation of a WebHook
 // that links a Github repository to an OpenShift BuildConfig
 // through the hook's TargetURL (OpenShift endpoint used to trigger a new build)
 type Hook struct {
+	// ID is the GitHub ID of the hook, or 0 if the hook does not exist (yet) on GitHub
+	ID               int
 	Enabled          bool
 	TargetURL        string
 	GithubRepository GithubRepository
+
+	// Secret is the secret used by GitHub to sign the deliveries (optional)
+	Secret string
+
+	// Inactive is true if the hook exists on GitHub, but has been deactivated
+	// (GitHub does not deliver its events)
+	Inactive bool
+
+	// Details are the informations returned by GitHub for an existing hook
+	// (nil if the hook does not exist on GitHub)
+	Details *HookDetails
+}
+
+// HookDetails are the informations returned by GitHub for an existing hook,
+// that are not required to sync it, but useful to inspect it
+type HookDetails struct {
+	Events      []string
+	ContentType string
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+
+	// LastResponse is the response of the last delivery of the hook
+	LastResponse HookResponse
+}
+
+// HookResponse is the response of a delivery of a hook
+type HookResponse struct {
+	// Code is the HTTP status code of the response (0 if there was no delivery)
+	Code    int
+	Status  string
+	Message string
 }
 
 // GithubRepository is a very basic representation of a GitHub repository
@@ -28,6 +62,49 @@ const (
 	// IgnoreAnnotation is an annotation whose boolean value
 	// is used to ignore a buildconfig
 	IgnoreAnnotation = "openshift-github-hooks-sync/ignore"
+
+	// HookIDsAnnotation is an annotation whose value is the comma-separated list
+	// of the GitHub hook IDs managed for a buildconfig
+	HookIDsAnnotation = "openshift-github-hooks-sync/hook-ids"
+
+	// RepositoryAnnotation is an annotation whose value is the GitHub repository
+	// (format "owner/name") on which the buildconfig's hooks are managed
+	RepositoryAnnotation = "openshift-github-hooks-sync/repository"
+
+	// HookURLHashAnnotation is an annotation whose value is the SHA-256 hash
+	// of the URL of the last hook applied for a buildconfig
+	// (the URL itself contains the trigger secret, so we don't store it)
+	HookURLHashAnnotation = "openshift-github-hooks-sync/hook-url-hash"
+
+	// LastSyncAnnotation is an annotation whose value is the time (RFC3339)
+	// at which the buildconfig's hooks status last changed
+	LastSyncAnnotation = "openshift-github-hooks-sync/last-sync"
+
+	// LastErrorAnnotation is an annotation whose value is the last error
+	// that happened while syncing the buildconfig's hooks
+	LastErrorAnnotation = "openshift-github-hooks-sync/last-error"
+
+	// LastErrorTimeAnnotation is an annotation whose value is the time (RFC3339)
+	// of the last error that happened while syncing the buildconfig's hooks
+	LastErrorTimeAnnotation = "openshift-github-hooks-sync/last-error-time"
+
+	// AllowMassDeletionAnnotation is an annotation whose boolean value
+	// is used (on the sync's guard configmap) to explicitly allow a blocked mass deletion of hooks
+	AllowMassDeletionAnnotation = "openshift-github-hooks-sync/allow-mass-deletion"
+
+	// GithubTeamAnnotation is an annotation (on a namespace) whose value is the slug
+	// of the GitHub team (of the organization) that owns the namespace: the buildconfigs of the namespace
+	// may only hook the repositories on which the team has the admin or write permission
+	GithubTeamAnnotation = "openshift-github-hooks-sync/github-team"
+
+	// FilterPathsAnnotation is an annotation whose boolean value is used to only trigger
+	// a buildconfig (through the relay) on the pushes that change files in its contextDir
+	FilterPathsAnnotation = "openshift-github-hooks-sync/filter-paths"
+
+	// PathsAnnotation is an annotation whose value is a comma-separated list of path globs
+	// (for example "app/**,lib/*.go") used to only trigger a buildconfig (through the relay)
+	// on the pushes that change matching files. It takes precedence over the contextDir.
+	PathsAnnotation = "openshift-github-hooks-sync/paths"
 )
 
 var (
@@ -0,0 +1,151 @@
+package approval
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/golang/glog"
+	"k8s.io/kubernetes/pkg/util/wait"
+)
+
+// Gate holds the hooks on the repositories that have never been hooked before,
+// until a cluster admin approves them
+type Gate struct {
+	// Store stores the approval requests
+	Store *Store
+
+	// HookedFunc checks if the given repository already has hooks that we manage
+	// (in which case it does not need an approval)
+	HookedFunc func(repository api.GithubRepository) (bool, error)
+
+	// known stores the repositories that don't need an approval (approved or already hooked)
+	known map[string]bool
+
+	// waiting stores the repositories waiting for an approval
+	waiting map[string]api.GithubRepository
+
+	lock sync.Mutex
+}
+
+// Allow returns true if a hook can be created on the given repository.
+// Otherwise, it records a pending approval request on behalf of the given requester,
+// and returns false.
+func (g *Gate) Allow(repository api.GithubRepository, requester string) (bool, error) {
+	allowed, err := g.IsAllowed(repository)
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	key := Key(repository)
+	if _, err := g.Store.RequestApproval(repository, requester); err != nil {
+		return false, err
+	}
+	g.lock.Lock()
+	if g.waiting == nil {
+		g.waiting = map[string]api.GithubRepository{}
+	}
+	g.waiting[key] = repository
+	g.lock.Unlock()
+
+	glog.Infof("Hook on repository %s requested by %s is pending approval", repository, requester)
+	return false, nil
+}
+
+// IsAllowed returns true if a hook can be created on the given repository
+// (approved or already hooked), without requesting an approval
+func (g *Gate) IsAllowed(repository api.GithubRepository) (bool, error) {
+	if g.isKnown(Key(repository)) {
+		return true, nil
+	}
+
+	request, err := g.Store.Get(repository)
+	if err != nil {
+		return false, err
+	}
+	if request != nil && request.Status == StatusApproved {
+		g.MarkHooked(repository)
+		return true, nil
+	}
+
+	hooked, err := g.HookedFunc(repository)
+	if err != nil {
+		return false, err
+	}
+	if hooked {
+		g.MarkHooked(repository)
+		return true, nil
+	}
+	return false, nil
+}
+
+// MarkHooked remembers that the given repositories already have hooks,
+// so that they don't need an approval
+func (g *Gate) MarkHooked(repositories ...api.GithubRepository) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.known == nil {
+		g.known = map[string]bool{}
+	}
+	for _, repository := range repositories {
+		key := Key(repository)
+		g.known[key] = true
+		delete(g.waiting, key)
+	}
+}
+
+// isKnown checks if the repository with the given key does not need an approval
+func (g *Gate) isKnown(key string) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.known[key]
+}
+
+// WatchApprovalsUntil checks the approval requests every period, until stopChan is closed,
+// and calls approvedFunc when some of the waiting repositories have been approved
+func (g *Gate) WatchApprovalsUntil(period time.Duration, approvedFunc func(), stopChan <-chan struct{}) {
+	go wait.Until(func() {
+		if g.checkApprovals() {
+			approvedFunc()
+		}
+	}, period, stopChan)
+}
+
+// checkApprovals returns true if some of the waiting repositories have been approved
+func (g *Gate) checkApprovals() bool {
+	g.lock.Lock()
+	waiting := len(g.waiting)
+	g.lock.Unlock()
+	if waiting == 0 {
+		return false
+	}
+
+	requests, err := g.Store.List()
+	if err != nil {
+		glog.Errorf("Failed to list the approval requests: %v", err)
+		return false
+	}
+
+	approved := []api.GithubRepository{}
+	g.lock.Lock()
+	for _, request := range requests {
+		if request.Status != StatusApproved {
+			continue
+		}
+		for _, repository := range g.waiting {
+			if strings.EqualFold(repository.String(), request.Repository) {
+				approved = append(approved, repository)
+			}
+		}
+	}
+	g.lock.Unlock()
+
+	if len(approved) == 0 {
+		return false
+	}
+	glog.Infof("Repositories %v have been approved", approved)
+	g.MarkHooked(approved...)
+	return true
+}
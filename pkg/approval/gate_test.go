@@ -0,0 +1,101 @@
+package approval
+
+import (
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+)
+
+func TestGateAllow(t *testing.T) {
+	tests := []struct {
+		repository      api.GithubRepository
+		approved        bool
+		hooked          bool
+		expectedAllowed bool
+		expectedPending bool
+	}{
+		// should hold a new repository
+		{
+			repository:      api.GithubRepository{Owner: "my-org", Name: "new"},
+			expectedAllowed: false,
+			expectedPending: true,
+		},
+		// should allow an already hooked repository
+		{
+			repository:      api.GithubRepository{Owner: "my-org", Name: "hooked"},
+			hooked:          true,
+			expectedAllowed: true,
+		},
+		// should allow an approved repository
+		{
+			repository:      api.GithubRepository{Owner: "my-org", Name: "approved"},
+			approved:        true,
+			expectedAllowed: true,
+		},
+	}
+
+	for count, test := range tests {
+		store := NewStore(&fakeConfigMaps{}, "ns", DefaultConfigMapName)
+		if test.approved {
+			store.Approve(test.repository, "admin")
+		}
+		hooked := test.hooked
+		gate := &Gate{
+			Store: store,
+			HookedFunc: func(repository api.GithubRepository) (bool, error) {
+				return hooked, nil
+			},
+		}
+
+		// checking does not request an approval
+		if allowed, err := gate.IsAllowed(test.repository); err != nil || allowed != test.expectedAllowed {
+			t.Errorf("Test[%d] Failed: Expected IsAllowed '%v' but got '%v' (error: %v)", count, test.expectedAllowed, allowed, err)
+		}
+		if request, _ := store.Get(test.repository); request != nil && request.Status == StatusPending {
+			t.Errorf("Test[%d] Failed: Expected no pending request after IsAllowed, but got %+v", count, request)
+		}
+
+		allowed, err := gate.Allow(test.repository, "ns/bc")
+		if err != nil {
+			t.Errorf("Test[%d] Failed: %v", count, err)
+			continue
+		}
+		if allowed != test.expectedAllowed {
+			t.Errorf("Test[%d] Failed: Expected allowed '%v' but got '%v'", count, test.expectedAllowed, allowed)
+		}
+		request, _ := store.Get(test.repository)
+		pending := request != nil && request.Status == StatusPending
+		if pending != test.expectedPending {
+			t.Errorf("Test[%d] Failed: Expected pending '%v' but got '%v'", count, test.expectedPending, pending)
+		}
+	}
+}
+
+func TestGateCheckApprovals(t *testing.T) {
+	repository := api.GithubRepository{Owner: "my-org", Name: "new"}
+	store := NewStore(&fakeConfigMaps{}, "ns", DefaultConfigMapName)
+	gate := &Gate{
+		Store: store,
+		HookedFunc: func(repository api.GithubRepository) (bool, error) {
+			return false, nil
+		},
+	}
+
+	if allowed, _ := gate.Allow(repository, "ns/bc"); allowed {
+		t.Fatalf("Expected the repository %s to be held", repository)
+	}
+	if gate.checkApprovals() {
+		t.Errorf("Expected no approved repository")
+	}
+
+	store.Approve(repository, "admin")
+	if !gate.checkApprovals() {
+		t.Errorf("Expected the repository %s to be approved", repository)
+	}
+	if gate.checkApprovals() {
+		t.Errorf("Expected the approved repository to be checked only once")
+	}
+	if allowed, _ := gate.Allow(repository, "ns/bc"); !allowed {
+		t.Errorf("Expected the repository %s to be allowed", repository)
+	}
+}
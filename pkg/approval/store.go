@@ -0,0 +1,220 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// DefaultConfigMapName is the default name of the configmap storing the approval requests
+const DefaultConfigMapName = "openshift-github-hooks-sync-approvals"
+
+// maxConflictRetries is the number of times an update of the configmap is retried on conflict
+const maxConflictRetries = 5
+
+// Status is the status of an approval request
+type Status string
+
+const (
+	// StatusPending is the status of a request waiting for an approval
+	StatusPending Status = "pending"
+
+	// StatusApproved is the status of an approved request
+	StatusApproved Status = "approved"
+)
+
+// Request is a request to hook a repository that has never been hooked before
+type Request struct {
+	// Repository is the repository to hook ("owner/name" format)
+	Repository string `json:"repository"`
+
+	Status Status `json:"status"`
+
+	// Requesters are the BuildConfigs ("namespace/name" format) that reference the repository
+	Requesters []string `json:"requesters,omitempty"`
+
+	RequestedAt time.Time  `json:"requestedAt"`
+	ApprovedBy  string     `json:"approvedBy,omitempty"`
+	ApprovedAt  *time.Time `json:"approvedAt,omitempty"`
+}
+
+// Store stores the approval requests in a configmap, with one entry per repository
+type Store struct {
+	client    kclient.ConfigMapsNamespacer
+	namespace string
+	name      string
+}
+
+// NewStore instantiates a new Store using the given configmap
+func NewStore(client kclient.ConfigMapsNamespacer, namespace, name string) *Store {
+	return &Store{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Key returns the key of the given repository in the configmap
+// (GitHub owners can't contain dots, so the first dot separates the owner from the name)
+func Key(repository api.GithubRepository) string {
+	return strings.ToLower(fmt.Sprintf("%s.%s", repository.Owner, repository.Name))
+}
+
+// List returns all the requests, sorted by repository
+func (s *Store) List() ([]Request, error) {
+	configMap, err := s.client.ConfigMaps(s.namespace).Get(s.name)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return []Request{}, nil
+		}
+		return nil, err
+	}
+
+	keys := []string{}
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	requests := []Request{}
+	for _, key := range keys {
+		request, err := decode(configMap.Data[key])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid approval request %s in configmap %s/%s: %v", key, s.namespace, s.name, err)
+		}
+		requests = append(requests, *request)
+	}
+	return requests, nil
+}
+
+// Get returns the request for the given repository, or nil if there is none
+func (s *Store) Get(repository api.GithubRepository) (*Request, error) {
+	configMap, err := s.client.ConfigMaps(s.namespace).Get(s.name)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, found := configMap.Data[Key(repository)]
+	if !found {
+		return nil, nil
+	}
+	return decode(data)
+}
+
+// RequestApproval records a pending request for the given repository, on behalf of the given requester.
+// If there is already a request, the requester is added to it.
+// It returns the (new or existing) request.
+func (s *Store) RequestApproval(repository api.GithubRepository, requester string) (*Request, error) {
+	return s.update(repository, func(request *Request) *Request {
+		if request == nil {
+			request = &Request{
+				Repository:  repository.String(),
+				Status:      StatusPending,
+				RequestedAt: time.Now().UTC(),
+			}
+		}
+		for _, existing := range request.Requesters {
+			if existing == requester {
+				return request
+			}
+		}
+		request.Requesters = append(request.Requesters, requester)
+		sort.Strings(request.Requesters)
+		return request
+	})
+}
+
+// Approve approves the request for the given repository.
+// A repository can be approved before it has been requested.
+func (s *Store) Approve(repository api.GithubRepository, approvedBy string) (*Request, error) {
+	return s.update(repository, func(request *Request) *Request {
+		now := time.Now().UTC()
+		if request == nil {
+			request = &Request{
+				Repository:  repository.String(),
+				RequestedAt: now,
+			}
+		}
+		if request.Status != StatusApproved {
+			request.Status = StatusApproved
+			request.ApprovedBy = approvedBy
+			request.ApprovedAt = &now
+		}
+		return request
+	})
+}
+
+// update applies the given change to the request of the given repository,
+// creating the configmap if needed, and retrying on conflicts
+func (s *Store) update(repository api.GithubRepository, change func(request *Request) *Request) (*Request, error) {
+	key := Key(repository)
+	var lastErr error
+	for i := 0; i < maxConflictRetries; i++ {
+		configMap, err := s.client.ConfigMaps(s.namespace).Get(s.name)
+		create := false
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				return nil, err
+			}
+			create = true
+			configMap = &kapi.ConfigMap{
+				ObjectMeta: kapi.ObjectMeta{
+					Namespace: s.namespace,
+					Name:      s.name,
+				},
+			}
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+
+		var request *Request
+		if data, found := configMap.Data[key]; found {
+			if request, err = decode(data); err != nil {
+				return nil, fmt.Errorf("Invalid approval request %s in configmap %s/%s: %v", key, s.namespace, s.name, err)
+			}
+		}
+		request = change(request)
+		data, err := json.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		if existing, found := configMap.Data[key]; found && existing == string(data) {
+			return request, nil
+		}
+		configMap.Data[key] = string(data)
+
+		if create {
+			_, err = s.client.ConfigMaps(s.namespace).Create(configMap)
+		} else {
+			_, err = s.client.ConfigMaps(s.namespace).Update(configMap)
+		}
+		if err == nil {
+			return request, nil
+		}
+		if !kerrors.IsConflict(err) && !kerrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// decode decodes the given request
+func decode(data string) (*Request, error) {
+	request := &Request{}
+	if err := json.Unmarshal([]byte(data), request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
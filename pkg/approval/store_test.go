@@ -0,0 +1,105 @@
+package approval
+
+import (
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// fakeConfigMaps is a kclient.ConfigMapsNamespacer that stores a single configmap in memory
+type fakeConfigMaps struct {
+	kclient.ConfigMapsInterface
+	configMap *kapi.ConfigMap
+	updates   int
+}
+
+func (f *fakeConfigMaps) ConfigMaps(namespace string) kclient.ConfigMapsInterface {
+	return f
+}
+
+func (f *fakeConfigMaps) Get(name string) (*kapi.ConfigMap, error) {
+	if f.configMap == nil {
+		return nil, kerrors.NewNotFound(kapi.Resource("configmaps"), name)
+	}
+	copied := *f.configMap
+	copied.Data = map[string]string{}
+	for key, value := range f.configMap.Data {
+		copied.Data[key] = value
+	}
+	return &copied, nil
+}
+
+func (f *fakeConfigMaps) Create(configMap *kapi.ConfigMap) (*kapi.ConfigMap, error) {
+	f.configMap = configMap
+	f.updates++
+	return configMap, nil
+}
+
+func (f *fakeConfigMaps) Update(configMap *kapi.ConfigMap) (*kapi.ConfigMap, error) {
+	f.configMap = configMap
+	f.updates++
+	return configMap, nil
+}
+
+func TestStore(t *testing.T) {
+	repository := api.GithubRepository{Owner: "my-org", Name: "my-repo"}
+	client := &fakeConfigMaps{}
+	store := NewStore(client, "ns", DefaultConfigMapName)
+
+	request, err := store.Get(repository)
+	if err != nil || request != nil {
+		t.Fatalf("Expected no request but got %v (error %v)", request, err)
+	}
+
+	if _, err := store.RequestApproval(repository, "ns1/bc1"); err != nil {
+		t.Fatalf("Failed to request approval: %v", err)
+	}
+	if _, err := store.RequestApproval(repository, "ns2/bc2"); err != nil {
+		t.Fatalf("Failed to request approval: %v", err)
+	}
+	// the same requester should not update the configmap
+	if _, err := store.RequestApproval(repository, "ns1/bc1"); err != nil {
+		t.Fatalf("Failed to request approval: %v", err)
+	}
+	if client.updates != 2 {
+		t.Errorf("Expected 2 updates of the configmap but got %d", client.updates)
+	}
+
+	request, err = store.Get(repository)
+	if err != nil || request == nil {
+		t.Fatalf("Expected a request but got %v (error %v)", request, err)
+	}
+	if request.Status != StatusPending {
+		t.Errorf("Expected status %s but got %s", StatusPending, request.Status)
+	}
+	if len(request.Requesters) != 2 || request.Requesters[0] != "ns1/bc1" || request.Requesters[1] != "ns2/bc2" {
+		t.Errorf("Expected requesters [ns1/bc1 ns2/bc2] but got %v", request.Requesters)
+	}
+
+	if _, err := store.Approve(api.GithubRepository{Owner: "My-Org", Name: "My-Repo"}, "admin"); err != nil {
+		t.Fatalf("Failed to approve: %v", err)
+	}
+	if _, err := store.Approve(api.GithubRepository{Owner: "my-org", Name: "other-repo"}, "admin"); err != nil {
+		t.Fatalf("Failed to approve: %v", err)
+	}
+
+	requests, err := store.List()
+	if err != nil {
+		t.Fatalf("Failed to list the requests: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests but got %v", requests)
+	}
+	for count, request := range requests {
+		if request.Status != StatusApproved || request.ApprovedBy != "admin" || request.ApprovedAt == nil {
+			t.Errorf("Test[%d] Failed: Expected request %s to be approved by admin but got %+v", count, request.Repository, request)
+		}
+	}
+	if requests[0].Repository != "my-org/my-repo" || len(requests[0].Requesters) != 2 {
+		t.Errorf("Expected the approval to keep the pending request of my-org/my-repo but got %+v", requests[0])
+	}
+}
@@ -0,0 +1,194 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+)
+
+// Category is the category of an inconsistency between GitHub and OpenShift
+type Category string
+
+const (
+	// OrphanHook is a hook that targets a BuildConfig that does not exist (or that is not synced anymore)
+	OrphanHook Category = "orphan-hook"
+
+	// MissingHook is a BuildConfig with a GitHub trigger, but no hook on its repository
+	MissingHook Category = "missing-hook"
+
+	// StaleSecret is a hook whose URL contains a secret that is not the secret of the BuildConfig trigger anymore
+	StaleSecret Category = "stale-secret"
+
+	// DuplicateHook is a hook that targets the same BuildConfig as another hook of the same repository
+	DuplicateHook Category = "duplicate-hook"
+
+	// ExternalRepository is a BuildConfig that hooks a repository outside of the organization
+	// (its hook is not managed)
+	ExternalRepository Category = "external-repository"
+
+	// DisabledHook is a hook that has been deactivated on GitHub
+	DisabledHook Category = "disabled-hook"
+)
+
+// Finding is a single inconsistency between GitHub and OpenShift
+type Finding struct {
+	Category    Category `json:"category"`
+	Repository  string   `json:"repository"`
+	BuildConfig string   `json:"buildConfig,omitempty"`
+	HookID      int      `json:"hookID,omitempty"`
+	Message     string   `json:"message"`
+}
+
+// Auditor cross-references the hooks that exist on GitHub with the BuildConfigs
+type Auditor struct {
+	// Organization is the GitHub organization whose hooks are managed
+	Organization string
+
+	// KeyFunc returns the key ("namespace/name" format) of the BuildConfig targeted by a hook,
+	// or an error if the hook is not managed by us
+	KeyFunc func(hook api.Hook) (string, error)
+
+	// BuildConfigExistsFunc returns true if the BuildConfig with the given key exists
+	BuildConfigExistsFunc func(key string) bool
+
+	// Unmanaged contains the keys of the BuildConfigs that exist but are not managed
+	// (for example ignored): their hooks are left untouched, so they are not reported
+	Unmanaged map[string]bool
+}
+
+// Audit returns the inconsistencies between the desired hooks (from the BuildConfigs)
+// and the actual hooks (on GitHub), sorted by category, repository and BuildConfig
+func (a *Auditor) Audit(desired, actual []api.Hook) []Finding {
+	findings := []Finding{}
+
+	desiredByKey := map[string]api.Hook{}
+	for _, hook := range desired {
+		key, err := a.KeyFunc(hook)
+		if err != nil {
+			continue
+		}
+		if !strings.EqualFold(hook.GithubRepository.Owner, a.Organization) {
+			findings = append(findings, newFinding(ExternalRepository, hook, key,
+				"The BuildConfig %s hooks the repository %s outside of the organization %s: its hook is not managed", key, hook.GithubRepository, a.Organization))
+			continue
+		}
+		desiredByKey[key] = hook
+	}
+
+	actualByKey := map[string][]api.Hook{}
+	for _, hook := range actual {
+		key, err := a.KeyFunc(hook)
+		if err != nil {
+			continue
+		}
+		if hook.Inactive {
+			findings = append(findings, newFinding(DisabledHook, hook, key,
+				"The hook %d targeting the BuildConfig %s has been deactivated on GitHub", hook.ID, key))
+		}
+		if a.Unmanaged[key] {
+			continue
+		}
+		actualByKey[key] = append(actualByKey[key], hook)
+	}
+
+	for key, hooks := range actualByKey {
+		desiredHook, found := desiredByKey[key]
+		if !found {
+			reason := "does not exist"
+			if a.BuildConfigExistsFunc != nil && a.BuildConfigExistsFunc(key) {
+				reason = "is not synced (no GitHub trigger, or not allowed by the policy)"
+			}
+			for _, hook := range hooks {
+				findings = append(findings, newFinding(OrphanHook, hook, key,
+					"The hook %d targets the BuildConfig %s that %s", hook.ID, key, reason))
+			}
+			continue
+		}
+
+		sameRepository := []api.Hook{}
+		for _, hook := range hooks {
+			if hook.GithubRepository == desiredHook.GithubRepository {
+				sameRepository = append(sameRepository, hook)
+			} else {
+				findings = append(findings, newFinding(OrphanHook, hook, key,
+					"The hook %d targets the BuildConfig %s whose repository is now %s", hook.ID, key, desiredHook.GithubRepository))
+			}
+		}
+		if len(sameRepository) == 0 {
+			findings = append(findings, newFinding(MissingHook, desiredHook, key,
+				"The BuildConfig %s has a GitHub trigger, but no hook on %s", key, desiredHook.GithubRepository))
+			continue
+		}
+
+		// keep the hook with the right URL (or the oldest one), the others are duplicates
+		sort.Sort(byID(sameRepository))
+		kept := 0
+		for i, hook := range sameRepository {
+			if hook.TargetURL == desiredHook.TargetURL {
+				kept = i
+				break
+			}
+		}
+		for i, hook := range sameRepository {
+			if i != kept {
+				findings = append(findings, newFinding(DuplicateHook, hook, key,
+					"The hook %d targets the BuildConfig %s, like the hook %d", hook.ID, key, sameRepository[kept].ID))
+			}
+		}
+
+		_, _, actualSecret := openshift.ExplodeOpenshiftWebhookURL(sameRepository[kept].TargetURL)
+		_, _, desiredSecret := openshift.ExplodeOpenshiftWebhookURL(desiredHook.TargetURL)
+		if len(actualSecret) > 0 && len(desiredSecret) > 0 && actualSecret != desiredSecret {
+			findings = append(findings, newFinding(StaleSecret, sameRepository[kept], key,
+				"The hook %d does not use the current secret of the GitHub trigger of the BuildConfig %s", sameRepository[kept].ID, key))
+		}
+	}
+
+	for key, hook := range desiredByKey {
+		if _, found := actualByKey[key]; !found {
+			findings = append(findings, newFinding(MissingHook, hook, key,
+				"The BuildConfig %s has a GitHub trigger, but no hook on %s", key, hook.GithubRepository))
+		}
+	}
+
+	sort.Sort(byCategory(findings))
+	return findings
+}
+
+// newFinding instantiates a new Finding for the given hook
+func newFinding(category Category, hook api.Hook, key string, format string, args ...interface{}) Finding {
+	return Finding{
+		Category:    category,
+		Repository:  hook.GithubRepository.String(),
+		BuildConfig: key,
+		HookID:      hook.ID,
+		Message:     fmt.Sprintf(format, args...),
+	}
+}
+
+// byID sorts the hooks by their ID
+type byID []api.Hook
+
+func (h byID) Len() int           { return len(h) }
+func (h byID) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h byID) Less(i, j int) bool { return h[i].ID < h[j].ID }
+
+// byCategory sorts the findings by category, repository, BuildConfig and hook ID
+type byCategory []Finding
+
+func (f byCategory) Len() int      { return len(f) }
+func (f byCategory) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f byCategory) Less(i, j int) bool {
+	switch {
+	case f[i].Category != f[j].Category:
+		return f[i].Category < f[j].Category
+	case f[i].Repository != f[j].Repository:
+		return f[i].Repository < f[j].Repository
+	case f[i].BuildConfig != f[j].BuildConfig:
+		return f[i].BuildConfig < f[j].BuildConfig
+	}
+	return f[i].HookID < f[j].HookID
+}
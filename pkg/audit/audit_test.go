@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+)
+
+func testKeyFunc(hook api.Hook) (string, error) {
+	ns, bc, _ := openshift.ExplodeOpenshiftWebhookURL(hook.TargetURL)
+	if len(ns) == 0 || len(bc) == 0 {
+		return "", fmt.Errorf("unmanaged hook %s", hook.TargetURL)
+	}
+	return ns + "/" + bc, nil
+}
+
+func testHookURL(bc, secret string) string {
+	return fmt.Sprintf("https://openshift/oapi/v1/namespaces/ns/buildconfigs/%s/webhooks/%s/github", bc, secret)
+}
+
+func TestAudit(t *testing.T) {
+	repo := api.GithubRepository{Owner: "my-org", Name: "repo"}
+	otherRepo := api.GithubRepository{Owner: "my-org", Name: "other"}
+	externalRepo := api.GithubRepository{Owner: "someone", Name: "repo"}
+
+	desired := []api.Hook{
+		{Enabled: true, TargetURL: testHookURL("ok", "secret"), GithubRepository: repo},
+		{Enabled: true, TargetURL: testHookURL("missing", "secret"), GithubRepository: repo},
+		{Enabled: true, TargetURL: testHookURL("stale", "new-secret"), GithubRepository: repo},
+		{Enabled: true, TargetURL: testHookURL("duplicated", "secret"), GithubRepository: repo},
+		{Enabled: true, TargetURL: testHookURL("moved", "secret"), GithubRepository: otherRepo},
+		{Enabled: true, TargetURL: testHookURL("external", "secret"), GithubRepository: externalRepo},
+	}
+	actual := []api.Hook{
+		{ID: 1, Enabled: true, TargetURL: testHookURL("ok", "secret"), GithubRepository: repo},
+		{ID: 2, Enabled: true, TargetURL: testHookURL("stale", "old-secret"), GithubRepository: repo},
+		{ID: 3, Enabled: true, TargetURL: testHookURL("duplicated", "old-secret"), GithubRepository: repo},
+		{ID: 4, Enabled: true, TargetURL: testHookURL("duplicated", "secret"), GithubRepository: repo},
+		{ID: 5, Enabled: true, TargetURL: testHookURL("moved", "secret"), GithubRepository: repo},
+		{ID: 6, Enabled: true, TargetURL: testHookURL("deleted", "secret"), GithubRepository: repo},
+		{ID: 7, Enabled: true, TargetURL: testHookURL("ignored", "secret"), GithubRepository: repo, Inactive: true},
+		{ID: 8, Enabled: true, TargetURL: "https://ci.example.com/hook", GithubRepository: repo},
+		{ID: 9, Enabled: true, TargetURL: testHookURL("unmanaged", "secret"), GithubRepository: repo},
+	}
+
+	auditor := &Auditor{
+		Organization: "My-Org",
+		KeyFunc:      testKeyFunc,
+		BuildConfigExistsFunc: func(key string) bool {
+			return key == "ns/ignored" || key == "ns/unmanaged"
+		},
+		Unmanaged: map[string]bool{"ns/unmanaged": true},
+	}
+	findings := auditor.Audit(desired, actual)
+
+	expected := []struct {
+		category    Category
+		buildConfig string
+		hookID      int
+	}{
+		{category: DisabledHook, buildConfig: "ns/ignored", hookID: 7},
+		{category: DuplicateHook, buildConfig: "ns/duplicated", hookID: 3},
+		{category: ExternalRepository, buildConfig: "ns/external"},
+		{category: MissingHook, buildConfig: "ns/moved"},
+		{category: MissingHook, buildConfig: "ns/missing"},
+		{category: OrphanHook, buildConfig: "ns/deleted", hookID: 6},
+		{category: OrphanHook, buildConfig: "ns/ignored", hookID: 7},
+		{category: OrphanHook, buildConfig: "ns/moved", hookID: 5},
+		{category: StaleSecret, buildConfig: "ns/stale", hookID: 2},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings but got %d: %+v", len(expected), len(findings), findings)
+	}
+	for count, test := range expected {
+		finding := findings[count]
+		if finding.Category != test.category || finding.BuildConfig != test.buildConfig || finding.HookID != test.hookID {
+			t.Errorf("Test[%d] Failed: Expected %s for %s (hook %d) but got %+v", count, test.category, test.buildConfig, test.hookID, finding)
+		}
+	}
+}
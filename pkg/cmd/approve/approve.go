@@ -0,0 +1,86 @@
+package approve
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/approval"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+
+	"github.com/golang/glog"
+)
+
+// listPending prints the approval requests
+func listPending(options *Options) {
+	_, kclient, err := openshift.Factory.Clients()
+	if err != nil {
+		glog.Fatalf("Failed to get OpenShift client: %v", err)
+	}
+
+	requests, err := approval.NewStore(kclient, options.Namespace, options.Name).List()
+	if err != nil {
+		glog.Fatalf("Failed to list the approval requests: %v", err)
+	}
+
+	w := &tabwriter.Writer{}
+	w.Init(os.Stdout, 10, 4, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s", "REPOSITORY", "REQUESTED BY", "REQUESTED AT")
+	if options.All {
+		fmt.Fprintf(w, "\t%s\t%s", "STATUS", "APPROVED BY")
+	}
+	fmt.Fprintln(w)
+
+	for _, request := range requests {
+		if request.Status != approval.StatusPending && !options.All {
+			continue
+		}
+		requesters := strings.Join(request.Requesters, ",")
+		if len(requesters) == 0 {
+			requesters = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s", request.Repository, requesters, request.RequestedAt.Format(time.RFC3339))
+		if options.All {
+			fmt.Fprintf(w, "\t%s\t%s", request.Status, request.ApprovedBy)
+		}
+		fmt.Fprintln(w)
+	}
+
+	w.Flush()
+}
+
+// approve approves the given repositories ("owner/name" format)
+func approve(options *Options, repositories []string) {
+	oclient, kclient, err := openshift.Factory.Clients()
+	if err != nil {
+		glog.Fatalf("Failed to get OpenShift client: %v", err)
+	}
+
+	approvedBy := options.ApprovedBy
+	if len(approvedBy) == 0 {
+		user, err := oclient.Users().Get("~")
+		if err != nil {
+			glog.Fatalf("Failed to get the current OpenShift user (use the --approved-by flag to set the approver): %v", err)
+		}
+		approvedBy = user.Name
+	}
+
+	store := approval.NewStore(kclient, options.Namespace, options.Name)
+	for _, name := range repositories {
+		parts := strings.Split(name, "/")
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			glog.Fatalf("Invalid repository %s: expected the owner/name format", name)
+		}
+		repository := api.GithubRepository{
+			Owner: parts[0],
+			Name:  parts[1],
+		}
+		if _, err := store.Approve(repository, approvedBy); err != nil {
+			glog.Fatalf("Failed to approve the repository %s: %v", repository, err)
+		}
+		fmt.Printf("Approved hooks on repository %s\n", repository)
+	}
+}
@@ -0,0 +1,97 @@
+package approve
+
+import (
+	"fmt"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/approval"
+	"github.com/vbehar/openshift-github-hooks/pkg/cmd"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Options represents the commands' options
+type Options struct {
+	Namespace  string
+	Name       string
+	ApprovedBy string
+	All        bool
+}
+
+var (
+	pendingCmdExample = `
+	# List the repositories waiting for an approval
+	$ %[1]s
+
+	# List all the approval requests, including the approved ones
+	$ %[1]s --all`
+
+	approveCmdExample = `
+	# Approve the hooks on the "my-org/some-repository" repository
+	$ %[1]s my-org/some-repository
+
+	# Approve the hooks on multiple repositories, before they are requested
+	$ %[1]s my-org/some-repository my-org/another-repository`
+
+	pendingCmd = &cobra.Command{
+		Use:   "pending",
+		Short: "List the GitHub repositories waiting for an approval before being hooked",
+		Long: `
+The pending command lists the GitHub repositories that have never been hooked before,
+and on which the sync command is waiting for an approval before creating hooks
+(when it has been started with the --require-approval flag).
+
+The approval requests are stored in a configmap, in the namespace of the sync command.`,
+		Run: func(command *cobra.Command, args []string) {
+			listPending(options)
+		},
+	}
+
+	approveCmd = &cobra.Command{
+		Use:   "approve OWNER/REPOSITORY...",
+		Short: "Approve the creation of GitHub hooks on repositories that have never been hooked before",
+		Long: `
+The approve command approves the creation of hooks on GitHub repositories that have never been hooked before,
+when the sync command has been started with the --require-approval flag.
+Once approved, the sync command creates the hooks on its next check of the approval requests.
+
+A repository can also be approved before being requested.
+The approval is recorded with the name of the current OpenShift user (or the --approved-by flag).`,
+		PreRunE: func(command *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("No repository to approve. Please provide at least one, in the owner/name format.")
+			}
+			return nil
+		},
+		Run: func(command *cobra.Command, args []string) {
+			approve(options, args)
+		},
+	}
+
+	options = &Options{}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(pendingCmd)
+	cmd.RootCmd.AddCommand(approveCmd)
+
+	pendingCmd.Example = fmt.Sprintf(pendingCmdExample, cmd.FullName(pendingCmd))
+	approveCmd.Example = fmt.Sprintf(approveCmdExample, cmd.FullName(approveCmd))
+
+	flags := pflag.NewFlagSet("approvals", pflag.ContinueOnError)
+	flags.StringVar(&options.Namespace, "approvals-namespace", cmd.GetenvWithDefault("POD_NAMESPACE", "default"),
+		"The namespace of the configmap storing the approval requests - could also be defined by the POD_NAMESPACE env var.")
+	flags.StringVar(&options.Name, "approvals-configmap", approval.DefaultConfigMapName,
+		"The name of the configmap storing the approval requests.")
+
+	pendingCmd.Flags().AddFlagSet(openshift.Flags)
+	pendingCmd.Flags().AddFlagSet(flags)
+	pendingCmd.Flags().BoolVar(&options.All, "all", false,
+		"List all the approval requests, including the approved ones.")
+
+	approveCmd.Flags().AddFlagSet(openshift.Flags)
+	approveCmd.Flags().AddFlagSet(flags)
+	approveCmd.Flags().StringVar(&options.ApprovedBy, "approved-by", "",
+		"The name recorded as the approver. Default to the name of the current OpenShift user.")
+}
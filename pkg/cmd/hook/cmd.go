@@ -0,0 +1,133 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/cmd"
+	"github.com/vbehar/openshift-github-hooks/pkg/github"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+
+	"github.com/spf13/cobra"
+)
+
+// Options represents the commands' options
+type Options struct {
+	GithubBaseURL            string
+	GithubInsecureSkipVerify bool
+	GithubTimeouts           github.Timeouts
+	Token                    string
+	OpenshiftPublicURL       string
+	WebhookAPIPath           string
+	WebhookURL               openshift.WebhookURLOptions
+	RelayURL                 string
+	RelayKey                 string
+	SecretReferences         bool
+	DryRun                   bool
+}
+
+var (
+	createCmdExample = `
+	# Create the GitHub hook of the "mybc" BuildConfig in the "myproj" project
+	$ %[1]s -n myproj mybc --github-token=...
+
+	# Show the hook that would be created, without creating it
+	$ %[1]s -n myproj mybc --github-token=... --dry-run`
+
+	deleteCmdExample = `
+	# Delete the GitHub hook of the "mybc" BuildConfig in the "myproj" project
+	$ %[1]s -n myproj mybc --github-token=...`
+
+	hookCmd = &cobra.Command{
+		Use:   "hook",
+		Short: "Manage the GitHub hook of a single BuildConfig",
+		Long: `
+The hook command manages the GitHub hook of a single BuildConfig, without the sync daemon:
+for example when the daemon is not running, or for a BuildConfig with the ignore annotation.
+
+The hook is computed like the sync command does, so it accepts the same flags to generate the Webhooks URLs.
+It uses the GitHub API, so it needs a GitHub Token with the "admin:repo_hook" scope.
+It can be set either with the --github-token flag, or the GITHUB_ACCESS_TOKEN environment variable.`,
+		PersistentPreRunE: func(command *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Please provide the name of a single BuildConfig (and its project with the -n flag).")
+			}
+			if len(options.Token) == 0 {
+				return fmt.Errorf("Empty GitHub Access Token. Please provide one either with the --github-token flag or the GITHUB_ACCESS_TOKEN environment variable.")
+			}
+			if len(options.RelayURL) > 0 && len(options.RelayKey) == 0 {
+				return fmt.Errorf("Empty relay key. Please provide one either with the --relay-key flag or the RELAY_KEY environment variable.")
+			}
+			switch options.WebhookAPIPath {
+			case "", openshift.LegacyWebhookAPIPath, openshift.GroupWebhookAPIPath:
+			default:
+				return fmt.Errorf("Invalid webhook API path %s. Please use either %s or %s.", options.WebhookAPIPath, openshift.LegacyWebhookAPIPath, openshift.GroupWebhookAPIPath)
+			}
+			return nil
+		},
+	}
+
+	createCmd = &cobra.Command{
+		Use:   "create BUILDCONFIG",
+		Short: "Create the GitHub hook of a BuildConfig",
+		Long: `
+The create command creates the GitHub hook of a BuildConfig (or updates the existing hook
+targeting the same endpoint), and prints its ID.`,
+		Run: func(command *cobra.Command, args []string) {
+			createHook(options, args[0])
+		},
+	}
+
+	deleteCmd = &cobra.Command{
+		Use:   "delete BUILDCONFIG",
+		Short: "Delete the GitHub hook of a BuildConfig",
+		Long: `
+The delete command deletes the GitHub hooks targeting a BuildConfig from its repository, and prints their IDs.`,
+		Run: func(command *cobra.Command, args []string) {
+			deleteHook(options, args[0])
+		},
+	}
+
+	options = &Options{}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(hookCmd)
+	hookCmd.AddCommand(createCmd)
+	hookCmd.AddCommand(deleteCmd)
+
+	createCmd.Example = fmt.Sprintf(createCmdExample, cmd.FullName(createCmd))
+	deleteCmd.Example = fmt.Sprintf(deleteCmdExample, cmd.FullName(deleteCmd))
+
+	hookCmd.PersistentFlags().AddFlagSet(openshift.Flags)
+
+	hookCmd.PersistentFlags().StringVar(&options.GithubBaseURL, "github-base-url", cmd.GetenvWithDefault("GITHUB_BASE_URL", "https://api.github.com/"),
+		"The GitHub Base URL - if you use GitHub Enterprise. Could also be defined by the GITHUB_BASE_URL env var. Format: https://github.domain.tld/api/v3/")
+	hookCmd.PersistentFlags().BoolVar(&options.GithubInsecureSkipVerify, "github-insecure-skip-tls-verify", false,
+		"If true, the github server's certificate will not be checked for validity. This will make your HTTPS connections insecure.")
+	hookCmd.PersistentFlags().DurationVar(&options.GithubTimeouts.Request, "github-request-timeout", 30*time.Second,
+		"The timeout of a single request to the GitHub API. 0 means no timeout.")
+	hookCmd.PersistentFlags().DurationVar(&options.GithubTimeouts.Operation, "github-operation-timeout", 10*time.Minute,
+		"The timeout of an operation on the GitHub API (creating a hook, listing all the hooks of a repository, ...), which may perform multiple requests. 0 means no timeout.")
+	hookCmd.PersistentFlags().StringVar(&options.Token, "github-token", os.Getenv("GITHUB_ACCESS_TOKEN"),
+		"The GitHub Access Token - could also be defined by the GITHUB_ACCESS_TOKEN env var. See https://github.com/settings/tokens to get one.")
+	hookCmd.PersistentFlags().StringVar(&options.OpenshiftPublicURL, "openshift-public-url", openshift.DefaultOpenshiftPublicURL(),
+		"The public URL of your OpenShift Master, used to generate the Webhooks URLs.")
+	hookCmd.PersistentFlags().StringVar(&options.WebhookURL.BaseURL, "webhook-base-url", "",
+		"An external base URL reachable by GitHub (a router or ingress host), used instead of the OpenShift public URL to generate the Webhooks URLs.")
+	hookCmd.PersistentFlags().StringVar(&options.WebhookURL.Route, "webhook-route", "",
+		"The namespace/name of a Route whose host is used instead of the OpenShift public URL to generate the Webhooks URLs. Ignored if --webhook-base-url is set.")
+	hookCmd.PersistentFlags().StringVar(&options.WebhookURL.PathPrefix, "webhook-path-prefix", "",
+		"A path prefix added after the base URL of the Webhooks URLs, if the router or proxy exposes the OpenShift API under a sub-path.")
+	hookCmd.PersistentFlags().StringVar(&options.WebhookAPIPath, "webhook-api-path", "",
+		fmt.Sprintf("The API path of the generated Webhooks URLs: %s (legacy) or %s (API group). Default to the path generated by the OpenShift client.", openshift.LegacyWebhookAPIPath, openshift.GroupWebhookAPIPath))
+	hookCmd.PersistentFlags().StringVar(&options.RelayURL, "relay-url", "",
+		"The public URL of the relay (see the relay command). If set, the hook targets the relay instead of OpenShift.")
+	hookCmd.PersistentFlags().StringVar(&options.RelayKey, "relay-key", os.Getenv("RELAY_KEY"),
+		"The relay key, used to derive the secret of the hook targeting the relay - could also be defined by the RELAY_KEY env var.")
+	hookCmd.PersistentFlags().BoolVar(&options.SecretReferences, "secret-references", false,
+		fmt.Sprintf("Support the GitHub triggers that reference their secret from a Secret object (in its %s key) instead of setting it inline. Requires the permission to read the Secrets.", openshift.WebHookSecretKey))
+	hookCmd.PersistentFlags().BoolVar(&options.DryRun, "dry-run", false,
+		"Run in dry-run mode (does not really create/delete the hook on github, but prints what would be done).")
+}
@@ -0,0 +1,141 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/github"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+)
+
+// hookContext holds what is needed to manage the hook of a single BuildConfig
+type hookContext struct {
+	hooksManager *github.HooksManager
+	publicURL    string
+	directURL    string
+	hook         *api.Hook
+	key          string
+}
+
+// newHookContext connects to GitHub and OpenShift, and computes the hook of the given BuildConfig
+// (in the namespace defined by the -n flag, or the current project)
+func newHookContext(options *Options, name string) *hookContext {
+	hooksManager, err := github.NewHooksManager(options.GithubBaseURL, options.Token, options.GithubInsecureSkipVerify, options.GithubTimeouts)
+	if err != nil {
+		glog.Fatalf("Failed to connect to GitHub: %v", err)
+	}
+	hooksManager.SameURLFunc = openshift.SameOpenshiftHook
+
+	oclient, kclient, err := openshift.Factory.Clients()
+	if err != nil {
+		glog.Fatalf("Failed to get OpenShift client: %v", err)
+	}
+	namespace, _, err := openshift.Factory.DefaultNamespace()
+	if err != nil {
+		glog.Fatalf("Failed to get the current project: %v", err)
+	}
+
+	publicURL, err := options.WebhookURL.PublicURL(oclient, options.OpenshiftPublicURL)
+	if err != nil {
+		glog.Fatalf("Failed to get the webhooks public URL: %v", err)
+	}
+	// the hooks that still target OpenShift directly are matched too (only with a relay)
+	directURL := ""
+	if len(options.RelayURL) > 0 {
+		directURL = publicURL
+		publicURL = strings.TrimSuffix(options.RelayURL, "/")
+	}
+
+	bc, err := oclient.BuildConfigs(namespace).Get(name)
+	if err != nil {
+		glog.Fatalf("Failed to get the BuildConfig %s/%s: %v", namespace, name, err)
+	}
+
+	controller := &openshift.BuildConfigsController{
+		BuildConfigsNamespacer: oclient,
+		OpenshiftPublicURL:     publicURL,
+		WebhookAPIPath:         options.WebhookAPIPath,
+		RelayURL:               options.RelayURL,
+		RelayKey:               []byte(options.RelayKey),
+	}
+	if options.SecretReferences {
+		secretReferences := openshift.NewSecretReferences(oclient)
+		secretReferences.SecretFunc = openshift.NewSecretCache(kclient).Get
+		controller.SecretReferenceFunc = secretReferences.Resolve
+	}
+	hook, err := controller.HookFor(bc)
+	if err != nil {
+		glog.Fatalf("Failed to compute the hook: %v", err)
+	}
+
+	return &hookContext{
+		hooksManager: hooksManager,
+		publicURL:    publicURL,
+		directURL:    directURL,
+		hook:         hook,
+		key:          fmt.Sprintf("%s/%s", namespace, name),
+	}
+}
+
+// createHook creates the hook of the given BuildConfig, and prints its ID
+func createHook(options *Options, name string) {
+	c := newHookContext(options, name)
+	hook := *c.hook
+
+	if options.DryRun {
+		fmt.Printf("DRY-RUN: would have registered hook on %s with target URL %s\n", hook.GithubRepository, openshift.MaskOpenshiftHookSecret(hook.TargetURL))
+		return
+	}
+
+	registeredHook, changed, err := c.hooksManager.RegisterHook(context.Background(), hook)
+	if err != nil {
+		glog.Fatalf("Failed to register hook on %s: %v", hook.GithubRepository, err)
+	}
+	if changed {
+		fmt.Printf("Hook %d registered on %s for BuildConfig %s\n", registeredHook.ID, hook.GithubRepository, c.key)
+	} else {
+		fmt.Printf("Hook %d already exists on %s for BuildConfig %s\n", registeredHook.ID, hook.GithubRepository, c.key)
+	}
+}
+
+// deleteHook deletes the hooks targeting the given BuildConfig from its repository, and prints their IDs
+func deleteHook(options *Options, name string) {
+	c := newHookContext(options, name)
+	ctx := context.Background()
+
+	hooks, err := c.hooksManager.ListHooksForRepository(ctx, c.hook.GithubRepository)
+	if err != nil {
+		glog.Fatalf("Failed to list the hooks of %s: %v", c.hook.GithubRepository, err)
+	}
+
+	// match the hooks by BuildConfig, to also delete the ones with an outdated secret
+	found := false
+	for _, hook := range hooks {
+		if !openshift.IsOpenshiftHook(hook.TargetURL, c.publicURL) && (len(c.directURL) == 0 || !openshift.IsOpenshiftHook(hook.TargetURL, c.directURL)) {
+			continue
+		}
+		ns, bc, _ := openshift.ExplodeOpenshiftWebhookURL(hook.TargetURL)
+		if fmt.Sprintf("%s/%s", ns, bc) != c.key {
+			continue
+		}
+		found = true
+
+		if options.DryRun {
+			fmt.Printf("DRY-RUN: would have deleted hook %d from %s\n", hook.ID, hook.GithubRepository)
+			continue
+		}
+		hook.Enabled = false
+		if _, err := c.hooksManager.DeleteHook(ctx, hook); err != nil {
+			glog.Fatalf("Failed to delete hook %d from %s: %v", hook.ID, hook.GithubRepository, err)
+		}
+		fmt.Printf("Hook %d deleted from %s\n", hook.ID, hook.GithubRepository)
+	}
+
+	if !found {
+		fmt.Printf("No hook found on %s for BuildConfig %s\n", c.hook.GithubRepository, c.key)
+	}
+}
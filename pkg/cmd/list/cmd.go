@@ -3,9 +3,12 @@ package list
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/vbehar/openshift-github-hooks/pkg/cmd"
+	"github.com/vbehar/openshift-github-hooks/pkg/github"
 	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/policy"
 
 	"github.com/spf13/cobra"
 )
@@ -14,10 +17,15 @@ import (
 type Options struct {
 	GithubBaseURL            string
 	GithubInsecureSkipVerify bool
+	GithubTimeouts           github.Timeouts
 	OrganizationName         string
 	RepositoryName           string
 	Token                    string
 	OpenshiftPublicURL       string
+	WebhookURL               openshift.WebhookURLOptions
+	PolicyFile               string
+	PolicyConfigMap          string
+	Output                   string
 }
 
 var (
@@ -26,7 +34,22 @@ var (
 	$ %[1]s --organization=my-org --github-token=...
 
 	# List all github webhooks of the "my-org/some-repository" repository
-	$ %[1]s --organization=my-org --repository=some-repository --github-token=...`
+	$ %[1]s --organization=my-org --repository=some-repository --github-token=...
+
+	# List all github webhooks of the "my-org" organization, and flag the ones that violate the policy
+	$ %[1]s --organization=my-org --github-token=... --policy-configmap=github-hooks/policy
+
+	# List all github webhooks of the "my-org" organization, with their ID, events, and last delivery status
+	$ %[1]s --organization=my-org --github-token=... -o wide
+
+	# Print the hooks of the "my-org" organization as JSON, or YAML
+	$ %[1]s --organization=my-org --github-token=... -o json
+
+	# Print the namespace/buildconfig targeted by each hook of the "my-org" organization
+	$ %[1]s --organization=my-org --github-token=... -o go-template='{{range .items}}{{.namespace}}/{{.buildConfig}}{{"\n"}}{{end}}'
+
+	# Print the IDs of the hooks of the "my-org/some-repository" repository
+	$ %[1]s --organization=my-org --repository=some-repository --github-token=... -o jsonpath='{.items[*].id}'`
 
 	listCmd = &cobra.Command{
 		Use:   "list",
@@ -37,7 +60,11 @@ It can either list webhooks of all the repositories in a specific GitHub Organiz
 
 As it use the GitHub API to list the hooks, it needs a GitHub Token to authenticate against the GitHub API.
 Note that the token requires the "repo" and "read:repo_hook" scopes.
-It can be set either with the --github-token flag, or the GITHUB_ACCESS_TOKEN environment variable.`,
+It can be set either with the --github-token flag, or the GITHUB_ACCESS_TOKEN environment variable.
+
+The hooks are printed as a table by default. Use the --output (-o) flag to print them
+as json, yaml, a wide table (with the hooks IDs, events, and last delivery status),
+their names (owner/repository/hooks/ID), or with a go-template or jsonpath template.`,
 		PreRunE: func(command *cobra.Command, args []string) error {
 			if len(options.Token) == 0 {
 				return fmt.Errorf("Empty GitHub Access Token. Please provide one either with the --github-token flag or the GITHUB_ACCESS_TOKEN environment variable.")
@@ -45,6 +72,9 @@ It can be set either with the --github-token flag, or the GITHUB_ACCESS_TOKEN en
 			if len(options.OrganizationName) == 0 {
 				return fmt.Errorf("Empty GitHub Organization Name. Please provide one either with the --organization flag or the GITHUB_ORGANIZATION environment variable.")
 			}
+			if _, err := newHookPrinter(options.Output); err != nil {
+				return err
+			}
 			return nil
 		},
 		Run: func(command *cobra.Command, args []string) {
@@ -66,6 +96,10 @@ func init() {
 		"The GitHub Base URL - if you use GitHub Enterprise. Could also be defined by the GITHUB_BASE_URL env var. Format: https://github.domain.tld/api/v3/")
 	listCmd.Flags().BoolVar(&options.GithubInsecureSkipVerify, "github-insecure-skip-tls-verify", false,
 		"If true, the github server's certificate will not be checked for validity. This will make your HTTPS connections insecure.")
+	listCmd.Flags().DurationVar(&options.GithubTimeouts.Request, "github-request-timeout", 30*time.Second,
+		"The timeout of a single request to the GitHub API. 0 means no timeout.")
+	listCmd.Flags().DurationVar(&options.GithubTimeouts.Operation, "github-operation-timeout", 10*time.Minute,
+		"The timeout of an operation on the GitHub API (creating a hook, listing all the hooks of the organization, ...), which may perform multiple requests. 0 means no timeout.")
 	listCmd.Flags().StringVar(&options.Token, "github-token", os.Getenv("GITHUB_ACCESS_TOKEN"),
 		"The GitHub Access Token - could also be defined by the GITHUB_ACCESS_TOKEN env var. See https://github.com/settings/tokens to get one.")
 	listCmd.Flags().StringVar(&options.OrganizationName, "organization", os.Getenv("GITHUB_ORGANIZATION"),
@@ -74,4 +108,16 @@ func init() {
 		"The name of the GitHub Repository for which we will list the webhooks. Optional (default to retrieve all repositories from the organization).")
 	listCmd.Flags().StringVar(&options.OpenshiftPublicURL, "openshift-public-url", openshift.DefaultOpenshiftPublicURL(),
 		"The public URL of your OpenShift Master, used to generate the Webhooks URLs.")
+	listCmd.Flags().StringVar(&options.PolicyFile, "policy-file", "",
+		"A policy file (YAML or JSON) restricting which namespaces may hook which repositories. If set, the hooks that violate the policy are flagged.")
+	listCmd.Flags().StringVar(&options.PolicyConfigMap, "policy-configmap", "",
+		fmt.Sprintf("The namespace/name of a configmap containing the policy (in its %s key). If set, the hooks that violate the policy are flagged. Ignored if --policy-file is set.", policy.ConfigMapKey))
+	listCmd.Flags().StringVar(&options.WebhookURL.BaseURL, "webhook-base-url", "",
+		"An external base URL reachable by GitHub (a router or ingress host), used instead of the OpenShift public URL to generate the Webhooks URLs.")
+	listCmd.Flags().StringVar(&options.WebhookURL.Route, "webhook-route", "",
+		"The namespace/name of a Route whose host is used instead of the OpenShift public URL to generate the Webhooks URLs. Ignored if --webhook-base-url is set.")
+	listCmd.Flags().StringVar(&options.WebhookURL.PathPrefix, "webhook-path-prefix", "",
+		"A path prefix added after the base URL of the Webhooks URLs, if the router or proxy exposes the OpenShift API under a sub-path.")
+	listCmd.Flags().StringVarP(&options.Output, "output", "o", "",
+		"Output format. One of: json|yaml|wide|name|go-template=TEMPLATE|jsonpath=TEMPLATE. Default to a table.")
 }
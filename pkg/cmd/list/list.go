@@ -1,52 +1,93 @@
 package list
 
 import (
-	"fmt"
 	"os"
-	"text/tabwriter"
+	"sort"
 
 	"github.com/vbehar/openshift-github-hooks/pkg/api"
 	"github.com/vbehar/openshift-github-hooks/pkg/github"
 	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/policy"
 
 	"github.com/golang/glog"
+	"github.com/openshift/origin/pkg/client"
+	"golang.org/x/net/context"
 )
 
 // listHooks prints the github hooks that references openshift buildconfigs
 func listHooks(options *Options) {
-	hooksManager, err := github.NewHooksManager(options.GithubBaseURL, options.Token, options.GithubInsecureSkipVerify)
+	printer, err := newHookPrinter(options.Output)
+	if err != nil {
+		glog.Fatalf("%v", err)
+	}
+
+	hooksManager, err := github.NewHooksManager(options.GithubBaseURL, options.Token, options.GithubInsecureSkipVerify, options.GithubTimeouts)
 	if err != nil {
 		glog.Fatalf("Failed to connect to GitHub: %v", err)
 	}
 
+	var routes client.RoutesNamespacer
+	var policyChecker *policy.Checker
+	withPolicy := len(options.PolicyFile) > 0 || len(options.PolicyConfigMap) > 0
+	if (len(options.WebhookURL.Route) > 0 && len(options.WebhookURL.BaseURL) == 0) || withPolicy {
+		oclient, kclient, err := openshift.Factory.Clients()
+		if err != nil {
+			glog.Fatalf("Failed to get OpenShift client: %v", err)
+		}
+		routes = oclient
+
+		if withPolicy {
+			hooksPolicy, err := policy.Load(kclient, options.PolicyFile, options.PolicyConfigMap)
+			if err != nil {
+				glog.Fatalf("Failed to load the policy: %v", err)
+			}
+			policyChecker = policy.NewChecker(hooksPolicy, openshift.NewNamespaceCache(kclient))
+		}
+	}
+	publicURL, err := options.WebhookURL.PublicURL(routes, options.OpenshiftPublicURL)
+	if err != nil {
+		glog.Fatalf("Failed to get the webhooks public URL: %v", err)
+	}
+
 	var hooks []api.Hook
 	if len(options.RepositoryName) > 0 {
 		repository := api.GithubRepository{
 			Owner: options.OrganizationName,
 			Name:  options.RepositoryName,
 		}
-		hooks, err = hooksManager.ListHooksForRepository(repository)
+		hooks, err = hooksManager.ListHooksForRepository(context.Background(), repository)
 	} else {
-		hooks, err = hooksManager.ListHooksForOrganization(options.OrganizationName)
+		hooks, err = hooksManager.ListHooksForOrganization(context.Background(), options.OrganizationName)
 	}
 	if err != nil {
 		glog.Fatalf("Failed to list GitHub hooks: %v", err)
 	}
 
-	w := &tabwriter.Writer{}
-	w.Init(os.Stdout, 10, 4, 3, ' ', 0)
-	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", "OWNER", "REPOSITORY", "NAMESPACE", "BUILDCONFIG", "WEBHOOK SECRET")
-
+	listed := []listedHook{}
 	for _, hook := range hooks {
-		if !openshift.IsOpenshiftHook(hook.TargetURL, options.OpenshiftPublicURL) {
+		if !openshift.IsOpenshiftHook(hook.TargetURL, publicURL) {
 			glog.V(4).Infof("Ignoring non-openshift hook %s for repository %s", hook.TargetURL, hook.GithubRepository)
 		} else {
 			ns, bc, secret := openshift.ExplodeOpenshiftWebhookURL(hook.TargetURL)
 			if len(ns) > 0 && len(bc) > 0 {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", hook.GithubRepository.Owner, hook.GithubRepository.Name, ns, bc, secret)
+				item := newListedHook(hook, ns, bc, secret)
+				if withPolicy {
+					item.Policy = "allowed"
+					switch err := policyChecker.Check(ns, hook.GithubRepository); {
+					case openshift.IsRepositoryCheckUnknown(err):
+						glog.Warningf("Failed to check the policy for hook %d of repository %s: %v", hook.ID, hook.GithubRepository, err)
+						item.Policy = "unknown"
+					case err != nil:
+						item.Policy = "VIOLATION"
+					}
+				}
+				listed = append(listed, item)
 			}
 		}
 	}
+	sort.Sort(byRepository(listed))
 
-	w.Flush()
+	if err := printer(os.Stdout, listed, withPolicy); err != nil {
+		glog.Fatalf("Failed to print the hooks: %v", err)
+	}
 }
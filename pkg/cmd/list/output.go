@@ -0,0 +1,266 @@
+package list
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/kubernetes/pkg/util/jsonpath"
+)
+
+const (
+	wideOutput       = "wide"
+	jsonOutput       = "json"
+	yamlOutput       = "yaml"
+	nameOutput       = "name"
+	goTemplateOutput = "go-template"
+	jsonPathOutput   = "jsonpath"
+)
+
+// listedHook is a github hook that targets an openshift buildconfig,
+// as printed by the list command
+type listedHook struct {
+	Owner        string       `json:"owner"`
+	Repository   string       `json:"repository"`
+	Namespace    string       `json:"namespace"`
+	BuildConfig  string       `json:"buildConfig"`
+	Secret       string       `json:"secret,omitempty"`
+	ID           int          `json:"id"`
+	Events       []string     `json:"events"`
+	Active       bool         `json:"active"`
+	ContentType  string       `json:"contentType"`
+	CreatedAt    *time.Time   `json:"createdAt"`
+	UpdatedAt    *time.Time   `json:"updatedAt"`
+	LastResponse lastResponse `json:"lastResponse"`
+
+	// Policy is the policy status of the hook ("allowed", "VIOLATION", or "unknown" if it can't be checked),
+	// or empty if no policy has been given
+	Policy string `json:"policy,omitempty"`
+}
+
+// lastResponse is the response of the last delivery of a listed hook
+// (empty if the hook has never been delivered)
+type lastResponse struct {
+	Code    int    `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// listedHooks is the list of hooks printed by the list command
+type listedHooks struct {
+	Items []listedHook `json:"items"`
+}
+
+// newListedHook returns the listedHook for the given github hook,
+// which targets the given namespace and buildconfig
+func newListedHook(hook api.Hook, ns, bc, secret string) listedHook {
+	listed := listedHook{
+		Owner:       hook.GithubRepository.Owner,
+		Repository:  hook.GithubRepository.Name,
+		Namespace:   ns,
+		BuildConfig: bc,
+		Secret:      secret,
+		ID:          hook.ID,
+		Events:      []string{},
+		Active:      !hook.Inactive,
+	}
+	if details := hook.Details; details != nil {
+		if details.Events != nil {
+			listed.Events = details.Events
+		}
+		listed.ContentType = details.ContentType
+		listed.CreatedAt = details.CreatedAt
+		listed.UpdatedAt = details.UpdatedAt
+		listed.LastResponse = lastResponse{
+			Code:    details.LastResponse.Code,
+			Status:  details.LastResponse.Status,
+			Message: details.LastResponse.Message,
+		}
+	}
+	return listed
+}
+
+// byRepository sorts the listed hooks by owner, repository and ID
+type byRepository []listedHook
+
+func (h byRepository) Len() int      { return len(h) }
+func (h byRepository) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h byRepository) Less(i, j int) bool {
+	if h[i].Owner != h[j].Owner {
+		return h[i].Owner < h[j].Owner
+	}
+	if h[i].Repository != h[j].Repository {
+		return h[i].Repository < h[j].Repository
+	}
+	return h[i].ID < h[j].ID
+}
+
+// hookPrinter prints the given hooks to the given writer
+type hookPrinter func(w io.Writer, hooks []listedHook, withPolicy bool) error
+
+// newHookPrinter returns the hookPrinter for the given output format:
+// empty (default table), wide, json, yaml, name, go-template=TEMPLATE or jsonpath=TEMPLATE
+func newHookPrinter(output string) (hookPrinter, error) {
+	format, tmpl := output, ""
+	if i := strings.Index(output, "="); i >= 0 {
+		format, tmpl = output[:i], output[i+1:]
+	}
+
+	switch format {
+	case "", wideOutput, jsonOutput, yamlOutput, nameOutput:
+		if len(tmpl) > 0 {
+			break
+		}
+		return newSimpleHookPrinter(format), nil
+	case goTemplateOutput:
+		if len(tmpl) == 0 {
+			return nil, fmt.Errorf("Empty template. Please provide one with -o %s=TEMPLATE.", format)
+		}
+		t, err := template.New("output").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid go-template %q: %v", tmpl, err)
+		}
+		return func(w io.Writer, hooks []listedHook, withPolicy bool) error {
+			data, err := toGenericObject(hooks)
+			if err != nil {
+				return err
+			}
+			return t.Execute(w, data)
+		}, nil
+	case jsonPathOutput:
+		if len(tmpl) == 0 {
+			return nil, fmt.Errorf("Empty template. Please provide one with -o %s=TEMPLATE.", format)
+		}
+		j := jsonpath.New("output")
+		if err := j.Parse(tmpl); err != nil {
+			return nil, fmt.Errorf("Invalid jsonpath %q: %v", tmpl, err)
+		}
+		return func(w io.Writer, hooks []listedHook, withPolicy bool) error {
+			data, err := toGenericObject(hooks)
+			if err != nil {
+				return err
+			}
+			return j.Execute(w, data)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Invalid output format %s. Please use one of json, yaml, wide, name, go-template=TEMPLATE, jsonpath=TEMPLATE, or nothing for the default format.", output)
+}
+
+// newSimpleHookPrinter returns the hookPrinter for the given output format
+// that does not require a template
+func newSimpleHookPrinter(format string) hookPrinter {
+	return func(w io.Writer, hooks []listedHook, withPolicy bool) error {
+		switch format {
+		case jsonOutput:
+			data, err := json.MarshalIndent(listedHooks{Items: hooks}, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(w, string(data))
+			return err
+		case yamlOutput:
+			data, err := yaml.Marshal(listedHooks{Items: hooks})
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		case nameOutput:
+			for _, hook := range hooks {
+				fmt.Fprintf(w, "%s/%s/hooks/%d\n", hook.Owner, hook.Repository, hook.ID)
+			}
+			return nil
+		default:
+			printHooksTable(w, hooks, withPolicy, format == wideOutput)
+			return nil
+		}
+	}
+}
+
+// printHooksTable prints the given hooks as a table,
+// with the details of the hooks if wide is true
+func printHooksTable(out io.Writer, hooks []listedHook, withPolicy bool, wide bool) {
+	w := &tabwriter.Writer{}
+	w.Init(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s", "OWNER", "REPOSITORY", "NAMESPACE", "BUILDCONFIG", "WEBHOOK SECRET")
+	if withPolicy {
+		fmt.Fprintf(w, "\t%s", "POLICY")
+	}
+	if wide {
+		fmt.Fprintf(w, "\t%s\t%s\t%s\t%s\t%s\t%s\t%s", "ID", "EVENTS", "ACTIVE", "CONTENT TYPE", "CREATED", "UPDATED", "LAST DELIVERY")
+	}
+	fmt.Fprintln(w)
+
+	for _, hook := range hooks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s", hook.Owner, hook.Repository, hook.Namespace, hook.BuildConfig, hook.Secret)
+		if withPolicy {
+			fmt.Fprintf(w, "\t%s", hook.Policy)
+		}
+		if wide {
+			events := strings.Join(hook.Events, ",")
+			if len(events) == 0 {
+				events = "-"
+			}
+			fmt.Fprintf(w, "\t%d\t%s\t%t\t%s\t%s\t%s\t%s", hook.ID, events, hook.Active,
+				valueOrDash(hook.ContentType), formatTime(hook.CreatedAt), formatTime(hook.UpdatedAt), formatLastResponse(hook.LastResponse))
+		}
+		fmt.Fprintln(w)
+	}
+
+	w.Flush()
+}
+
+// toGenericObject converts the given hooks to a generic object (maps and slices),
+// so that the templates can use the same field names as the json output
+func toGenericObject(hooks []listedHook) (interface{}, error) {
+	data, err := json.Marshal(listedHooks{Items: hooks})
+	if err != nil {
+		return nil, err
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// formatLastResponse returns a short representation of the last delivery of a hook,
+// for example "200 OK" or "unused"
+func formatLastResponse(r lastResponse) string {
+	message := r.Message
+	if len(message) == 0 {
+		message = r.Status
+	}
+	if r.Code == 0 {
+		return valueOrDash(message)
+	}
+	if len(message) == 0 {
+		return strconv.Itoa(r.Code)
+	}
+	return fmt.Sprintf("%d %s", r.Code, message)
+}
+
+// formatTime returns the given time in the RFC3339 format, or a dash if it is nil
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// valueOrDash returns the given value, or a dash if it is empty
+func valueOrDash(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,174 @@
+package list
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testListedHooks() []listedHook {
+	createdAt := time.Date(2016, 5, 1, 10, 0, 0, 0, time.UTC)
+	return []listedHook{
+		{
+			Owner:        "my-org",
+			Repository:   "repo",
+			Namespace:    "ns",
+			BuildConfig:  "bc",
+			Secret:       "secret",
+			ID:           1234,
+			Events:       []string{"push"},
+			Active:       true,
+			ContentType:  "json",
+			CreatedAt:    &createdAt,
+			LastResponse: lastResponse{Code: 200, Message: "OK"},
+			Policy:       "allowed",
+		},
+		{
+			Owner:       "my-org",
+			Repository:  "other",
+			Namespace:   "ns",
+			BuildConfig: "other-bc",
+			Secret:      "other-secret",
+			ID:          5678,
+			Events:      []string{},
+			Policy:      "VIOLATION",
+		},
+	}
+}
+
+func TestNewHookPrinter(t *testing.T) {
+	tests := []struct {
+		output           string
+		withPolicy       bool
+		expectedLines    []string
+		expectedContains []string
+	}{
+		// default table
+		{
+			output: "",
+			expectedLines: []string{
+				"OWNER REPOSITORY NAMESPACE BUILDCONFIG WEBHOOK SECRET",
+				"my-org repo ns bc secret",
+				"my-org other ns other-bc other-secret",
+			},
+		},
+		// default table with the policy
+		{
+			output:     "",
+			withPolicy: true,
+			expectedLines: []string{
+				"OWNER REPOSITORY NAMESPACE BUILDCONFIG WEBHOOK SECRET POLICY",
+				"my-org repo ns bc secret allowed",
+				"my-org other ns other-bc other-secret VIOLATION",
+			},
+		},
+		// wide table
+		{
+			output: "wide",
+			expectedLines: []string{
+				"OWNER REPOSITORY NAMESPACE BUILDCONFIG WEBHOOK SECRET ID EVENTS ACTIVE CONTENT TYPE CREATED UPDATED LAST DELIVERY",
+				"my-org repo ns bc secret 1234 push true json 2016-05-01T10:00:00Z - 200 OK",
+				"my-org other ns other-bc other-secret 5678 - false - - - -",
+			},
+		},
+		// name
+		{
+			output: "name",
+			expectedLines: []string{
+				"my-org/repo/hooks/1234",
+				"my-org/other/hooks/5678",
+			},
+		},
+		// go-template
+		{
+			output: `go-template={{range .items}}{{.repository}}:{{.buildConfig}}{{"\n"}}{{end}}`,
+			expectedLines: []string{
+				"repo:bc",
+				"other:other-bc",
+			},
+		},
+		// jsonpath
+		{
+			output: `jsonpath={range .items[*]}{.repository} {.id}{"\n"}{end}`,
+			expectedLines: []string{
+				"repo 1234",
+				"other 5678",
+			},
+		},
+		// json
+		{
+			output: "json",
+			expectedContains: []string{
+				`"items": [`,
+				`"repository": "repo"`,
+				`"id": 1234`,
+				`"createdAt": "2016-05-01T10:00:00Z"`,
+				`"updatedAt": null`,
+				`"policy": "VIOLATION"`,
+			},
+		},
+		// yaml
+		{
+			output: "yaml",
+			expectedContains: []string{
+				"items:\n",
+				"repository: repo\n",
+				"id: 1234\n",
+				"policy: VIOLATION\n",
+			},
+		},
+	}
+
+	for count, test := range tests {
+		printer, err := newHookPrinter(test.output)
+		if err != nil {
+			t.Errorf("Test[%d] Failed: Unexpected error for output %q: %v", count, test.output, err)
+			continue
+		}
+
+		var buffer bytes.Buffer
+		if err := printer(&buffer, testListedHooks(), test.withPolicy); err != nil {
+			t.Errorf("Test[%d] Failed: Failed to print the hooks: %v", count, err)
+			continue
+		}
+		result := buffer.String()
+
+		if test.expectedLines != nil {
+			lines := strings.Split(strings.TrimSuffix(result, "\n"), "\n")
+			if len(lines) != len(test.expectedLines) {
+				t.Errorf("Test[%d] Failed: Expected %d lines but got %d:\n%s", count, len(test.expectedLines), len(lines), result)
+				continue
+			}
+			for i, line := range lines {
+				// the columns of the tables are aligned with a variable number of spaces
+				if normalized := strings.Join(strings.Fields(line), " "); normalized != test.expectedLines[i] {
+					t.Errorf("Test[%d] Failed: Expected line %d to be '%s' but got '%s'", count, i, test.expectedLines[i], normalized)
+				}
+			}
+		}
+		for _, expected := range test.expectedContains {
+			if !strings.Contains(result, expected) {
+				t.Errorf("Test[%d] Failed: Expected the output to contain '%s', but got:\n%s", count, expected, result)
+			}
+		}
+	}
+}
+
+func TestNewHookPrinterInvalidFormat(t *testing.T) {
+	tests := []string{
+		"xml",
+		"json=TEMPLATE",
+		"go-template",
+		"go-template=",
+		"go-template={{.items",
+		"jsonpath",
+		"jsonpath={.items[",
+	}
+
+	for count, output := range tests {
+		if printer, err := newHookPrinter(output); err == nil || printer != nil {
+			t.Errorf("Test[%d] Failed: Expected an error for output %q but got none", count, output)
+		}
+	}
+}
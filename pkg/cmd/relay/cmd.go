@@ -0,0 +1,86 @@
+package relay
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/cmd"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/relay"
+
+	"github.com/spf13/cobra"
+)
+
+// Options represents the command's options
+type Options struct {
+	ListenAddress    string
+	TLSCertFile      string
+	TLSKeyFile       string
+	Key              string
+	SecretReferences bool
+	ForwardTimeout   time.Duration
+	MaxPayloadSize   int64
+}
+
+var (
+	relayCmdExample = `
+	# Start the relay on port 8080
+	$ %[1]s --relay-key=...
+
+	# Start the relay with TLS on port 8443
+	$ %[1]s --relay-key=... --listen=:8443 --tls-cert-file=/path/to/tls.crt --tls-key-file=/path/to/tls.key`
+
+	relayCmd = &cobra.Command{
+		Use:   "relay",
+		Short: "Relay the GitHub hooks deliveries to the OpenShift BuildConfigs webhooks",
+		Long: `
+The relay command runs an HTTP server that relays the GitHub hooks deliveries to the OpenShift BuildConfigs webhooks,
+for the clusters where GitHub must not reach the OpenShift master.
+
+The sync command must be started with the --relay-url flag (pointing to the relay), and the same relay key:
+the hooks will then target the relay, with URLs that don't contain the BuildConfigs trigger secrets,
+and their deliveries will be signed with a secret derived from the relay key.
+The relay checks the signature of each delivery (the X-Hub-Signature header), rejects the spoofed deliveries,
+and forwards the others to the internal webhook URL of the BuildConfig - so the trigger secrets never leave the cluster.
+
+The relay key can be set either with the --relay-key flag, or the RELAY_KEY environment variable.`,
+		PreRunE: func(command *cobra.Command, args []string) error {
+			if len(options.Key) == 0 {
+				return fmt.Errorf("Empty relay key. Please provide one either with the --relay-key flag or the RELAY_KEY environment variable.")
+			}
+			if (len(options.TLSCertFile) == 0) != (len(options.TLSKeyFile) == 0) {
+				return fmt.Errorf("Both --tls-cert-file and --tls-key-file must be set to enable TLS.")
+			}
+			return nil
+		},
+		Run: func(command *cobra.Command, args []string) {
+			runRelay(options)
+		},
+	}
+
+	options = &Options{}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(relayCmd)
+
+	relayCmd.Example = fmt.Sprintf(relayCmdExample, cmd.FullName(relayCmd))
+
+	relayCmd.Flags().AddFlagSet(openshift.Flags)
+
+	relayCmd.Flags().StringVar(&options.ListenAddress, "listen", ":8080",
+		"The address on which the relay listens.")
+	relayCmd.Flags().StringVar(&options.TLSCertFile, "tls-cert-file", "",
+		"The TLS certificate file, to serve HTTPS. Optional (you can also terminate TLS on a Route).")
+	relayCmd.Flags().StringVar(&options.TLSKeyFile, "tls-key-file", "",
+		"The TLS private key file, to serve HTTPS. Optional (you can also terminate TLS on a Route).")
+	relayCmd.Flags().StringVar(&options.Key, "relay-key", os.Getenv("RELAY_KEY"),
+		"The relay key, used to derive the secret of each hook - could also be defined by the RELAY_KEY env var. It must be the same for the sync and relay commands.")
+	relayCmd.Flags().BoolVar(&options.SecretReferences, "secret-references", false,
+		fmt.Sprintf("Support the GitHub triggers that reference their secret from a Secret object (in its %s key) instead of setting it inline. Requires the permission to read the Secrets.", openshift.WebHookSecretKey))
+	relayCmd.Flags().DurationVar(&options.ForwardTimeout, "forward-timeout", relay.DefaultForwardTimeout,
+		"The timeout of a delivery forwarded to OpenShift.")
+	relayCmd.Flags().Int64Var(&options.MaxPayloadSize, "max-payload-size", relay.DefaultMaxPayloadSize,
+		"The maximum size (in bytes) of a delivery payload.")
+}
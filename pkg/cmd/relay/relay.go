@@ -0,0 +1,61 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/relay"
+
+	"github.com/golang/glog"
+
+	"k8s.io/kubernetes/pkg/client/restclient"
+)
+
+// runRelay starts the relay server, and blocks until it fails
+func runRelay(options *Options) {
+	config, err := openshift.Factory.OpenShiftClientConfig.ClientConfig()
+	if err != nil {
+		glog.Fatalf("Failed to get OpenShift config: %v", err)
+	}
+	oclient, kclient, err := openshift.Factory.Clients()
+	if err != nil {
+		glog.Fatalf("Failed to get OpenShift client: %v", err)
+	}
+
+	// the deliveries are forwarded to the OpenShift master, so we trust the same CA
+	tlsConfig, err := restclient.TLSConfigFor(config)
+	if err != nil {
+		glog.Fatalf("Failed to get the OpenShift TLS config: %v", err)
+	}
+
+	server := &relay.Server{
+		Key:                    []byte(options.Key),
+		BuildConfigsNamespacer: oclient,
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+		ForwardTimeout: options.ForwardTimeout,
+		MaxPayloadSize: options.MaxPayloadSize,
+	}
+	if options.SecretReferences {
+		// the Secrets are read on each delivery, so that a rotated secret is used right away
+		secretReferences := openshift.NewSecretReferences(oclient)
+		secretReferences.SecretFunc = openshift.NewSecretCache(kclient).Get
+		server.SecretReferenceFunc = secretReferences.Resolve
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+
+	glog.Infof("Relay listening on %s", options.ListenAddress)
+	if len(options.TLSCertFile) > 0 {
+		err = http.ListenAndServeTLS(options.ListenAddress, options.TLSCertFile, options.TLSKeyFile, mux)
+	} else {
+		err = http.ListenAndServe(options.ListenAddress, mux)
+	}
+	glog.Fatalf("Relay failed: %v", err)
+}
@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/reconciler"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+)
+
+// partialFailureExitCode is the exit code of the apply command when some changes failed,
+// or when the deletions have been blocked by the mass deletion guard
+const partialFailureExitCode = 1
+
+// appliedChange is the outcome of a single change applied by the apply command
+type appliedChange struct {
+	action  string
+	hook    api.Hook
+	key     string
+	changed bool
+	err     error
+}
+
+// applySummary records the outcome of each change applied by the reconciler.
+// A change may be retried, so only its last outcome is kept.
+type applySummary struct {
+	keyFunc reconciler.KeyFunc
+	changes []*appliedChange
+	byHook  map[api.Hook]*appliedChange
+	lock    sync.Mutex
+}
+
+// record records the outcome of a change
+func (s *applySummary) record(hook api.Hook, changed bool, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	change, found := s.byHook[hook]
+	if !found {
+		change = &appliedChange{hook: hook}
+		switch {
+		case !hook.Enabled:
+			change.action = deleteAction
+		case hook.ID != 0:
+			change.action = updateAction
+		default:
+			change.action = createAction
+		}
+		change.key, _ = s.keyFunc(hook)
+		s.byHook[hook] = change
+		s.changes = append(s.changes, change)
+	}
+	change.changed = changed
+	change.err = err
+}
+
+// counts returns the number of changes applied for each action, and the number of failed changes
+func (s *applySummary) counts() (applied map[string]int, failed int) {
+	applied = map[string]int{}
+	for _, change := range s.changes {
+		switch {
+		case change.err != nil:
+			failed++
+		case change.changed:
+			applied[change.action]++
+		}
+	}
+	return
+}
+
+// exitCode returns the exit code of the apply command: the partialFailureExitCode
+// if some changes failed or if the deletions have been blocked, 0 otherwise
+func (s *applySummary) exitCode(blockedDeletions error) int {
+	if _, failed := s.counts(); failed > 0 || blockedDeletions != nil {
+		return partialFailureExitCode
+	}
+	return 0
+}
+
+// applyHooks performs a single full reconciliation of the hooks, prints a summary of the changes,
+// and exits with the partialFailureExitCode if some changes failed
+func applyHooks(options *Options) {
+	s := newSyncer(context.Background(), options, nil)
+	if err := s.controller.ListOnce(); err != nil {
+		glog.Fatalf("Failed to list the BuildConfigs: %v", err)
+	}
+
+	summary := &applySummary{
+		keyFunc: s.keyFunc,
+		byHook:  map[api.Hook]*appliedChange{},
+	}
+	handleHook := s.reconciler.HookHandlerFunc
+	s.reconciler.HookHandlerFunc = func(hook api.Hook) (*api.Hook, bool, error) {
+		handledHook, changed, err := handleHook(hook)
+		summary.record(hook, changed, err)
+		return handledHook, changed, err
+	}
+
+	plan, err := s.reconciler.Reconcile()
+	// an empty plan can't fail to be applied: the hooks could not be retrieved
+	if err != nil && plan.IsEmpty() && !reconciler.IsMassDeletion(err) {
+		glog.Fatalf("Failed to reconcile the hooks: %v", err)
+	}
+
+	var blockedDeletions error
+	if reconciler.IsMassDeletion(err) {
+		blockedDeletions = err
+	}
+	printApplySummary(os.Stdout, summary, blockedDeletions)
+
+	if code := summary.exitCode(blockedDeletions); code != 0 {
+		glog.Flush()
+		os.Exit(code)
+	}
+}
+
+// printApplySummary prints the outcome of each change, followed by the number of changes
+func printApplySummary(w io.Writer, summary *applySummary, blockedDeletions error) {
+	if len(summary.changes) == 0 && blockedDeletions == nil {
+		fmt.Fprintln(w, "No changes. The GitHub hooks are in sync with the BuildConfigs.")
+		return
+	}
+
+	done := map[string]string{
+		createAction: "created",
+		updateAction: "updated",
+		deleteAction: "deleted",
+	}
+	for _, change := range summary.changes {
+		switch {
+		case change.err != nil:
+			fmt.Fprintf(w, "  FAILED to %s", change.action)
+		case change.changed:
+			fmt.Fprintf(w, "  %s", done[change.action])
+		default:
+			fmt.Fprintf(w, "  skipped %s", change.action)
+		}
+		fmt.Fprintf(w, " %s", change.hook.GithubRepository)
+		if len(change.key) > 0 {
+			fmt.Fprintf(w, " (%s)", change.key)
+		}
+		if change.hook.ID > 0 {
+			fmt.Fprintf(w, " hook %d", change.hook.ID)
+		}
+		if change.err != nil {
+			fmt.Fprintf(w, ": %v", change.err)
+		}
+		fmt.Fprintln(w)
+		if glog.V(1) {
+			fmt.Fprintf(w, "      url: %s\n", openshift.MaskOpenshiftHookSecret(change.hook.TargetURL))
+		}
+	}
+
+	applied, failed := summary.counts()
+	skipped := len(summary.changes) - failed - applied[createAction] - applied[updateAction] - applied[deleteAction]
+	fmt.Fprintf(w, "\nApply: %d created, %d updated, %d deleted, %d skipped, %d failed.\n",
+		applied[createAction], applied[updateAction], applied[deleteAction], skipped, failed)
+	if blockedDeletions != nil {
+		fmt.Fprintf(w, "\nWARNING: the deletions have been blocked by the mass deletion guard: %v\n", blockedDeletions)
+	}
+}
@@ -0,0 +1,164 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+)
+
+func newTestApplySummary() *applySummary {
+	return &applySummary{
+		keyFunc: testKeyFunc,
+		byHook:  map[api.Hook]*appliedChange{},
+	}
+}
+
+func TestApplySummaryRecord(t *testing.T) {
+	repo := api.GithubRepository{Owner: "my-org", Name: "repo"}
+	created := api.Hook{Enabled: true, TargetURL: testHookURL("created", "secret"), GithubRepository: repo}
+	updated := api.Hook{ID: 1, Enabled: true, TargetURL: testHookURL("updated", "secret"), GithubRepository: repo}
+	deleted := api.Hook{ID: 2, TargetURL: testHookURL("deleted", "secret"), GithubRepository: repo}
+	failed := api.Hook{ID: 3, TargetURL: testHookURL("failed", "secret"), GithubRepository: repo}
+	skipped := api.Hook{Enabled: true, TargetURL: "https://ci.example.com/hook", GithubRepository: repo}
+
+	summary := newTestApplySummary()
+	// the created hook has been retried: only its last outcome is kept
+	summary.record(created, false, fmt.Errorf("github is down"))
+	summary.record(created, true, nil)
+	summary.record(updated, true, nil)
+	summary.record(deleted, true, nil)
+	summary.record(failed, false, fmt.Errorf("github is down"))
+	summary.record(skipped, false, nil)
+
+	expected := []struct {
+		action  string
+		key     string
+		changed bool
+		failed  bool
+	}{
+		{action: createAction, key: "ns/created", changed: true},
+		{action: updateAction, key: "ns/updated", changed: true},
+		{action: deleteAction, key: "ns/deleted", changed: true},
+		{action: deleteAction, key: "ns/failed", failed: true},
+		{action: createAction, key: ""},
+	}
+	if len(summary.changes) != len(expected) {
+		t.Fatalf("Expected %d changes but got %d: %+v", len(expected), len(summary.changes), summary.changes)
+	}
+	for count, test := range expected {
+		change := summary.changes[count]
+		if change.action != test.action || change.key != test.key || change.changed != test.changed || (change.err != nil) != test.failed {
+			t.Errorf("Test[%d] Failed: Expected %s of %s (changed: %v, failed: %v) but got %+v", count, test.action, test.key, test.changed, test.failed, change)
+		}
+	}
+
+	applied, failedCount := summary.counts()
+	expectedApplied := map[string]int{createAction: 1, updateAction: 1, deleteAction: 1}
+	if !reflect.DeepEqual(applied, expectedApplied) {
+		t.Errorf("Expected %v applied changes but got %v", expectedApplied, applied)
+	}
+	if failedCount != 1 {
+		t.Errorf("Expected 1 failed change but got %d", failedCount)
+	}
+}
+
+func TestApplySummaryExitCode(t *testing.T) {
+	repo := api.GithubRepository{Owner: "my-org", Name: "repo"}
+	hook := api.Hook{Enabled: true, TargetURL: testHookURL("bc", "secret"), GithubRepository: repo}
+
+	tests := []struct {
+		err              error
+		noChange         bool
+		blockedDeletions error
+		expectedCode     int
+	}{
+		// no changes
+		{
+			noChange:     true,
+			expectedCode: 0,
+		},
+		// all changes applied
+		{
+			expectedCode: 0,
+		},
+		// a failed change
+		{
+			err:          fmt.Errorf("github is down"),
+			expectedCode: partialFailureExitCode,
+		},
+		// blocked deletions
+		{
+			noChange:         true,
+			blockedDeletions: fmt.Errorf("too many deletions"),
+			expectedCode:     partialFailureExitCode,
+		},
+	}
+
+	for count, test := range tests {
+		summary := newTestApplySummary()
+		if !test.noChange {
+			summary.record(hook, test.err == nil, test.err)
+		}
+		if code := summary.exitCode(test.blockedDeletions); code != test.expectedCode {
+			t.Errorf("Test[%d] Failed: Expected exit code %d but got %d", count, test.expectedCode, code)
+		}
+	}
+}
+
+func TestPrintApplySummary(t *testing.T) {
+	repo := api.GithubRepository{Owner: "my-org", Name: "repo"}
+
+	tests := []struct {
+		record           func(summary *applySummary)
+		blockedDeletions error
+		expectedOutput   string
+	}{
+		// no changes
+		{
+			record:         func(summary *applySummary) {},
+			expectedOutput: "No changes. The GitHub hooks are in sync with the BuildConfigs.\n",
+		},
+		// all kinds of outcomes
+		{
+			record: func(summary *applySummary) {
+				summary.record(api.Hook{Enabled: true, TargetURL: testHookURL("created", "secret"), GithubRepository: repo}, true, nil)
+				summary.record(api.Hook{ID: 1, Enabled: true, TargetURL: testHookURL("updated", "secret"), GithubRepository: repo}, true, nil)
+				summary.record(api.Hook{ID: 2, TargetURL: testHookURL("deleted", "secret"), GithubRepository: repo}, true, nil)
+				summary.record(api.Hook{ID: 3, TargetURL: testHookURL("failed", "secret"), GithubRepository: repo}, false, fmt.Errorf("github is down"))
+				summary.record(api.Hook{Enabled: true, TargetURL: testHookURL("skipped", "secret"), GithubRepository: repo}, false, nil)
+			},
+			expectedOutput: `  created my-org/repo (ns/created)
+  updated my-org/repo (ns/updated) hook 1
+  deleted my-org/repo (ns/deleted) hook 2
+  FAILED to delete my-org/repo (ns/failed) hook 3: github is down
+  skipped create my-org/repo (ns/skipped)
+
+Apply: 1 created, 1 updated, 1 deleted, 1 skipped, 1 failed.
+`,
+		},
+		// blocked deletions, without other changes
+		{
+			record:           func(summary *applySummary) {},
+			blockedDeletions: fmt.Errorf("too many deletions"),
+			expectedOutput: `
+Apply: 0 created, 0 updated, 0 deleted, 0 skipped, 0 failed.
+
+WARNING: the deletions have been blocked by the mass deletion guard: too many deletions
+`,
+		},
+	}
+
+	for count, test := range tests {
+		summary := newTestApplySummary()
+		test.record(summary)
+
+		var buffer bytes.Buffer
+		printApplySummary(&buffer, summary, test.blockedDeletions)
+		if buffer.String() != test.expectedOutput {
+			t.Errorf("Test[%d] Failed: Expected output\n%s\nbut got\n%s", count, test.expectedOutput, buffer.String())
+		}
+	}
+}
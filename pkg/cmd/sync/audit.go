@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/audit"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+)
+
+// auditHooks prints the inconsistencies between the hooks on GitHub and the BuildConfigs
+func auditHooks(options *Options) {
+	// the audit never changes anything
+	options.DryRun = true
+	options.UpdateStatus = false
+
+	s := newSyncer(context.Background(), options, nil)
+	if err := s.controller.ListOnce(); err != nil {
+		glog.Fatalf("Failed to list the BuildConfigs: %v", err)
+	}
+
+	// the desired hooks include the ones on repositories outside of the organization
+	desired, unmanaged, err := s.controller.DesiredHooks()
+	if err != nil {
+		glog.Fatalf("Failed to retrieve the desired hooks: %v", err)
+	}
+	actual, err := s.reconciler.ActualHooksFunc()
+	if err != nil {
+		glog.Fatalf("Failed to retrieve the actual hooks: %v", err)
+	}
+
+	auditor := &audit.Auditor{
+		Organization:          options.OrganizationName,
+		KeyFunc:               s.keyFunc,
+		BuildConfigExistsFunc: s.controller.HasBuildConfig,
+		Unmanaged:             unmanaged,
+	}
+	findings := auditor.Audit(desired, actual)
+
+	switch options.Output {
+	case "json":
+		data, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			glog.Fatalf("Failed to marshal the audit findings: %v", err)
+		}
+		fmt.Println(string(data))
+	default:
+		printFindings(os.Stdout, findings)
+	}
+}
+
+// printFindings prints the given findings in a table
+func printFindings(out io.Writer, findings []audit.Finding) {
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "No inconsistencies found between the GitHub hooks and the BuildConfigs.")
+		return
+	}
+
+	w := &tabwriter.Writer{}
+	w.Init(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", "CATEGORY", "REPOSITORY", "BUILDCONFIG", "HOOK", "MESSAGE")
+	for _, finding := range findings {
+		hookID := "-"
+		if finding.HookID > 0 {
+			hookID = fmt.Sprintf("%d", finding.HookID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", finding.Category, finding.Repository, finding.BuildConfig, hookID, finding.Message)
+	}
+	w.Flush()
+}
@@ -5,21 +5,86 @@ import (
 	"os"
 	"time"
 
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/approval"
 	"github.com/vbehar/openshift-github-hooks/pkg/cmd"
+	"github.com/vbehar/openshift-github-hooks/pkg/github"
+	"github.com/vbehar/openshift-github-hooks/pkg/leaderelection"
 	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/policy"
 
+	"github.com/golang/glog"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Options represents the command's options
 type Options struct {
 	GithubBaseURL            string
 	GithubInsecureSkipVerify bool
+	GithubTimeouts           github.Timeouts
 	OrganizationName         string
 	Token                    string
 	OpenshiftPublicURL       string
+	WebhookAPIPath           string
+	WebhookURL               openshift.WebhookURLOptions
+	Relay                    RelayOptions
+	SecretReferences         bool
+	SecretRefreshPeriod      time.Duration
+	PolicyFile               string
+	PolicyConfigMap          string
+	TeamCheck                TeamCheckOptions
+	Approval                 ApprovalOptions
 	ResyncPeriod             time.Duration
+	DeletionGracePeriod      time.Duration
+	ShutdownTimeout          time.Duration
 	DryRun                   bool
+	UpdateStatus             bool
+	LeaderElection           LeaderElectionOptions
+	MassDeletion             MassDeletionOptions
+	RetryPolicy              openshift.RetryPolicy
+	Output                   string
+}
+
+// TeamCheckOptions represents the GitHub team ownership check options
+type TeamCheckOptions struct {
+	Enabled bool
+	TTL     time.Duration
+}
+
+// ApprovalOptions represents the approval workflow options
+type ApprovalOptions struct {
+	Required    bool
+	Namespace   string
+	Name        string
+	CheckPeriod time.Duration
+}
+
+// RelayOptions represents the relay options
+type RelayOptions struct {
+	URL string
+	Key string
+}
+
+// MassDeletionOptions represents the mass deletion guard options
+type MassDeletionOptions struct {
+	MaxCount   int
+	MaxPercent int
+	Allow      bool
+	Namespace  string
+	Name       string
+}
+
+// LeaderElectionOptions represents the leader election options
+type LeaderElectionOptions struct {
+	Enabled       bool
+	LockType      string
+	Namespace     string
+	Name          string
+	Identity      string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
 }
 
 var (
@@ -32,7 +97,26 @@ var (
 	$ %[1]s --organization=my-org --github-token=...
 
 	# Start the sync daemon, and log each hook that has been created or deleted
-	$ %[1]s --organization=my-org --github-token=... --v=1`
+	$ %[1]s --organization=my-org --github-token=... --v=1
+
+	# Start the sync daemon, and keep the hooks of deleted BuildConfigs for 5 minutes
+	# (so that they are not re-created if the BuildConfigs are re-created in the meantime)
+	$ %[1]s --organization=my-org --github-token=... --deletion-grace-period=5m
+
+	# Start the sync daemon, and refuse to delete more than 20 hooks (or more than 25%% of the hooks) at once
+	$ %[1]s --organization=my-org --github-token=... --max-deletions=20 --max-deletions-percent=25
+
+	# Start the sync daemon, with Webhooks URLs targeting a dedicated router (if the master is not reachable by GitHub)
+	$ %[1]s --organization=my-org --github-token=... --webhook-route=github-hooks/webhooks --webhook-path-prefix=/openshift
+
+	# Start the sync daemon, with hooks targeting the relay (see the relay command)
+	$ %[1]s --organization=my-org --github-token=... --relay-url=https://relay.example.com --relay-key=...
+
+	# Start the sync daemon, and generate the Webhooks URLs with the build.openshift.io API group path
+	$ %[1]s --organization=my-org --github-token=... --webhook-api-path=apis/build.openshift.io/v1
+
+	# Start the sync daemon with leader election, to run multiple replicas
+	$ %[1]s --organization=my-org --github-token=... --leader-elect --leader-elect-namespace=github-hooks-controller`
 
 	syncCmd = &cobra.Command{
 		Use:   "sync",
@@ -49,41 +133,302 @@ As it use the GitHub API to create/delete the hooks, it needs a GitHub Token to
 Note that the token requires the "repo" and "admin:repo_hook" scopes.
 It can be set either with the --github-token flag, or the GITHUB_ACCESS_TOKEN environment variable.`,
 		PreRunE: func(command *cobra.Command, args []string) error {
-			if len(options.Token) == 0 {
-				return fmt.Errorf("Empty GitHub Access Token. Please provide one either with the --github-token flag or the GITHUB_ACCESS_TOKEN environment variable.")
-			}
-			if len(options.OrganizationName) == 0 {
-				return fmt.Errorf("Empty GitHub Organization Name. Please provide one either with the --organization flag or the GITHUB_ORGANIZATION environment variable.")
+			if options.SecretReferences && options.SecretRefreshPeriod <= 0 {
+				return fmt.Errorf("Invalid secret references refresh period %v. Please provide a positive duration with the --secret-references-refresh-period flag.", options.SecretRefreshPeriod)
 			}
-			return nil
+			return validateOptions(options)
 		},
 		Run: func(command *cobra.Command, args []string) {
-			syncHooks(options)
+			if err := syncHooks(options); err != nil {
+				glog.Errorf("%v", err)
+				glog.Flush()
+				os.Exit(1)
+			}
 		},
 	}
 
 	options = &Options{}
+
+	planCmdExample = `
+	# Show the hooks that would be created, updated or deleted for the "my-org" organization
+	$ %[1]s --organization=my-org --github-token=...
+
+	# Show the changes in JSON, for a CI job
+	$ %[1]s --organization=my-org --github-token=... -o json`
+
+	planCmd = &cobra.Command{
+		Use:   "plan",
+		Short: "Show the GitHub hooks changes that the sync command would apply",
+		Long: `
+The plan command lists all the BuildConfigs and all the GitHub hooks once,
+and prints the hooks that the sync command would create, update or delete - without changing anything.
+The secrets of the Webhooks URLs are masked.
+
+It exits with the code 2 if some hooks are not in sync with the BuildConfigs (and 0 otherwise),
+so that it can be used to detect drift, for example in a CI job.`,
+		PreRunE: func(command *cobra.Command, args []string) error {
+			switch planOptions.Output {
+			case "", "json":
+			default:
+				return fmt.Errorf("Invalid output format %s. Please use json, or nothing for the default format.", planOptions.Output)
+			}
+			return validateOptions(planOptions)
+		},
+		Run: func(command *cobra.Command, args []string) {
+			planHooks(planOptions)
+		},
+	}
+
+	planOptions = &Options{}
+
+	applyCmdExample = `
+	# Reconcile once the hooks of all the repositories in the "my-org" organization
+	$ %[1]s --organization=my-org --github-token=...
+
+	# Reconcile once, but refuse to delete more than 20 hooks at once
+	$ %[1]s --organization=my-org --github-token=... --max-deletions=20`
+
+	applyCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Create or delete GitHub hooks once, based on OpenShift BuildConfig triggers",
+		Long: `
+The apply command lists all the BuildConfigs and all the GitHub hooks once,
+creates, updates and deletes the hooks so that they are in sync with the BuildConfigs (like a resync of the sync command),
+prints a summary of the changes, and exits - it can be run periodically, for example by a CronJob,
+instead of the long-running sync command.
+
+It accepts the same filters and safety guards as the sync command (policy, team check, approvals, mass deletion guard, ...).
+It exits with the code 1 if some changes failed, or if the deletions have been blocked by the mass deletion guard.`,
+		PreRunE: func(command *cobra.Command, args []string) error {
+			return validateOptions(applyOptions)
+		},
+		Run: func(command *cobra.Command, args []string) {
+			applyHooks(applyOptions)
+		},
+	}
+
+	applyOptions = &Options{}
+
+	auditCmdExample = `
+	# Report the inconsistencies between the GitHub hooks of the "my-org" organization and the BuildConfigs
+	$ %[1]s --organization=my-org --github-token=...
+
+	# Report the inconsistencies in JSON
+	$ %[1]s --organization=my-org --github-token=... -o json`
+
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Report the inconsistencies between the GitHub hooks and the OpenShift BuildConfigs",
+		Long: `
+The audit command lists all the BuildConfigs and all the GitHub hooks once, cross-references them,
+and reports the inconsistencies - without changing anything:
+
+- orphan-hook: a hook targets a BuildConfig that does not exist (or that is not synced anymore)
+- missing-hook: a BuildConfig has a GitHub trigger, but no hook on its repository
+- stale-secret: a hook does not use the current secret of the BuildConfig's GitHub trigger
+- duplicate-hook: several hooks of the same repository target the same BuildConfig
+- external-repository: a BuildConfig hooks a repository outside of the organization (its hook is not managed)
+- disabled-hook: a hook has been deactivated on GitHub`,
+		PreRunE: func(command *cobra.Command, args []string) error {
+			switch auditOptions.Output {
+			case "", "json":
+			default:
+				return fmt.Errorf("Invalid output format %s. Please use json, or nothing for the default table format.", auditOptions.Output)
+			}
+			return validateOptions(auditOptions)
+		},
+		Run: func(command *cobra.Command, args []string) {
+			auditHooks(auditOptions)
+		},
+	}
+
+	auditOptions = &Options{}
 )
 
 func init() {
+	defaultRetryPolicy := openshift.DefaultRetryPolicy()
+
 	cmd.RootCmd.AddCommand(syncCmd)
 
 	syncCmd.Example = fmt.Sprintf(syncCmdExample, cmd.FullName(syncCmd))
 
 	syncCmd.Flags().AddFlagSet(openshift.Flags)
+	addCommonFlags(syncCmd.Flags(), options)
+	addDeletionLimitsFlags(syncCmd.Flags(), options)
+	addApplyFlags(syncCmd.Flags(), options)
+	addApprovalFlags(syncCmd.Flags(), options)
+
+	syncCmd.Flags().DurationVar(&options.ResyncPeriod, "resync-period", 1*time.Hour,
+		"If not zero, defines the interval of time to perform a full resync of all the webhooks.")
+	syncCmd.Flags().DurationVar(&options.SecretRefreshPeriod, "secret-references-refresh-period", 1*time.Minute,
+		"The interval at which the Secrets referenced by the GitHub triggers are retrieved again, to update the hooks when they are rotated (with --secret-references).")
+	syncCmd.Flags().DurationVar(&options.DeletionGracePeriod, "deletion-grace-period", 0,
+		"If not zero, defines the delay before deleting the hook of a deleted BuildConfig. The deletion is cancelled if the BuildConfig is re-created in the meantime.")
+	syncCmd.Flags().Float32Var(&options.RetryPolicy.QPS, "queue-qps", defaultRetryPolicy.QPS,
+		"The maximum number of failed hook changes re-queued per second.")
+	syncCmd.Flags().IntVar(&options.RetryPolicy.Burst, "queue-burst", defaultRetryPolicy.Burst,
+		"The maximum burst of failed hook changes re-queued.")
+	syncCmd.Flags().DurationVar(&options.RetryPolicy.DeadLetterRetryPeriod, "dead-letter-retry-period", defaultRetryPolicy.DeadLetterRetryPeriod,
+		"The interval at which the hook changes of the dead-letter list (that failed after all their retries) are retried. 0 means never.")
+	syncCmd.Flags().DurationVar(&options.ShutdownTimeout, "shutdown-timeout", 30*time.Second,
+		"The maximum duration to wait for the in-flight hook operations on shutdown. If they don't complete in time, the command exits with a non-zero code.")
+	syncCmd.Flags().BoolVar(&options.LeaderElection.Enabled, "leader-elect", false,
+		"Enable leader election, to run multiple replicas of the sync daemon. Only the leader will create/delete hooks, the others are standby replicas.")
+	syncCmd.Flags().StringVar(&options.LeaderElection.LockType, "leader-elect-lock-type", leaderelection.ConfigMapsLockType,
+		fmt.Sprintf("The type of object used to hold the leader election lock: %s or %s.", leaderelection.ConfigMapsLockType, leaderelection.EndpointsLockType))
+	syncCmd.Flags().StringVar(&options.LeaderElection.Namespace, "leader-elect-namespace", cmd.GetenvWithDefault("POD_NAMESPACE", "default"),
+		"The namespace of the leader election lock object - could also be defined by the POD_NAMESPACE env var.")
+	syncCmd.Flags().StringVar(&options.LeaderElection.Name, "leader-elect-name", "openshift-github-hooks-sync",
+		"The name of the leader election lock object.")
+	syncCmd.Flags().StringVar(&options.LeaderElection.Identity, "leader-elect-identity", defaultIdentity(),
+		"The identity of this replica for the leader election. Default to the hostname (the pod name).")
+	syncCmd.Flags().DurationVar(&options.LeaderElection.LeaseDuration, "leader-elect-lease-duration", 15*time.Second,
+		"The duration that standby replicas will wait before trying to acquire the leadership, after the last leader renewal.")
+	syncCmd.Flags().DurationVar(&options.LeaderElection.RenewDeadline, "leader-elect-renew-deadline", 10*time.Second,
+		"The duration that the leader will retry to renew its leadership before giving up.")
+	syncCmd.Flags().DurationVar(&options.LeaderElection.RetryPeriod, "leader-elect-retry-period", 2*time.Second,
+		"The duration that the replicas will wait between tries to acquire or renew the leadership.")
+	syncCmd.Flags().DurationVar(&options.Approval.CheckPeriod, "approvals-check-period", 1*time.Minute,
+		"The interval at which the approval requests are checked, to create the approved hooks without waiting for the next resync.")
+
+	cmd.RootCmd.AddCommand(planCmd)
+
+	planCmd.Example = fmt.Sprintf(planCmdExample, cmd.FullName(planCmd))
+
+	planCmd.Flags().AddFlagSet(openshift.Flags)
+	addCommonFlags(planCmd.Flags(), planOptions)
+	addDeletionLimitsFlags(planCmd.Flags(), planOptions)
+	addApprovalFlags(planCmd.Flags(), planOptions)
+	planCmd.Flags().StringVarP(&planOptions.Output, "output", "o", "",
+		"The output format: json, or nothing for the default human-readable format.")
+
+	cmd.RootCmd.AddCommand(applyCmd)
+
+	applyCmd.Example = fmt.Sprintf(applyCmdExample, cmd.FullName(applyCmd))
+
+	applyCmd.Flags().AddFlagSet(openshift.Flags)
+	addCommonFlags(applyCmd.Flags(), applyOptions)
+	addDeletionLimitsFlags(applyCmd.Flags(), applyOptions)
+	addApplyFlags(applyCmd.Flags(), applyOptions)
+	addApprovalFlags(applyCmd.Flags(), applyOptions)
+
+	cmd.RootCmd.AddCommand(auditCmd)
+
+	auditCmd.Example = fmt.Sprintf(auditCmdExample, cmd.FullName(auditCmd))
 
-	syncCmd.Flags().StringVar(&options.GithubBaseURL, "github-base-url", cmd.GetenvWithDefault("GITHUB_BASE_URL", "https://api.github.com/"),
+	auditCmd.Flags().AddFlagSet(openshift.Flags)
+	addCommonFlags(auditCmd.Flags(), auditOptions)
+	auditCmd.Flags().StringVarP(&auditOptions.Output, "output", "o", "",
+		"The output format: json, or nothing for the default table format.")
+}
+
+// addCommonFlags adds the flags shared by the sync, plan, apply and audit commands:
+// how to connect to GitHub, how to generate the Webhooks URLs, and which hooks are allowed
+func addCommonFlags(flags *pflag.FlagSet, options *Options) {
+	flags.StringVar(&options.GithubBaseURL, "github-base-url", cmd.GetenvWithDefault("GITHUB_BASE_URL", "https://api.github.com/"),
 		"The GitHub Base URL - if you use GitHub Enterprise. Could also be defined by the GITHUB_BASE_URL env var. Format: https://github.domain.tld/api/v3/")
-	syncCmd.Flags().BoolVar(&options.GithubInsecureSkipVerify, "github-insecure-skip-tls-verify", false,
+	flags.BoolVar(&options.GithubInsecureSkipVerify, "github-insecure-skip-tls-verify", false,
 		"If true, the github server's certificate will not be checked for validity. This will make your HTTPS connections insecure.")
-	syncCmd.Flags().StringVar(&options.Token, "github-token", os.Getenv("GITHUB_ACCESS_TOKEN"),
+	flags.DurationVar(&options.GithubTimeouts.Request, "github-request-timeout", 30*time.Second,
+		"The timeout of a single request to the GitHub API. 0 means no timeout.")
+	flags.DurationVar(&options.GithubTimeouts.Operation, "github-operation-timeout", 10*time.Minute,
+		"The timeout of an operation on the GitHub API (creating a hook, listing all the hooks of the organization, ...), which may perform multiple requests. 0 means no timeout.")
+	flags.StringVar(&options.Token, "github-token", os.Getenv("GITHUB_ACCESS_TOKEN"),
 		"The GitHub Access Token - could also be defined by the GITHUB_ACCESS_TOKEN env var. See https://github.com/settings/tokens to get one.")
-	syncCmd.Flags().DurationVar(&options.ResyncPeriod, "resync-period", 1*time.Hour,
-		"If not zero, defines the interval of time to perform a full resync of all the webhooks.")
-	syncCmd.Flags().StringVar(&options.OrganizationName, "organization", os.Getenv("GITHUB_ORGANIZATION"),
+	flags.StringVar(&options.OrganizationName, "organization", os.Getenv("GITHUB_ORGANIZATION"),
 		"The name of the GitHub Organization for which we will sync the webhooks - could also be defined by the GITHUB_ORGANIZATION env var.")
-	syncCmd.Flags().BoolVar(&options.DryRun, "dry-run", false,
-		"Run in dry-run mode (does not really create/delete hooks on github).")
-	syncCmd.Flags().StringVar(&options.OpenshiftPublicURL, "openshift-public-url", openshift.DefaultOpenshiftPublicURL(),
+	flags.StringVar(&options.OpenshiftPublicURL, "openshift-public-url", openshift.DefaultOpenshiftPublicURL(),
 		"The public URL of your OpenShift Master, used to generate the Webhooks URLs.")
+	flags.StringVar(&options.WebhookURL.BaseURL, "webhook-base-url", "",
+		"An external base URL reachable by GitHub (a router or ingress host), used instead of the OpenShift public URL to generate the Webhooks URLs.")
+	flags.StringVar(&options.WebhookURL.Route, "webhook-route", "",
+		"The namespace/name of a Route whose host is used instead of the OpenShift public URL to generate the Webhooks URLs. Ignored if --webhook-base-url is set.")
+	flags.StringVar(&options.WebhookURL.PathPrefix, "webhook-path-prefix", "",
+		"A path prefix added after the base URL of the Webhooks URLs, if the router or proxy exposes the OpenShift API under a sub-path.")
+	flags.StringVar(&options.WebhookAPIPath, "webhook-api-path", "",
+		fmt.Sprintf("The API path of the generated Webhooks URLs: %s (legacy) or %s (API group). Default to the path generated by the OpenShift client. Existing hooks using the other path are updated instead of being duplicated.", openshift.LegacyWebhookAPIPath, openshift.GroupWebhookAPIPath))
+	flags.StringVar(&options.Relay.URL, "relay-url", "",
+		"The public URL of the relay (see the relay command). If set, the hooks target the relay instead of OpenShift, and the BuildConfigs trigger secrets never leave the cluster.")
+	flags.StringVar(&options.Relay.Key, "relay-key", os.Getenv("RELAY_KEY"),
+		"The relay key, used to derive the secret of each hook targeting the relay - could also be defined by the RELAY_KEY env var. It must be the same for the sync and relay commands.")
+	flags.BoolVar(&options.SecretReferences, "secret-references", false,
+		fmt.Sprintf("Support the GitHub triggers that reference their secret from a Secret object (in its %s key) instead of setting it inline. Requires the permission to get the Secrets.", openshift.WebHookSecretKey))
+	flags.StringVar(&options.PolicyFile, "policy-file", "",
+		"A policy file (YAML or JSON) restricting which namespaces may hook which repositories. Optional (default to allow everything).")
+	flags.StringVar(&options.PolicyConfigMap, "policy-configmap", "",
+		fmt.Sprintf("The namespace/name of a configmap containing the policy (in its %s key), restricting which namespaces may hook which repositories. Ignored if --policy-file is set.", policy.ConfigMapKey))
+	flags.BoolVar(&options.TeamCheck.Enabled, "team-check", false,
+		fmt.Sprintf("Only allow the BuildConfigs to hook the repositories on which the GitHub team of their namespace (set with the %s annotation) has the admin or write permission. Requires the read:org scope.", api.GithubTeamAnnotation))
+	flags.DurationVar(&options.TeamCheck.TTL, "team-check-ttl", 10*time.Minute,
+		"The duration during which the teams permissions retrieved from GitHub are cached.")
+}
+
+// addDeletionLimitsFlags adds the flags defining the mass deletion limits
+func addDeletionLimitsFlags(flags *pflag.FlagSet, options *Options) {
+	flags.IntVar(&options.MassDeletion.MaxCount, "max-deletions", 10,
+		"The maximum number of hooks that a single resync can delete. Above it, the deletions are blocked until explicitly allowed. 0 means no limit.")
+	flags.IntVar(&options.MassDeletion.MaxPercent, "max-deletions-percent", 50,
+		"The maximum percentage of the managed hooks that a single resync can delete. Above it, the deletions are blocked until explicitly allowed. 0 means no limit.")
+}
+
+// addApplyFlags adds the flags shared by the sync and apply commands:
+// how the hooks changes are applied and retried, and the mass deletion guard
+func addApplyFlags(flags *pflag.FlagSet, options *Options) {
+	defaultRetryPolicy := openshift.DefaultRetryPolicy()
+
+	flags.IntVar(&options.RetryPolicy.MaxRetries, "max-retries", defaultRetryPolicy.MaxRetries,
+		"The number of times a failed hook change is retried (before being moved to the dead-letter list, for the sync command).")
+	flags.DurationVar(&options.RetryPolicy.InitialBackoff, "retry-initial-backoff", defaultRetryPolicy.InitialBackoff,
+		"The delay before the first retry of a failed hook change. It is doubled on each retry.")
+	flags.DurationVar(&options.RetryPolicy.MaxBackoff, "retry-max-backoff", defaultRetryPolicy.MaxBackoff,
+		"The maximum delay between 2 retries of a failed hook change.")
+	flags.BoolVar(&options.MassDeletion.Allow, "allow-mass-deletion", false,
+		"Allow the resyncs (or the apply command) to delete more hooks than the --max-deletions and --max-deletions-percent limits. Use with care!")
+	flags.StringVar(&options.MassDeletion.Namespace, "guard-namespace", cmd.GetenvWithDefault("POD_NAMESPACE", "default"),
+		"The namespace of the guard configmap - could also be defined by the POD_NAMESPACE env var.")
+	flags.StringVar(&options.MassDeletion.Name, "guard-configmap", "openshift-github-hooks-sync-guard",
+		fmt.Sprintf("The name of the guard configmap, that can be annotated with %s=true to allow a single blocked mass deletion.", api.AllowMassDeletionAnnotation))
+	flags.BoolVar(&options.DryRun, "dry-run", false,
+		"Run in dry-run mode (does not really create/delete hooks on github).")
+	flags.BoolVar(&options.UpdateStatus, "update-status", true,
+		"Write the sync status (hook IDs, repository, last sync time, last error) in the BuildConfigs annotations. Requires the permission to update BuildConfigs. Ignored in dry-run mode.")
+}
+
+// addApprovalFlags adds the flags shared by the sync, plan and apply commands
+// to hold the hooks on the repositories that have never been hooked before
+func addApprovalFlags(flags *pflag.FlagSet, options *Options) {
+	flags.BoolVar(&options.Approval.Required, "require-approval", false,
+		"Require an approval before creating a hook on a repository that has never been hooked before. The requests are recorded in a configmap, and can be listed with the pending command and approved with the approve command.")
+	flags.StringVar(&options.Approval.Namespace, "approvals-namespace", cmd.GetenvWithDefault("POD_NAMESPACE", "default"),
+		"The namespace of the configmap storing the approval requests - could also be defined by the POD_NAMESPACE env var.")
+	flags.StringVar(&options.Approval.Name, "approvals-configmap", approval.DefaultConfigMapName,
+		"The name of the configmap storing the approval requests.")
+}
+
+// validateOptions checks the options shared by the sync, plan, apply and audit commands
+func validateOptions(options *Options) error {
+	if len(options.Token) == 0 {
+		return fmt.Errorf("Empty GitHub Access Token. Please provide one either with the --github-token flag or the GITHUB_ACCESS_TOKEN environment variable.")
+	}
+	if len(options.OrganizationName) == 0 {
+		return fmt.Errorf("Empty GitHub Organization Name. Please provide one either with the --organization flag or the GITHUB_ORGANIZATION environment variable.")
+	}
+	if len(options.Relay.URL) > 0 && len(options.Relay.Key) == 0 {
+		return fmt.Errorf("Empty relay key. Please provide one either with the --relay-key flag or the RELAY_KEY environment variable.")
+	}
+	switch options.WebhookAPIPath {
+	case "", openshift.LegacyWebhookAPIPath, openshift.GroupWebhookAPIPath:
+	default:
+		return fmt.Errorf("Invalid webhook API path %s. Please use either %s or %s.", options.WebhookAPIPath, openshift.LegacyWebhookAPIPath, openshift.GroupWebhookAPIPath)
+	}
+	return nil
+}
+
+// defaultIdentity returns the default identity for the leader election: the hostname
+func defaultIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
 }
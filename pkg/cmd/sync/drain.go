@@ -0,0 +1,53 @@
+package sync
+
+import (
+	"sync"
+	"time"
+)
+
+// inFlightOperations tracks the in-flight GitHub operations,
+// so that they can be drained on shutdown
+type inFlightOperations struct {
+	wg       sync.WaitGroup
+	lock     sync.Mutex
+	draining bool
+}
+
+// start registers a new operation, and returns false if we are draining
+// (in which case the operation should not be started, and github.ErrShuttingDown returned)
+func (o *inFlightOperations) start() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.draining {
+		return false
+	}
+	o.wg.Add(1)
+	return true
+}
+
+// done marks a started operation as completed
+func (o *inFlightOperations) done() {
+	o.wg.Done()
+}
+
+// drain stops accepting new operations, and waits for the in-flight operations
+// to complete, up to the given timeout.
+// It returns true if all the in-flight operations completed.
+func (o *inFlightOperations) drain(timeout time.Duration) bool {
+	o.lock.Lock()
+	o.draining = true
+	o.lock.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,78 @@
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInFlightOperationsDrain(t *testing.T) {
+	tests := []struct {
+		inFlight       int
+		completed      int
+		expectedResult bool
+	}{
+		// nothing in flight
+		{
+			inFlight:       0,
+			completed:      0,
+			expectedResult: true,
+		},
+		// all the in-flight operations complete before the timeout
+		{
+			inFlight:       2,
+			completed:      2,
+			expectedResult: true,
+		},
+		// an operation is still in flight at the timeout
+		{
+			inFlight:       2,
+			completed:      1,
+			expectedResult: false,
+		},
+	}
+
+	for count, test := range tests {
+		operations := &inFlightOperations{}
+		for i := 0; i < test.inFlight; i++ {
+			if !operations.start() {
+				t.Fatalf("Test[%d] Failed: Expected the operation %d to start", count, i)
+			}
+		}
+		go func(completed int) {
+			for i := 0; i < completed; i++ {
+				operations.done()
+			}
+		}(test.completed)
+
+		result := operations.drain(100 * time.Millisecond)
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected drained %v but got %v", count, test.expectedResult, result)
+		}
+		if operations.start() {
+			t.Errorf("Test[%d] Failed: Expected no new operation to start once draining", count)
+		}
+	}
+}
+
+func TestInFlightOperationsDrainWaitsForOperations(t *testing.T) {
+	operations := &inFlightOperations{}
+	if !operations.start() {
+		t.Fatalf("Expected the operation to start")
+	}
+
+	completed := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(completed)
+		operations.done()
+	}()
+
+	if !operations.drain(5 * time.Second) {
+		t.Errorf("Expected the in-flight operation to be drained")
+	}
+	select {
+	case <-completed:
+	default:
+		t.Errorf("Expected drain to wait for the in-flight operation to complete")
+	}
+}
@@ -0,0 +1,102 @@
+package sync
+
+import (
+	"strconv"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/golang/glog"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/client/record"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// Reasons of the events recorded against the guard configmap
+const (
+	// MassDeletionBlockedReason is used when a mass deletion of hooks has been blocked
+	MassDeletionBlockedReason = "MassDeletionBlocked"
+
+	// MassDeletionAllowedReason is used when a mass deletion of hooks has been explicitly allowed
+	MassDeletionAllowedReason = "MassDeletionAllowed"
+)
+
+// massDeletionGuard decides if a blocked mass deletion of hooks can be applied anyway,
+// either because of the --allow-mass-deletion flag, or because of the
+// "allow-mass-deletion" annotation on the guard configmap (a one-shot override)
+type massDeletionGuard struct {
+	client      kclient.ConfigMapsNamespacer
+	recorder    record.EventRecorder
+	namespace   string
+	name        string
+	alwaysAllow bool
+}
+
+// allow returns true if the mass deletion has been explicitly allowed.
+// The override annotation is removed once consumed.
+func (g *massDeletionGuard) allow(err error) bool {
+	if g.alwaysAllow {
+		g.recorder.Eventf(g.reference(), kapi.EventTypeWarning, MassDeletionAllowedReason, "%v - allowed by the --allow-mass-deletion flag", err)
+		return true
+	}
+
+	configMap, getErr := g.client.ConfigMaps(g.namespace).Get(g.name)
+	if getErr != nil {
+		if !kerrors.IsNotFound(getErr) {
+			glog.Errorf("Failed to get the guard configmap %s/%s: %v", g.namespace, g.name, getErr)
+		}
+		return false
+	}
+
+	allowStr, found := configMap.Annotations[api.AllowMassDeletionAnnotation]
+	if !found {
+		return false
+	}
+	allow, parseErr := strconv.ParseBool(allowStr)
+	if parseErr != nil {
+		glog.Errorf("Failed to parse annotation value '%v' for %s on configmap %s/%s: %v", allowStr, api.AllowMassDeletionAnnotation, g.namespace, g.name, parseErr)
+		return false
+	}
+	if !allow {
+		return false
+	}
+
+	// consume the override, so that the next mass deletion is blocked again
+	delete(configMap.Annotations, api.AllowMassDeletionAnnotation)
+	if _, updateErr := g.client.ConfigMaps(g.namespace).Update(configMap); updateErr != nil {
+		glog.Errorf("Failed to remove the annotation %s from configmap %s/%s: %v", api.AllowMassDeletionAnnotation, g.namespace, g.name, updateErr)
+		return false
+	}
+
+	g.recorder.Eventf(g.reference(), kapi.EventTypeWarning, MassDeletionAllowedReason, "%v - allowed by the %s annotation", err, api.AllowMassDeletionAnnotation)
+	return true
+}
+
+// blocked records an event explaining how to allow the blocked mass deletion.
+// The guard configmap is created if it does not exist yet, so that it can be annotated.
+func (g *massDeletionGuard) blocked(err error) {
+	_, getErr := g.client.ConfigMaps(g.namespace).Get(g.name)
+	if kerrors.IsNotFound(getErr) {
+		_, getErr = g.client.ConfigMaps(g.namespace).Create(&kapi.ConfigMap{
+			ObjectMeta: kapi.ObjectMeta{
+				Namespace: g.namespace,
+				Name:      g.name,
+			},
+		})
+	}
+	if getErr != nil {
+		glog.Errorf("Failed to get or create the guard configmap %s/%s: %v", g.namespace, g.name, getErr)
+	}
+
+	g.recorder.Eventf(g.reference(), kapi.EventTypeWarning, MassDeletionBlockedReason, "%v - to allow it, run: oc annotate configmap %s -n %s %s=true", err, g.name, g.namespace, api.AllowMassDeletionAnnotation)
+}
+
+// reference returns a reference to the guard configmap, to record events against it
+func (g *massDeletionGuard) reference() *kapi.ObjectReference {
+	return &kapi.ObjectReference{
+		Kind:      "ConfigMap",
+		Namespace: g.namespace,
+		Name:      g.name,
+	}
+}
@@ -0,0 +1,201 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/client/record"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// fakeConfigMaps is a kclient.ConfigMapsNamespacer that stores a single configmap in memory
+type fakeConfigMaps struct {
+	kclient.ConfigMapsInterface
+	configMap *kapi.ConfigMap
+	creates   int
+	updates   int
+}
+
+func (f *fakeConfigMaps) ConfigMaps(namespace string) kclient.ConfigMapsInterface {
+	return f
+}
+
+func (f *fakeConfigMaps) Get(name string) (*kapi.ConfigMap, error) {
+	if f.configMap == nil {
+		return nil, kerrors.NewNotFound(kapi.Resource("configmaps"), name)
+	}
+	copied := *f.configMap
+	copied.Annotations = map[string]string{}
+	for key, value := range f.configMap.Annotations {
+		copied.Annotations[key] = value
+	}
+	return &copied, nil
+}
+
+func (f *fakeConfigMaps) Create(configMap *kapi.ConfigMap) (*kapi.ConfigMap, error) {
+	f.configMap = configMap
+	f.creates++
+	return configMap, nil
+}
+
+func (f *fakeConfigMaps) Update(configMap *kapi.ConfigMap) (*kapi.ConfigMap, error) {
+	f.configMap = configMap
+	f.updates++
+	return configMap, nil
+}
+
+func TestMassDeletionGuardAllow(t *testing.T) {
+	tests := []struct {
+		alwaysAllow         bool
+		annotations         map[string]string
+		noConfigMap         bool
+		expectedAllowed     bool
+		expectedAnnotation  bool
+		expectedEventReason string
+	}{
+		// allowed by the flag, whatever the configmap
+		{
+			alwaysAllow:         true,
+			noConfigMap:         true,
+			expectedAllowed:     true,
+			expectedEventReason: MassDeletionAllowedReason,
+		},
+		// no configmap
+		{
+			noConfigMap:     true,
+			expectedAllowed: false,
+		},
+		// no annotation
+		{
+			annotations:     map[string]string{},
+			expectedAllowed: false,
+		},
+		// annotation set to false
+		{
+			annotations:        map[string]string{api.AllowMassDeletionAnnotation: "false"},
+			expectedAllowed:    false,
+			expectedAnnotation: true,
+		},
+		// invalid annotation
+		{
+			annotations:        map[string]string{api.AllowMassDeletionAnnotation: "whatever"},
+			expectedAllowed:    false,
+			expectedAnnotation: true,
+		},
+		// allowed by the annotation, which is consumed
+		{
+			annotations:         map[string]string{api.AllowMassDeletionAnnotation: "true"},
+			expectedAllowed:     true,
+			expectedEventReason: MassDeletionAllowedReason,
+		},
+	}
+
+	for count, test := range tests {
+		client := &fakeConfigMaps{}
+		if !test.noConfigMap {
+			client.configMap = &kapi.ConfigMap{
+				ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "guard", Annotations: test.annotations},
+			}
+		}
+		recorder := &record.FakeRecorder{}
+		guard := &massDeletionGuard{
+			client:      client,
+			recorder:    recorder,
+			namespace:   "ns",
+			name:        "guard",
+			alwaysAllow: test.alwaysAllow,
+		}
+
+		allowed := guard.allow(fmt.Errorf("too many deletions"))
+		if allowed != test.expectedAllowed {
+			t.Errorf("Test[%d] Failed: Expected allowed %v but got %v", count, test.expectedAllowed, allowed)
+		}
+		if client.configMap != nil {
+			_, found := client.configMap.Annotations[api.AllowMassDeletionAnnotation]
+			if found != test.expectedAnnotation {
+				t.Errorf("Test[%d] Failed: Expected the annotation to be present: %v, but got %v", count, test.expectedAnnotation, found)
+			}
+		}
+		switch {
+		case len(test.expectedEventReason) == 0 && len(recorder.Events) > 0:
+			t.Errorf("Test[%d] Failed: Expected no event but got %v", count, recorder.Events)
+		case len(test.expectedEventReason) > 0 && (len(recorder.Events) != 1 || !strings.Contains(recorder.Events[0], test.expectedEventReason)):
+			t.Errorf("Test[%d] Failed: Expected a single %s event but got %v", count, test.expectedEventReason, recorder.Events)
+		}
+	}
+}
+
+func TestMassDeletionGuardAllowConsumesOverride(t *testing.T) {
+	client := &fakeConfigMaps{
+		configMap: &kapi.ConfigMap{
+			ObjectMeta: kapi.ObjectMeta{
+				Namespace:   "ns",
+				Name:        "guard",
+				Annotations: map[string]string{api.AllowMassDeletionAnnotation: "true"},
+			},
+		},
+	}
+	guard := &massDeletionGuard{
+		client:    client,
+		recorder:  &record.FakeRecorder{},
+		namespace: "ns",
+		name:      "guard",
+	}
+
+	for count, expected := range []bool{true, false} {
+		if allowed := guard.allow(fmt.Errorf("too many deletions")); allowed != expected {
+			t.Errorf("Test[%d] Failed: Expected allowed %v but got %v", count, expected, allowed)
+		}
+	}
+	if client.updates != 1 {
+		t.Errorf("Expected the configmap to be updated once, but got %d updates", client.updates)
+	}
+}
+
+func TestMassDeletionGuardBlocked(t *testing.T) {
+	tests := []struct {
+		existingConfigMap bool
+		expectedCreates   int
+	}{
+		// the configmap is created, so that it can be annotated
+		{
+			existingConfigMap: false,
+			expectedCreates:   1,
+		},
+		// the existing configmap is kept
+		{
+			existingConfigMap: true,
+			expectedCreates:   0,
+		},
+	}
+
+	for count, test := range tests {
+		client := &fakeConfigMaps{}
+		if test.existingConfigMap {
+			client.configMap = &kapi.ConfigMap{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "guard"}}
+		}
+		recorder := &record.FakeRecorder{}
+		guard := &massDeletionGuard{
+			client:    client,
+			recorder:  recorder,
+			namespace: "ns",
+			name:      "guard",
+		}
+
+		guard.blocked(fmt.Errorf("too many deletions"))
+		if client.creates != test.expectedCreates {
+			t.Errorf("Test[%d] Failed: Expected %d creates but got %d", count, test.expectedCreates, client.creates)
+		}
+		if client.configMap == nil {
+			t.Errorf("Test[%d] Failed: Expected the guard configmap to exist", count)
+		}
+		if len(recorder.Events) != 1 || !strings.Contains(recorder.Events[0], MassDeletionBlockedReason) || !strings.Contains(recorder.Events[0], api.AllowMassDeletionAnnotation) {
+			t.Errorf("Test[%d] Failed: Expected a single %s event explaining the override, but got %v", count, MassDeletionBlockedReason, recorder.Events)
+		}
+	}
+}
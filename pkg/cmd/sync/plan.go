@@ -0,0 +1,200 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/approval"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/reconciler"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+)
+
+// driftExitCode is the exit code of the plan command when the hooks are not in sync
+// (1 is used for the errors)
+const driftExitCode = 2
+
+// Actions of the planned changes
+const (
+	createAction = "create"
+	updateAction = "update"
+	deleteAction = "delete"
+)
+
+// plannedChange is a single change of the plan, as printed by the plan command.
+// The secrets of the URLs are masked.
+type plannedChange struct {
+	Action            string `json:"action"`
+	Repository        string `json:"repository"`
+	BuildConfig       string `json:"buildConfig,omitempty"`
+	HookID            int    `json:"hookID,omitempty"`
+	TargetURL         string `json:"targetURL"`
+	PreviousTargetURL string `json:"previousTargetURL,omitempty"`
+
+	// PendingApproval is true for a hook that won't be created until its repository is approved
+	PendingApproval bool `json:"pendingApproval,omitempty"`
+}
+
+// planOutput is the output of the plan command.
+// The hooks pending approval are not counted in the hooks to create.
+type planOutput struct {
+	Changes         []plannedChange `json:"changes"`
+	Create          int             `json:"create"`
+	Update          int             `json:"update"`
+	Delete          int             `json:"delete"`
+	PendingApproval int             `json:"pendingApproval"`
+
+	// BlockedDeletions explains why the deletions would be blocked by the mass deletion guard
+	BlockedDeletions string `json:"blockedDeletions,omitempty"`
+}
+
+// planHooks prints the changes that the sync command would apply,
+// and exits with the driftExitCode if there are some
+func planHooks(options *Options) {
+	// the plan never changes anything
+	options.DryRun = true
+	options.UpdateStatus = false
+
+	s := newSyncer(context.Background(), options, nil)
+	if err := s.controller.ListOnce(); err != nil {
+		glog.Fatalf("Failed to list the BuildConfigs: %v", err)
+	}
+
+	plan, actual, err := s.reconciler.Plan()
+	if err != nil {
+		glog.Fatalf("Failed to compute the plan: %v", err)
+	}
+
+	output := newPlanOutput(plan, actual, s.keyFunc, pendingApprovalFunc(s.approvalGate))
+	if err := s.reconciler.CheckDeletions(plan, actual); err != nil {
+		output.BlockedDeletions = err.Error()
+	}
+
+	switch options.Output {
+	case "json":
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			glog.Fatalf("Failed to marshal the plan: %v", err)
+		}
+		fmt.Println(string(data))
+	default:
+		printPlan(os.Stdout, output)
+	}
+
+	if output.Create > 0 || output.Update > 0 || output.Delete > 0 {
+		glog.Flush()
+		os.Exit(driftExitCode)
+	}
+}
+
+// pendingApprovalFunc returns a func checking if a hook to create is pending approval
+// (the sync command would not create it), or nil if there is no approval gate
+func pendingApprovalFunc(gate *approval.Gate) func(hook api.Hook) bool {
+	if gate == nil {
+		return nil
+	}
+	return func(hook api.Hook) bool {
+		allowed, err := gate.IsAllowed(hook.GithubRepository)
+		if err != nil {
+			glog.Warningf("Failed to check if the repository %s is approved: %v", hook.GithubRepository, err)
+			return false
+		}
+		return !allowed
+	}
+}
+
+// newPlanOutput converts the given plan to the plan command output.
+// The pendingFunc checks if a hook to create is pending approval (optional).
+func newPlanOutput(plan reconciler.Plan, actual []api.Hook, keyFunc reconciler.KeyFunc, pendingFunc func(hook api.Hook) bool) planOutput {
+	previousURLs := map[int]string{}
+	for _, hook := range actual {
+		previousURLs[hook.ID] = hook.TargetURL
+	}
+
+	output := planOutput{
+		Changes: []plannedChange{},
+		Update:  len(plan.Update),
+		Delete:  len(plan.Delete),
+	}
+	for _, change := range []struct {
+		action string
+		hooks  []api.Hook
+	}{
+		{action: createAction, hooks: plan.Create},
+		{action: updateAction, hooks: plan.Update},
+		{action: deleteAction, hooks: plan.Delete},
+	} {
+		for _, hook := range change.hooks {
+			key, _ := keyFunc(hook)
+			planned := plannedChange{
+				Action:      change.action,
+				Repository:  hook.GithubRepository.String(),
+				BuildConfig: key,
+				HookID:      hook.ID,
+				TargetURL:   openshift.MaskOpenshiftHookSecret(hook.TargetURL),
+			}
+			switch {
+			case change.action == updateAction:
+				planned.PreviousTargetURL = openshift.MaskOpenshiftHookSecret(previousURLs[hook.ID])
+			case change.action == createAction && pendingFunc != nil && pendingFunc(hook):
+				planned.PendingApproval = true
+				output.PendingApproval++
+			case change.action == createAction:
+				output.Create++
+			}
+			output.Changes = append(output.Changes, planned)
+		}
+	}
+	return output
+}
+
+// printPlan prints the given plan in a human-readable format:
+// one line per change ("+" to create, "~" to update, "-" to delete, "?" pending approval), followed by its URL
+func printPlan(w io.Writer, output planOutput) {
+	if len(output.Changes) == 0 {
+		fmt.Fprintln(w, "No changes. The GitHub hooks are in sync with the BuildConfigs.")
+		return
+	}
+
+	symbols := map[string]string{
+		createAction: "+",
+		updateAction: "~",
+		deleteAction: "-",
+	}
+	for _, change := range output.Changes {
+		symbol := symbols[change.Action]
+		if change.PendingApproval {
+			symbol = "?"
+		}
+		fmt.Fprintf(w, "  %s %s", symbol, change.Repository)
+		if len(change.BuildConfig) > 0 {
+			fmt.Fprintf(w, " (%s)", change.BuildConfig)
+		}
+		if change.HookID > 0 {
+			fmt.Fprintf(w, " hook %d", change.HookID)
+		}
+		if change.PendingApproval {
+			fmt.Fprint(w, " pending approval")
+		}
+		fmt.Fprintln(w)
+		if len(change.PreviousTargetURL) > 0 {
+			fmt.Fprintf(w, "      url: %s -> %s\n", change.PreviousTargetURL, change.TargetURL)
+		} else {
+			fmt.Fprintf(w, "      url: %s\n", change.TargetURL)
+		}
+	}
+
+	fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d to delete", output.Create, output.Update, output.Delete)
+	if output.PendingApproval > 0 {
+		fmt.Fprintf(w, ", %d pending approval", output.PendingApproval)
+	}
+	fmt.Fprintln(w, ".")
+	if len(output.BlockedDeletions) > 0 {
+		fmt.Fprintf(w, "\nWARNING: the deletions would be blocked by the mass deletion guard: %s\n", output.BlockedDeletions)
+	}
+}
@@ -0,0 +1,229 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/reconciler"
+)
+
+func testKeyFunc(hook api.Hook) (string, error) {
+	ns, bc, _ := openshift.ExplodeOpenshiftWebhookURL(hook.TargetURL)
+	if len(ns) == 0 || len(bc) == 0 {
+		return "", fmt.Errorf("unmanaged hook %s", hook.TargetURL)
+	}
+	return ns + "/" + bc, nil
+}
+
+func testHookURL(bc, secret string) string {
+	return fmt.Sprintf("https://openshift/oapi/v1/namespaces/ns/buildconfigs/%s/webhooks/%s/github", bc, secret)
+}
+
+func testMaskedHookURL(bc string) string {
+	return fmt.Sprintf("https://openshift/oapi/v1/namespaces/ns/buildconfigs/%s/webhooks/*****/github", bc)
+}
+
+func TestNewPlanOutput(t *testing.T) {
+	repo := api.GithubRepository{Owner: "my-org", Name: "repo"}
+	newRepo := api.GithubRepository{Owner: "my-org", Name: "new"}
+
+	plan := reconciler.Plan{
+		Create: []api.Hook{
+			{Enabled: true, TargetURL: testHookURL("created", "secret"), GithubRepository: repo},
+			{Enabled: true, TargetURL: testHookURL("pending", "secret"), GithubRepository: newRepo},
+		},
+		Update: []api.Hook{
+			{ID: 1, Enabled: true, TargetURL: testHookURL("updated", "new-secret"), GithubRepository: repo},
+		},
+		Delete: []api.Hook{
+			{ID: 2, TargetURL: testHookURL("deleted", "secret"), GithubRepository: repo},
+		},
+	}
+	actual := []api.Hook{
+		{ID: 1, Enabled: true, TargetURL: testHookURL("updated", "old-secret"), GithubRepository: repo},
+		{ID: 2, Enabled: true, TargetURL: testHookURL("deleted", "secret"), GithubRepository: repo},
+	}
+	pendingFunc := func(hook api.Hook) bool {
+		return hook.GithubRepository == newRepo
+	}
+
+	tests := []struct {
+		pendingFunc    func(hook api.Hook) bool
+		expectedOutput planOutput
+	}{
+		// no approval required
+		{
+			pendingFunc: nil,
+			expectedOutput: planOutput{
+				Changes: []plannedChange{
+					{Action: createAction, Repository: "my-org/repo", BuildConfig: "ns/created", TargetURL: testMaskedHookURL("created")},
+					{Action: createAction, Repository: "my-org/new", BuildConfig: "ns/pending", TargetURL: testMaskedHookURL("pending")},
+					{Action: updateAction, Repository: "my-org/repo", BuildConfig: "ns/updated", HookID: 1, TargetURL: testMaskedHookURL("updated"), PreviousTargetURL: testMaskedHookURL("updated")},
+					{Action: deleteAction, Repository: "my-org/repo", BuildConfig: "ns/deleted", HookID: 2, TargetURL: testMaskedHookURL("deleted")},
+				},
+				Create: 2,
+				Update: 1,
+				Delete: 1,
+			},
+		},
+		// the hooks pending approval are not counted in the hooks to create
+		{
+			pendingFunc: pendingFunc,
+			expectedOutput: planOutput{
+				Changes: []plannedChange{
+					{Action: createAction, Repository: "my-org/repo", BuildConfig: "ns/created", TargetURL: testMaskedHookURL("created")},
+					{Action: createAction, Repository: "my-org/new", BuildConfig: "ns/pending", TargetURL: testMaskedHookURL("pending"), PendingApproval: true},
+					{Action: updateAction, Repository: "my-org/repo", BuildConfig: "ns/updated", HookID: 1, TargetURL: testMaskedHookURL("updated"), PreviousTargetURL: testMaskedHookURL("updated")},
+					{Action: deleteAction, Repository: "my-org/repo", BuildConfig: "ns/deleted", HookID: 2, TargetURL: testMaskedHookURL("deleted")},
+				},
+				Create:          1,
+				Update:          1,
+				Delete:          1,
+				PendingApproval: 1,
+			},
+		},
+	}
+
+	for count, test := range tests {
+		output := newPlanOutput(plan, actual, testKeyFunc, test.pendingFunc)
+		if !reflect.DeepEqual(output, test.expectedOutput) {
+			t.Errorf("Test[%d] Failed: Expected %+v but got %+v", count, test.expectedOutput, output)
+		}
+	}
+
+	empty := newPlanOutput(reconciler.Plan{}, nil, testKeyFunc, pendingFunc)
+	if empty.Changes == nil || len(empty.Changes) != 0 {
+		t.Errorf("Expected an empty (but not nil) list of changes, but got %+v", empty.Changes)
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	tests := []struct {
+		output         planOutput
+		expectedOutput string
+	}{
+		// no changes
+		{
+			output:         planOutput{Changes: []plannedChange{}},
+			expectedOutput: "No changes. The GitHub hooks are in sync with the BuildConfigs.\n",
+		},
+		// all kinds of changes
+		{
+			output: planOutput{
+				Changes: []plannedChange{
+					{Action: createAction, Repository: "my-org/repo", BuildConfig: "ns/created", TargetURL: "https://created"},
+					{Action: updateAction, Repository: "my-org/repo", BuildConfig: "ns/updated", HookID: 1, TargetURL: "https://new", PreviousTargetURL: "https://old"},
+					{Action: deleteAction, Repository: "my-org/repo", HookID: 2, TargetURL: "https://deleted"},
+				},
+				Create: 1,
+				Update: 1,
+				Delete: 1,
+			},
+			expectedOutput: `  + my-org/repo (ns/created)
+      url: https://created
+  ~ my-org/repo (ns/updated) hook 1
+      url: https://old -> https://new
+  - my-org/repo hook 2
+      url: https://deleted
+
+Plan: 1 to create, 1 to update, 1 to delete.
+`,
+		},
+		// pending approval only
+		{
+			output: planOutput{
+				Changes: []plannedChange{
+					{Action: createAction, Repository: "my-org/new", BuildConfig: "ns/pending", TargetURL: "https://pending", PendingApproval: true},
+				},
+				PendingApproval: 1,
+			},
+			expectedOutput: `  ? my-org/new (ns/pending) pending approval
+      url: https://pending
+
+Plan: 0 to create, 0 to update, 0 to delete, 1 pending approval.
+`,
+		},
+		// blocked deletions
+		{
+			output: planOutput{
+				Changes: []plannedChange{
+					{Action: deleteAction, Repository: "my-org/repo", HookID: 2, TargetURL: "https://deleted"},
+				},
+				Delete:           1,
+				BlockedDeletions: "too many deletions",
+			},
+			expectedOutput: `  - my-org/repo hook 2
+      url: https://deleted
+
+Plan: 0 to create, 0 to update, 1 to delete.
+
+WARNING: the deletions would be blocked by the mass deletion guard: too many deletions
+`,
+		},
+	}
+
+	for count, test := range tests {
+		var buffer bytes.Buffer
+		printPlan(&buffer, test.output)
+		if buffer.String() != test.expectedOutput {
+			t.Errorf("Test[%d] Failed: Expected output\n%s\nbut got\n%s", count, test.expectedOutput, buffer.String())
+		}
+	}
+}
+
+func TestPlanCommandReportsPendingApprovals(t *testing.T) {
+	repository := api.GithubRepository{Owner: "my-org", Name: "new"}
+	hook := api.Hook{Enabled: true, TargetURL: testHookURL("bc", "secret"), GithubRepository: repository}
+	plan := reconciler.Plan{Create: []api.Hook{hook}}
+	notHooked := func(repository api.GithubRepository) (bool, error) {
+		return false, nil
+	}
+	defer planCmd.Flags().Parse([]string{"--require-approval=false"})
+
+	tests := []struct {
+		args            []string
+		approved        bool
+		expectedCreate  int
+		expectedPending int
+	}{
+		// no approval required
+		{
+			args:           []string{},
+			expectedCreate: 1,
+		},
+		// the repository is pending approval
+		{
+			args:            []string{"--require-approval", "--approvals-namespace=ns"},
+			expectedPending: 1,
+		},
+		// the repository has been approved
+		{
+			args:           []string{"--require-approval", "--approvals-namespace=ns"},
+			approved:       true,
+			expectedCreate: 1,
+		},
+	}
+
+	for count, test := range tests {
+		planCmd.Flags().Parse([]string{"--require-approval=false"})
+		if err := planCmd.Flags().Parse(test.args); err != nil {
+			t.Fatalf("Test[%d] Failed: Failed to parse the plan flags %v: %v", count, test.args, err)
+		}
+
+		gate := newApprovalGate(planOptions, &fakeConfigMaps{}, notHooked)
+		if test.approved {
+			if _, err := gate.Store.Approve(repository, "admin"); err != nil {
+				t.Fatalf("Test[%d] Failed: Failed to approve %s: %v", count, repository, err)
+			}
+		}
+
+		output := newPlanOutput(plan, nil, testKeyFunc, pendingApprovalFunc(gate))
+		if output.Create != test.expectedCreate || output.PendingApproval != test.expectedPending {
+			t.Errorf("Test[%d] Failed: Expected %d to create and %d pending approval, but got %d and %d", count, test.expectedCreate, test.expectedPending, output.Create, output.PendingApproval)
+		}
+	}
+}
@@ -5,21 +5,31 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/approval"
 	"github.com/vbehar/openshift-github-hooks/pkg/github"
+	"github.com/vbehar/openshift-github-hooks/pkg/leaderelection"
 	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+	"github.com/vbehar/openshift-github-hooks/pkg/policy"
+	"github.com/vbehar/openshift-github-hooks/pkg/reconciler"
 
-	"k8s.io/kubernetes/pkg/client/cache"
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/record"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+	utilerrors "k8s.io/kubernetes/pkg/util/errors"
 
 	"github.com/golang/glog"
+	"golang.org/x/net/context"
 )
 
 // syncHooks runs the main sync loop to watch for all BC
-// and handle the matching events to the github hooks manager
-func syncHooks(options *Options) {
+// and handle the matching events to the github hooks manager.
+// It returns an error if the in-flight hook operations could not be drained on shutdown.
+func syncHooks(options *Options) error {
 	if options.DryRun {
 		glog.Info("Starting openshift-github-hooks sync in DRY-RUN mode...")
 	} else {
@@ -28,22 +38,156 @@ func syncHooks(options *Options) {
 
 	stopChan := make(chan struct{})
 
-	hooksManager, err := github.NewHooksManager(options.GithubBaseURL, options.Token, options.GithubInsecureSkipVerify)
+	// ctx is used to cancel the GitHub calls once the shutdown timeout expired
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := newSyncer(ctx, options, stopChan)
+	controller := s.controller
+
+	// startSyncing starts handling the BCs changes, and reconciling all the hooks periodically
+	startSyncing := func(stop <-chan struct{}) {
+		controller.HandleUntil(stop)
+		s.namespacesController.RunUntil(stop)
+		go s.reconciler.RunUntil(options.ResyncPeriod, controller.HasSynced, stop)
+		if s.approvalGate != nil {
+			// don't wait for the next resync to create the approved hooks
+			s.approvalGate.WatchApprovalsUntil(options.Approval.CheckPeriod, s.reconciler.Trigger, stop)
+		}
+	}
+
+	controller.WatchUntil(stopChan)
+	if options.LeaderElection.Enabled {
+		// standby replicas only watch the BCs (to warm their cache),
+		// and start syncing them once they become the leader
+		go runLeaderElection(options.LeaderElection, s.kclient, startSyncing, stopChan)
+	} else {
+		startSyncing(stopChan)
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGTERM, syscall.SIGUSR1)
+	for sig := range c {
+		if sig == syscall.SIGUSR1 {
+			logDeadLetters(controller.DeadLetters())
+			continue
+		}
+		glog.Infof("Interrupted by user (or killed) !")
+		close(stopChan)
+		break
+	}
+
+	glog.Infof("Shutting down openshift-github-hooks sync - waiting up to %v for the in-flight hook operations...", options.ShutdownTimeout)
+	drained := s.inFlight.drain(options.ShutdownTimeout)
+	// abort the GitHub calls that are still in flight
+	cancel()
+	logUnprocessedChanges(controller)
+	if !drained {
+		return fmt.Errorf("Timed out after %v while waiting for the in-flight hook operations", options.ShutdownTimeout)
+	}
+
+	glog.Info("Shutting down openshift-github-hooks sync")
+	return nil
+}
+
+// syncer holds the clients, controllers and reconciler used to sync the hooks.
+// It is shared by the sync daemon and the one-shot commands (plan, apply and audit).
+type syncer struct {
+	kclient              *kclient.Client
+	publicURL            string
+	keyFunc              reconciler.KeyFunc
+	controller           *openshift.BuildConfigsController
+	namespacesController *openshift.NamespacesController
+	reconciler           *reconciler.Reconciler
+	approvalGate         *approval.Gate
+	inFlight             *inFlightOperations
+}
+
+// newSyncer connects to GitHub and OpenShift, and wires the controllers and the reconciler.
+// The namespaces are watched until stopChan is closed, or just retrieved when needed if stopChan is nil.
+func newSyncer(ctx context.Context, options *Options, stopChan <-chan struct{}) *syncer {
+	hooksManager, err := github.NewHooksManager(options.GithubBaseURL, options.Token, options.GithubInsecureSkipVerify, options.GithubTimeouts)
 	if err != nil {
 		glog.Fatalf("Failed to connect to GitHub: %v", err)
 	}
+	// the legacy and API group URLs of the same webhook are the same hook
+	hooksManager.SameURLFunc = openshift.SameOpenshiftHook
 
-	oclient, _, err := openshift.Factory.Clients()
+	oclient, kclient, err := openshift.Factory.Clients()
 	if err != nil {
 		glog.Fatalf("Failed to get OpenShift client: %v", err)
 	}
 
-	keyFunc := func(obj interface{}) (string, error) {
-		hook, ok := obj.(api.Hook)
-		if !ok {
-			return "", fmt.Errorf("Invalid object type %T (expected a Hook)", obj)
+	publicURL, err := options.WebhookURL.PublicURL(oclient, options.OpenshiftPublicURL)
+	if err != nil {
+		glog.Fatalf("Failed to get the webhooks public URL: %v", err)
+	}
+	// isOpenshiftHook checks if a hook targets this OpenShift instance
+	isOpenshiftHook := func(hookURL string) bool {
+		return openshift.IsOpenshiftHook(hookURL, publicURL)
+	}
+	if len(options.Relay.URL) > 0 {
+		// the hooks target the relay, which forwards the deliveries to OpenShift.
+		// The hooks that still target OpenShift directly are managed too, so that they are migrated to the relay.
+		directURL := publicURL
+		publicURL = strings.TrimSuffix(options.Relay.URL, "/")
+		isOpenshiftHook = func(hookURL string) bool {
+			return openshift.IsOpenshiftHook(hookURL, publicURL) || openshift.IsOpenshiftHook(hookURL, directURL)
+		}
+	}
+	glog.V(1).Infof("Using %s as the webhooks public URL", publicURL)
+
+	hooksPolicy, err := policy.Load(kclient, options.PolicyFile, options.PolicyConfigMap)
+	if err != nil {
+		glog.Fatalf("Failed to load the policy: %v", err)
+	}
+	var namespaces *openshift.NamespaceCache
+	if hooksPolicy.NeedsLabels() || options.TeamCheck.Enabled {
+		if stopChan != nil {
+			namespaces = openshift.NewWatchingNamespaceCache(kclient, stopChan)
+		} else {
+			namespaces = openshift.NewNamespaceCache(kclient)
+		}
+	}
+	policyChecker := policy.NewChecker(hooksPolicy, namespaces)
+	teamChecker := github.NewTeamAccessChecker(hooksManager, options.OrganizationName, options.TeamCheck.TTL)
+
+	// checkRepository checks both the policy and the team ownership of the repository.
+	// Only a definite denial is returned as a plain error: a failed lookup is unknown.
+	checkRepository := func(namespace string, repository api.GithubRepository) error {
+		if err := policyChecker.Check(namespace, repository); err != nil {
+			return err
 		}
-		if !openshift.IsOpenshiftHook(hook.TargetURL, options.OpenshiftPublicURL) {
+		// the hooks of the repositories outside of the organization are ignored anyway
+		if !options.TeamCheck.Enabled || !strings.EqualFold(repository.Owner, options.OrganizationName) {
+			return nil
+		}
+		ns, err := namespaces.Get(namespace)
+		if err != nil {
+			return &openshift.RepositoryCheckUnknownError{Err: fmt.Errorf("Failed to get namespace %s to check its GitHub team: %v", namespace, err)}
+		}
+		team := ns.Annotations[api.GithubTeamAnnotation]
+		if len(team) == 0 {
+			return fmt.Errorf("The namespace %s has no GitHub team (%s annotation)", namespace, api.GithubTeamAnnotation)
+		}
+		allowed, err := teamChecker.HasWriteAccess(ctx, team, repository)
+		if err != nil {
+			return &openshift.RepositoryCheckUnknownError{Err: fmt.Errorf("Failed to check the permission of the GitHub team %s on the repository %s: %v", team, repository, err)}
+		}
+		if !allowed {
+			return fmt.Errorf("The GitHub team %s of the namespace %s has no admin or write permission on the repository %s", team, namespace, repository)
+		}
+		return nil
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(glog.Infof)
+	eventBroadcaster.StartRecordingToSink(kclient.Events(""))
+	recorder := eventBroadcaster.NewRecorder(kapi.EventSource{Component: "openshift-github-hooks-sync"})
+
+	// keyFunc identifies a hook by the "namespace/name" of the BC it targets
+	keyFunc := func(hook api.Hook) (string, error) {
+		if !isOpenshiftHook(hook.TargetURL) {
 			return "", fmt.Errorf("Hook %s does not target an OpenShift endpoint", hook.TargetURL)
 		}
 		ns, bc, _ := openshift.ExplodeOpenshiftWebhookURL(hook.TargetURL)
@@ -53,100 +197,298 @@ func syncHooks(options *Options) {
 		return fmt.Sprintf("%s/%s", ns, bc), nil
 	}
 
-	// store used as a cache for hooks from github
-	// (to avoid too many requests on github.com)
-	store := cache.NewTTLStore(keyFunc, 2*time.Minute)
+	// the hooks on repositories that have never been hooked before may require an approval
+	approvalGate := newApprovalGate(options, kclient, func(repository api.GithubRepository) (bool, error) {
+		hooks, err := hooksManager.ListHooksForRepository(ctx, repository)
+		if err != nil {
+			return false, err
+		}
+		for _, hook := range hooks {
+			if isOpenshiftHook(hook.TargetURL) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+
+	isOrganizationHook := func(hook api.Hook) bool {
+		return strings.ToLower(hook.GithubRepository.Owner) == strings.ToLower(options.OrganizationName)
+	}
+
+	// both the controller and the reconciler apply changes on GitHub,
+	// so we serialize them to avoid creating duplicate hooks
+	hooksLock := &sync.Mutex{}
+
+	// the in-flight GitHub operations are drained on shutdown
+	inFlight := &inFlightOperations{}
+
+	// deleteHooks deletes the given hooks from the given repository, in a single batch
+	deleteHooks := func(repository api.GithubRepository, hooks []api.Hook) error {
+		if !inFlight.start() {
+			return github.ErrShuttingDown
+		}
+		defer inFlight.done()
 
-	(&openshift.BuildConfigsController{
-		OpenshiftPublicURL:     options.OpenshiftPublicURL,
-		ResyncPeriod:           options.ResyncPeriod,
+		hooksLock.Lock()
+		defer hooksLock.Unlock()
+
+		deleted, err := hooksManager.DeleteHooks(ctx, repository, hooks)
+		glog.V(1).Infof("Deleted %d hooks from %s", deleted, repository)
+		return err
+	}
+
+	controller := &openshift.BuildConfigsController{
+		OpenshiftPublicURL:     publicURL,
+		WebhookAPIPath:         options.WebhookAPIPath,
+		RelayURL:               options.Relay.URL,
+		RelayKey:               []byte(options.Relay.Key),
+		CheckRepositoryFunc:    checkRepository,
+		UpdateStatus:           options.UpdateStatus && !options.DryRun,
+		DeletionGracePeriod:    options.DeletionGracePeriod,
+		RetryPolicy:            options.RetryPolicy,
 		BuildConfigsNamespacer: oclient,
-		HookHandlerFunc: func(hook api.Hook) error {
-			if strings.ToLower(hook.GithubRepository.Owner) != strings.ToLower(options.OrganizationName) {
+		Recorder:               recorder,
+		HookHandlerFunc: func(hook api.Hook) (*api.Hook, bool, error) {
+			if !isOrganizationHook(hook) {
 				glog.V(4).Infof("Ignoring hook for external repository '%s' owned by '%s' (instead of '%s')", hook.GithubRepository.Name, hook.GithubRepository.Owner, options.OrganizationName)
-				return nil
+				return nil, false, nil
 			}
 
+			if !inFlight.start() {
+				return nil, false, github.ErrShuttingDown
+			}
+			defer inFlight.done()
+
+			hooksLock.Lock()
+			defer hooksLock.Unlock()
+
 			if hook.Enabled {
 				if options.DryRun {
 					glog.Infof("DRY_RUN_MODE: would have registered hook on %s with target URL: %s", hook.GithubRepository, hook.TargetURL)
-					return nil
+					return nil, false, nil
+				}
+				if approvalGate != nil && hook.ID == 0 {
+					requester, err := keyFunc(hook)
+					if err != nil {
+						return nil, false, err
+					}
+					allowed, err := approvalGate.Allow(hook.GithubRepository, requester)
+					if err != nil {
+						return nil, false, err
+					}
+					if !allowed {
+						// not an error: the hook will be created by the reconciler once approved
+						return nil, false, nil
+					}
 				}
-				_, err := hooksManager.RegisterHook(hook)
-				return err
+				return hooksManager.RegisterHook(ctx, hook)
 			}
 
 			if options.DryRun {
-				glog.Infof("DRY_RUN_MODE: would have deleted hook from %s with target URL: %s", hook.GithubRepository, hook.TargetURL)
-				return nil
+				glog.Infof("DRY_RUN_MODE: would have deleted hook %d from %s with target URL: %s", hook.ID, hook.GithubRepository, hook.TargetURL)
+				return nil, false, nil
 			}
-			_, err := hooksManager.DeleteHook(hook)
-			return err
+			deleted, err := hooksManager.DeleteHook(ctx, hook)
+			return nil, deleted, err
 		},
-		KeyListFunc: func() []string {
-			hooks, err := hooksManager.ListHooksForOrganization(options.OrganizationName)
-			if err != nil {
-				glog.Fatalf("Failed to list github hooks for org %s: %v", options.OrganizationName, err)
+	}
+
+	// the secrets referenced from Secret objects are refreshed, so that the hooks are updated when they are rotated
+	if options.SecretReferences {
+		secretReferences := openshift.NewSecretReferences(oclient)
+		if stopChan != nil {
+			secretReferences.SecretFunc = openshift.NewRefreshingSecretCache(kclient, options.SecretRefreshPeriod, func(namespace, name string) {
+				controller.RequeueBuildConfigs(secretReferences.BuildConfigsReferencing(namespace, name))
+			}, stopChan).Get
+		} else {
+			secretReferences.SecretFunc = openshift.NewSecretCache(kclient).Get
+		}
+		controller.SecretReferenceFunc = secretReferences.Resolve
+	}
+
+	namespacesController := &openshift.NamespacesController{
+		NamespacesInterface: kclient,
+		MaxRetries:          options.RetryPolicy.MaxRetries,
+		NamespaceHandlerFunc: func(namespace string) error {
+			// only the repositories known for the namespace are listed: the hooks we don't know about
+			// (BuildConfigs created and deleted while we were not running) are left to the reconciliation
+			repositories := controller.RepositoriesForNamespace(namespace)
+			if len(repositories) == 0 {
+				glog.V(3).Infof("No known repository for namespace %s - nothing to clean up", namespace)
+				return nil
 			}
 
-			keys := []string{}
-			for _, hook := range hooks {
-				if openshift.IsOpenshiftHook(hook.TargetURL, options.OpenshiftPublicURL) {
-					key, err := keyFunc(hook)
-					if err != nil {
-						glog.Errorf("Failed to retrieve key from hook %+v: %v", hook, err)
-						continue
-					}
-					keys = append(keys, key)
-					if err := store.Add(hook); err != nil {
-						glog.Errorf("Failed to cache hook %+v: %v", hook, err)
-						continue
+			errs := []error{}
+			for _, repository := range repositories {
+				if !isOrganizationHook(api.Hook{GithubRepository: repository}) {
+					continue
+				}
+				hooks, err := hooksManager.ListHooksForRepository(ctx, repository)
+				if github.IsNotFound(err) {
+					continue
+				}
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+
+				// batch the deletions per repository
+				namespaceHooks := []api.Hook{}
+				for _, hook := range hooks {
+					if key, err := keyFunc(hook); err == nil && strings.HasPrefix(key, namespace+"/") {
+						namespaceHooks = append(namespaceHooks, hook)
 					}
-				} else {
-					glog.V(5).Infof("Ignoring non-openshift hook %s for repository %s", hook.TargetURL, hook.GithubRepository)
+				}
+				if len(namespaceHooks) == 0 {
+					continue
+				}
+				if options.DryRun {
+					glog.Infof("DRY_RUN_MODE: would have deleted %d hooks of namespace %s from %s", len(namespaceHooks), namespace, repository)
+					continue
+				}
+				if err := deleteHooks(repository, namespaceHooks); err != nil {
+					errs = append(errs, err)
 				}
 			}
-			return keys
+			return utilerrors.NewAggregate(errs)
 		},
-		KeyGetFunc: func(key string) (interface{}, bool, error) {
-			item, exists, err := store.GetByKey(key)
-			if exists && err == nil {
-				return item, true, nil
-			}
+	}
+
+	guard := &massDeletionGuard{
+		client:      kclient,
+		recorder:    recorder,
+		namespace:   options.MassDeletion.Namespace,
+		name:        options.MassDeletion.Name,
+		alwaysAllow: options.MassDeletion.Allow,
+	}
+
+	hooksReconciler := &reconciler.Reconciler{
+		KeyFunc:         keyFunc,
+		HookHandlerFunc: controller.HandleHook,
+		MaxRetries:      options.RetryPolicy.MaxRetries,
+		RetryDelay:      options.RetryPolicy.InitialBackoff,
+		MaxRetryDelay:   options.RetryPolicy.MaxBackoff,
+		RetryableFunc:   github.IsRetryable,
+		DeletionLimits: reconciler.DeletionLimits{
+			MaxCount:   options.MassDeletion.MaxCount,
+			MaxPercent: options.MassDeletion.MaxPercent,
+		},
+		DesiredHooksFunc: func() ([]api.Hook, map[string]bool, error) {
+			hooks, unmanaged, err := controller.DesiredHooks()
 			if err != nil {
-				glog.Warning("Failed to retrieve object from cache using key '%s': %v", key, err)
+				return nil, nil, err
 			}
-
-			hooks, err := hooksManager.ListHooksForOrganization(options.OrganizationName)
+			organizationHooks := []api.Hook{}
+			for _, hook := range hooks {
+				if isOrganizationHook(hook) {
+					organizationHooks = append(organizationHooks, hook)
+				}
+			}
+			return organizationHooks, unmanaged, nil
+		},
+		ActualHooksFunc: func() ([]api.Hook, error) {
+			hooks, err := hooksManager.ListHooksForOrganization(ctx, options.OrganizationName)
 			if err != nil {
-				return "", false, err
+				return nil, err
 			}
-
+			openshiftHooks := []api.Hook{}
 			for _, hook := range hooks {
-				if openshift.IsOpenshiftHook(hook.TargetURL, options.OpenshiftPublicURL) {
-					localKey, err := keyFunc(hook)
-					if err != nil {
-						glog.Errorf("Failed to retrieve key from hook %+v: %v", hook, err)
-						continue
-					}
-
-					if localKey == key {
-						return hook, true, nil
+				if isOpenshiftHook(hook.TargetURL) {
+					openshiftHooks = append(openshiftHooks, hook)
+					if approvalGate != nil {
+						approvalGate.MarkHooked(hook.GithubRepository)
 					}
+				} else {
+					glog.V(5).Infof("Ignoring non-openshift hook %s for repository %s", hook.TargetURL, hook.GithubRepository)
 				}
-
 			}
-			return "", false, nil
+			return openshiftHooks, nil
 		},
-	}).RunUntil(stopChan)
+	}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGTERM)
-	select {
-	case <-c:
-		glog.Infof("Interrupted by user (or killed) !")
-		close(stopChan)
+	if !options.DryRun {
+		hooksReconciler.AllowMassDeletionFunc = guard.allow
+		hooksReconciler.MassDeletionBlockedFunc = guard.blocked
 	}
 
-	glog.Info("Shutting down openshift-github-hooks sync")
+	return &syncer{
+		kclient:              kclient,
+		publicURL:            publicURL,
+		keyFunc:              keyFunc,
+		controller:           controller,
+		namespacesController: namespacesController,
+		reconciler:           hooksReconciler,
+		approvalGate:         approvalGate,
+		inFlight:             inFlight,
+	}
+}
+
+// newApprovalGate returns the approval gate holding the hooks on the repositories that have never been hooked before,
+// or nil if no approval is required. The hookedFunc checks if a repository already has hooks that we manage.
+func newApprovalGate(options *Options, client kclient.ConfigMapsNamespacer, hookedFunc func(repository api.GithubRepository) (bool, error)) *approval.Gate {
+	if !options.Approval.Required {
+		return nil
+	}
+	return &approval.Gate{
+		Store:      approval.NewStore(client, options.Approval.Namespace, options.Approval.Name),
+		HookedFunc: hookedFunc,
+	}
+}
+
+// logUnprocessedChanges logs the BuildConfigs changes that have not been processed,
+// so that they can be checked after the shutdown
+// (they will be caught up by the full reconciliation on the next start)
+func logUnprocessedChanges(controller *openshift.BuildConfigsController) {
+	if keys := controller.UnprocessedKeys(); len(keys) > 0 {
+		glog.Warningf("%d BuildConfigs changes have not been processed: %s", len(keys), strings.Join(keys, ", "))
+	}
+	if keys := controller.PendingDeletions(); len(keys) > 0 {
+		glog.Warningf("%d hooks of deleted BuildConfigs have not been deleted: %s", len(keys), strings.Join(keys, ", "))
+	}
+	if deadLetters := controller.DeadLetters(); len(deadLetters) > 0 {
+		logDeadLetters(deadLetters)
+	}
+}
+
+// logDeadLetters logs the content of the dead-letter list
+func logDeadLetters(deadLetters []openshift.DeadLetter) {
+	glog.Infof("%d BuildConfigs changes in the dead-letter list", len(deadLetters))
+	for _, deadLetter := range deadLetters {
+		glog.Infof("- BC %s failed after %d retries (last error at %v): %v", deadLetter.Key, deadLetter.Retries, deadLetter.Time.Format(time.RFC3339), deadLetter.Error)
+	}
+}
+
+// runLeaderElection runs the leader election until stopChan is closed,
+// and starts syncing the hooks once we become the leader.
+// It exits the process if the leadership is lost.
+func runLeaderElection(options LeaderElectionOptions, kclient *kclient.Client, startSyncing func(stop <-chan struct{}), stopChan <-chan struct{}) {
+	lock, err := leaderelection.NewResourceLock(options.LockType, options.Namespace, options.Name, kclient)
+	if err != nil {
+		glog.Fatalf("Failed to create the leader election lock: %v", err)
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.Config{
+		Lock:          lock,
+		Identity:      options.Identity,
+		LeaseDuration: options.LeaseDuration,
+		RenewDeadline: options.RenewDeadline,
+		RetryPeriod:   options.RetryPeriod,
+		OnStartedLeading: func(stop <-chan struct{}) {
+			glog.Infof("Became the leader - starting to sync hooks")
+			startSyncing(stop)
+		},
+		OnStoppedLeading: func() {
+			select {
+			case <-stopChan:
+				// we are shutting down, no need to panic
+			default:
+				glog.Fatalf("Lost the leadership - exiting to avoid conflicting hook changes")
+			}
+		},
+	})
+	if err != nil {
+		glog.Fatalf("Failed to configure the leader election: %v", err)
+	}
+
+	elector.RunUntil(stopChan)
 }
@@ -0,0 +1,154 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// ErrorType is the type of a GitHub API error
+type ErrorType string
+
+// Types of the GitHub API errors
+const (
+	// NotFoundError happens when the repository (or the hook) does not exist,
+	// or the token can't access it
+	NotFoundError ErrorType = "NotFound"
+
+	// ForbiddenError happens when the token does not have the required scopes
+	ForbiddenError ErrorType = "Forbidden"
+
+	// ValidationError happens when GitHub refuses the request,
+	// for example because the hook limit has been reached for the repository
+	ValidationError ErrorType = "Validation"
+
+	// RateLimitedError happens when the token exceeded its rate limit
+	RateLimitedError ErrorType = "RateLimited"
+
+	// TransientError is any other error (GitHub outage, network error, ...)
+	TransientError ErrorType = "Transient"
+
+	// ShuttingDownError happens when the operation has not been sent to GitHub
+	// because the process is shutting down
+	ShuttingDownError ErrorType = "ShuttingDown"
+)
+
+// ErrShuttingDown is returned for the operations refused while shutting down:
+// it is not a failure of the operation, so it should neither be reported nor retried
+var ErrShuttingDown error = &Error{
+	Type: ShuttingDownError,
+	Err:  fmt.Errorf("Shutting down - not accepting new hook operations"),
+}
+
+// Error is a GitHub API error, with its type
+type Error struct {
+	Type ErrorType
+
+	// Err is the original error
+	Err error
+}
+
+// Error is for the error implementation
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// classifyError returns the given error wrapped in an Error with its type
+// (or nil if the given error is nil)
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{
+		Type: errorType(err),
+		Err:  err,
+	}
+}
+
+// errorType returns the type of the given error
+func errorType(err error) ErrorType {
+	switch e := err.(type) {
+	case *Error:
+		return e.Type
+	case *github.RateLimitError:
+		return RateLimitedError
+	case *github.TwoFactorAuthError:
+		return ForbiddenError
+	case *github.ErrorResponse:
+		if e.Response == nil {
+			return TransientError
+		}
+		switch e.Response.StatusCode {
+		case http.StatusNotFound, http.StatusGone:
+			return NotFoundError
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ForbiddenError
+		case http.StatusBadRequest, http.StatusConflict, 422: // Unprocessable Entity
+			return ValidationError
+		case 429: // Too Many Requests
+			return RateLimitedError
+		}
+	}
+	return TransientError
+}
+
+// IsNotFound returns true if the given error is a GitHub API "Not Found" error,
+// which happens when the repository does not exist or the token can't access it
+func IsNotFound(err error) bool {
+	return err != nil && errorType(err) == NotFoundError
+}
+
+// IsForbidden returns true if the given error is a GitHub API "Forbidden" error,
+// which happens when the token does not have the required scopes
+func IsForbidden(err error) bool {
+	return err != nil && errorType(err) == ForbiddenError
+}
+
+// IsValidation returns true if the given error is a GitHub API validation error,
+// which happens when GitHub refuses the request (hook limit reached, invalid hook, ...)
+func IsValidation(err error) bool {
+	return err != nil && errorType(err) == ValidationError
+}
+
+// IsRateLimited returns true if the given error is a GitHub API rate limit error
+func IsRateLimited(err error) bool {
+	return err != nil && errorType(err) == RateLimitedError
+}
+
+// IsTransient returns true if the given error is a transient error
+// (GitHub outage, network error, ...)
+func IsTransient(err error) bool {
+	return err != nil && errorType(err) == TransientError
+}
+
+// IsShuttingDown returns true if the given error is ErrShuttingDown
+func IsShuttingDown(err error) bool {
+	return err != nil && errorType(err) == ShuttingDownError
+}
+
+// IsRetryable returns true if the request that failed with the given error
+// could succeed if retried later: transient and rate limit errors.
+// The other errors (not found, forbidden, validation) are permanent,
+// and require a change from the user.
+func IsRetryable(err error) bool {
+	return IsTransient(err) || IsRateLimited(err)
+}
+
+// RetryAfter returns the delay before the rate limit is reset,
+// if the given error is a rate limit error (or 0)
+func RetryAfter(err error) time.Duration {
+	if e, ok := err.(*Error); ok {
+		err = e.Err
+	}
+	if e, ok := err.(*github.RateLimitError); ok {
+		if delay := e.Rate.Reset.Time.Sub(time.Now()); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestErrorType(t *testing.T) {
+	errorResponse := func(statusCode int) error {
+		return &github.ErrorResponse{
+			Response: &http.Response{
+				StatusCode: statusCode,
+			},
+		}
+	}
+
+	tests := []struct {
+		err               error
+		expectedType      ErrorType
+		expectedRetryable bool
+	}{
+		{
+			err:               fmt.Errorf("some error"),
+			expectedType:      TransientError,
+			expectedRetryable: true,
+		},
+		{
+			err:               &github.ErrorResponse{},
+			expectedType:      TransientError,
+			expectedRetryable: true,
+		},
+		{
+			err:               errorResponse(http.StatusNotFound),
+			expectedType:      NotFoundError,
+			expectedRetryable: false,
+		},
+		{
+			err:               errorResponse(http.StatusForbidden),
+			expectedType:      ForbiddenError,
+			expectedRetryable: false,
+		},
+		{
+			err:               errorResponse(http.StatusUnauthorized),
+			expectedType:      ForbiddenError,
+			expectedRetryable: false,
+		},
+		{
+			err:               errorResponse(422),
+			expectedType:      ValidationError,
+			expectedRetryable: false,
+		},
+		{
+			err:               errorResponse(429),
+			expectedType:      RateLimitedError,
+			expectedRetryable: true,
+		},
+		{
+			err:               &github.RateLimitError{},
+			expectedType:      RateLimitedError,
+			expectedRetryable: true,
+		},
+		{
+			err:               errorResponse(http.StatusInternalServerError),
+			expectedType:      TransientError,
+			expectedRetryable: true,
+		},
+		{
+			err:               errorResponse(http.StatusBadGateway),
+			expectedType:      TransientError,
+			expectedRetryable: true,
+		},
+		{
+			err:               ErrShuttingDown,
+			expectedType:      ShuttingDownError,
+			expectedRetryable: false,
+		},
+		{
+			err:               classifyError(errorResponse(http.StatusNotFound)),
+			expectedType:      NotFoundError,
+			expectedRetryable: false,
+		},
+	}
+
+	for count, test := range tests {
+		if result := errorType(test.err); result != test.expectedType {
+			t.Errorf("Test[%d] Failed: Expected type '%v' but got '%v'", count, test.expectedType, result)
+		}
+		if result := IsRetryable(test.err); result != test.expectedRetryable {
+			t.Errorf("Test[%d] Failed: Expected retryable '%v' but got '%v'", count, test.expectedRetryable, result)
+		}
+		if classified, ok := classifyError(test.err).(*Error); !ok || classified.Type != test.expectedType {
+			t.Errorf("Test[%d] Failed: Expected classified error of type '%v' but got '%+v'", count, test.expectedType, classified)
+		}
+	}
+
+	if classifyError(nil) != nil {
+		t.Errorf("Expected a nil error to be classified as nil")
+	}
+	if IsRetryable(nil) || IsNotFound(nil) || IsForbidden(nil) {
+		t.Errorf("Expected a nil error to have no type")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	rateLimitError := &github.RateLimitError{
+		Rate: github.Rate{
+			Reset: github.Timestamp{Time: time.Now().Add(1 * time.Hour)},
+		},
+	}
+
+	if delay := RetryAfter(classifyError(rateLimitError)); delay <= 59*time.Minute || delay > 1*time.Hour {
+		t.Errorf("Expected a delay of about 1 hour, but got %v", delay)
+	}
+	if delay := RetryAfter(fmt.Errorf("some error")); delay != 0 {
+		t.Errorf("Expected no delay, but got %v", delay)
+	}
+}
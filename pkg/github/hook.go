@@ -9,7 +9,7 @@ import (
 // NewGithubHook returns a GitHub representation of a hook
 // GitHub hook refenrence: https://developer.github.com/v3/repos/hooks/#parameters
 func NewGithubHook(hook api.Hook) *github.Hook {
-	return &github.Hook{
+	githubHook := &github.Hook{
 		Name:   func(name string) *string { return &name }("web"),
 		Active: func(active bool) *bool { return &active }(true),
 		Events: []string{"*"},
@@ -19,6 +19,10 @@ func NewGithubHook(hook api.Hook) *github.Hook {
 			"insecure_ssl": "true",
 		},
 	}
+	if len(hook.Secret) > 0 {
+		githubHook.Config["secret"] = hook.Secret
+	}
+	return githubHook
 }
 
 // HooksMatches checks if the given GitHub hook is the same as the current OpenShift hook
@@ -2,10 +2,12 @@ package github
 
 import (
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/vbehar/openshift-github-hooks/pkg/api"
 
@@ -13,17 +15,42 @@ import (
 	"github.com/google/go-github/github"
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
+
+	utilerrors "k8s.io/kubernetes/pkg/util/errors"
 )
 
 // HooksManager provides an easy way to manage GitHub hooks
+// The errors returned by the GitHub API are classified (see Error),
+// so that the callers can retry only the transient ones.
+// All the methods accept a context, used to cancel the GitHub calls.
 type HooksManager struct {
-	client *github.Client
+	// transport is the authenticated transport used for all the GitHub calls
+	transport http.RoundTripper
+
+	// baseURL is the GitHub API base URL, or nil for the default api.github.com endpoint
+	baseURL *url.URL
+
+	timeouts Timeouts
+
+	// SameURLFunc checks if 2 hook URLs target the same endpoint (optional - URLs are compared as-is if not set).
+	// It is used to find an existing hook whose URL is written differently than the given hook's URL.
+	SameURLFunc func(url, otherURL string) bool
+}
+
+// Timeouts defines the timeouts of the GitHub calls
+type Timeouts struct {
+	// Request is the timeout of a single HTTP request to the GitHub API (0 means no timeout)
+	Request time.Duration
+
+	// Operation is the timeout of a HooksManager method call,
+	// which may perform multiple HTTP requests (0 means no timeout)
+	Operation time.Duration
 }
 
 // NewHooksManager instantiates a HooksManager
 // using the given GitHub base URL and access token
 // (you can leave the baseURL empty to use the default api.github.com endpoint)
-func NewHooksManager(baseURL, token string, insecureSkipVerify bool) (*HooksManager, error) {
+func NewHooksManager(baseURL, token string, insecureSkipVerify bool, timeouts Timeouts) (*HooksManager, error) {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
@@ -36,7 +63,11 @@ func NewHooksManager(baseURL, token string, insecureSkipVerify bool) (*HooksMana
 	ctx := context.WithValue(oauth2.NoContext, oauth2.HTTPClient, httpClient)
 	tc := oauth2.NewClient(ctx, ts)
 
-	client := github.NewClient(tc)
+	manager := &HooksManager{
+		transport: tc.Transport,
+		timeouts:  timeouts,
+	}
+
 	if len(baseURL) > 0 {
 		// ensure the api base URL ends with a "/"
 		if !strings.HasSuffix(baseURL, "/") {
@@ -46,70 +77,163 @@ func NewHooksManager(baseURL, token string, insecureSkipVerify bool) (*HooksMana
 		if err != nil {
 			return nil, err
 		}
-		client.BaseURL = clientBaseURL
+		manager.baseURL = clientBaseURL
 	}
 
-	manager := &HooksManager{
-		client: client,
+	return manager, nil
+}
+
+// operationContext returns a context for a single HooksManager method call,
+// limited by the operation timeout
+func (gh *HooksManager) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if gh.timeouts.Operation > 0 {
+		return context.WithTimeout(ctx, gh.timeouts.Operation)
 	}
+	return context.WithCancel(ctx)
+}
 
-	return manager, nil
+// clientFor returns a GitHub client whose requests are cancelled when the given context is done,
+// and limited by the request timeout
+func (gh *HooksManager) clientFor(ctx context.Context) *github.Client {
+	client := github.NewClient(&http.Client{
+		Transport: &contextTransport{
+			ctx:  ctx,
+			base: gh.transport,
+		},
+		Timeout: gh.timeouts.Request,
+	})
+	if gh.baseURL != nil {
+		client.BaseURL = gh.baseURL
+	}
+	return client
+}
+
+// sameURL checks if the given hook URLs target the same endpoint
+func (gh *HooksManager) sameURL(url, otherURL string) bool {
+	if gh.SameURLFunc != nil {
+		return gh.SameURLFunc(url, otherURL)
+	}
+	return url == otherURL
 }
 
 // RegisterHook registers the given hook (only if the hook does not already exists)
-// returns true if the hook has been created
-func (gh *HooksManager) RegisterHook(hook api.Hook) (bool, error) {
+// If the hook has an ID, it is the previous hook for the same BuildConfig,
+// which will be updated with the new hook's URL (or deleted if a hook with the new URL already exists).
+// returns the hook as registered on GitHub (with its ID), and true if the hook has been created or updated
+func (gh *HooksManager) RegisterHook(ctx context.Context, hook api.Hook) (*api.Hook, bool, error) {
 	glog.V(2).Infof("Creating Hook %s on Github repository %s ...", hook.TargetURL, hook.GithubRepository)
 
-	hookExists, err := gh.hookExists(hook)
+	ctx, cancel := gh.operationContext(ctx)
+	defer cancel()
+	client := gh.clientFor(ctx)
+
+	existingHook, equivalentHook, err := findHook(client, hook, gh.sameURL)
 	if err != nil {
-		return false, err
+		return nil, false, classifyError(err)
+	}
+	if existingHook == nil && equivalentHook != nil && hook.ID == 0 {
+		// same endpoint with a different URL: update it instead of creating a duplicate
+		glog.V(2).Infof("Hook %d on Github repository %s targets the same endpoint as %s - it will be updated", equivalentHook.ID, hook.GithubRepository, hook.TargetURL)
+		hook.ID = equivalentHook.ID
 	}
-	if hookExists {
+	if existingHook != nil {
+		if hook.ID != 0 && hook.ID != existingHook.ID {
+			if _, err = deleteHookByID(client, hook.GithubRepository, hook.ID); err != nil {
+				return nil, false, classifyError(err)
+			}
+			glog.V(1).Infof("Previous hook %d deleted on Github repository %s", hook.ID, hook.GithubRepository)
+		}
 		glog.V(2).Infof("Hook %s already exists on Github repository %s - nothing to do", hook.TargetURL, hook.GithubRepository)
-		return false, nil
+		return existingHook, false, nil
 	}
 
 	githubHook := NewGithubHook(hook)
-	_, _, err = gh.client.Repositories.CreateHook(hook.GithubRepository.Owner, hook.GithubRepository.Name, githubHook)
+	if hook.ID != 0 {
+		updatedHook, _, err := client.Repositories.EditHook(hook.GithubRepository.Owner, hook.GithubRepository.Name, hook.ID, githubHook)
+		if err == nil {
+			glog.V(1).Infof("Hook %d updated with URL %s on Github repository %s", hook.ID, hook.TargetURL, hook.GithubRepository)
+			return registeredHook(hook, updatedHook), true, nil
+		}
+		if !IsNotFound(err) {
+			return nil, false, classifyError(err)
+		}
+		glog.V(2).Infof("Previous hook %d not found on Github repository %s - creating a new one", hook.ID, hook.GithubRepository)
+	}
+
+	createdHook, _, err := client.Repositories.CreateHook(hook.GithubRepository.Owner, hook.GithubRepository.Name, githubHook)
 	if err != nil {
-		return false, err
+		return nil, false, classifyError(err)
 	}
 
 	glog.V(1).Infof("Hook %s created on Github repository %s", hook.TargetURL, hook.GithubRepository)
-	return true, nil
+	return registeredHook(hook, createdHook), true, nil
 }
 
-// hookExists checks if the given hook (URL) exists
-func (gh *HooksManager) hookExists(hook api.Hook) (bool, error) {
-	hooks, err := gh.ListHooksForRepository(hook.GithubRepository)
+// registeredHook returns a copy of the given hook with the ID of the given GitHub hook
+func registeredHook(hook api.Hook, githubHook *github.Hook) *api.Hook {
+	result := hook
+	result.ID = 0
+	if githubHook != nil && githubHook.ID != nil {
+		result.ID = *githubHook.ID
+	}
+	return &result
+}
+
+// findHook returns the existing hook with the same URL as the given hook,
+// or nil if there is no such hook.
+// It also returns an existing hook with a different URL targeting the same endpoint
+// (according to the given sameURL func), or nil if there is no such hook.
+func findHook(client *github.Client, hook api.Hook, sameURL func(url, otherURL string) bool) (existing *api.Hook, equivalent *api.Hook, err error) {
+	hooks, err := listHooksForRepository(client, hook.GithubRepository)
 	if err != nil {
-		return false, err
+		return nil, nil, err
 	}
 
-	for _, h := range hooks {
-		if hook.TargetURL == h.TargetURL {
-			return true, nil
+	for i := range hooks {
+		switch {
+		case hook.TargetURL == hooks[i].TargetURL:
+			return &hooks[i], nil, nil
+		case equivalent == nil && sameURL(hook.TargetURL, hooks[i].TargetURL):
+			equivalent = &hooks[i]
 		}
 	}
-	return false, nil
+	return nil, equivalent, nil
 }
 
 // DeleteHook deletes the given hook
+// If the hook has an ID, it is deleted by ID, otherwise it is matched by URL.
 // returns true if the hook has been deleted
-func (gh *HooksManager) DeleteHook(hook api.Hook) (bool, error) {
+func (gh *HooksManager) DeleteHook(ctx context.Context, hook api.Hook) (bool, error) {
+	ctx, cancel := gh.operationContext(ctx)
+	defer cancel()
+	client := gh.clientFor(ctx)
+
+	if hook.ID != 0 {
+		glog.V(2).Infof("Deleting Hook %d from Github repository %s ...", hook.ID, hook.GithubRepository)
+		deleted, err := deleteHookByID(client, hook.GithubRepository, hook.ID)
+		if err != nil {
+			return false, classifyError(err)
+		}
+		if deleted {
+			glog.V(1).Infof("Hook %d deleted on Github repository %s", hook.ID, hook.GithubRepository)
+		} else {
+			glog.V(2).Infof("Hook %d not found on Github repository %s - nothing to do", hook.ID, hook.GithubRepository)
+		}
+		return deleted, nil
+	}
+
 	glog.V(2).Infof("Deleting Hook %s from Github repository %s ...", hook.TargetURL, hook.GithubRepository)
 
-	hooks, err := gh.listHooks(hook.GithubRepository)
+	hooks, err := listHooks(client, hook.GithubRepository)
 	if err != nil {
-		return false, err
+		return false, classifyError(err)
 	}
 
 	for _, h := range hooks {
-		if HooksMatches(hook, h) {
-			_, err = gh.client.Repositories.DeleteHook(hook.GithubRepository.Owner, hook.GithubRepository.Name, *h.ID)
+		if url, ok := h.Config["url"].(string); ok && gh.sameURL(hook.TargetURL, url) {
+			_, err = client.Repositories.DeleteHook(hook.GithubRepository.Owner, hook.GithubRepository.Name, *h.ID)
 			if err != nil {
-				return false, err
+				return false, classifyError(err)
 			}
 
 			glog.V(1).Infof("Hook %s deleted on Github repository %s", hook.TargetURL, hook.GithubRepository)
@@ -121,12 +245,88 @@ func (gh *HooksManager) DeleteHook(hook api.Hook) (bool, error) {
 	return false, nil
 }
 
-// ListHooksForOrganization returns all the hooks for all the repositories in given github organization
-func (gh *HooksManager) ListHooksForOrganization(org string) ([]api.Hook, error) {
+// DeleteHooks deletes the given hooks from the given repository, in a single batch:
+// the hooks of the repository are listed once, and each matching hook (by ID, or by URL if it has no ID)
+// is deleted. It tries to delete all the hooks even if some deletions fail.
+// returns the number of deleted hooks, and the first error
+func (gh *HooksManager) DeleteHooks(ctx context.Context, repository api.GithubRepository, hooks []api.Hook) (int, error) {
+	glog.V(2).Infof("Deleting %d Hooks from Github repository %s ...", len(hooks), repository)
+
+	ctx, cancel := gh.operationContext(ctx)
+	defer cancel()
+	client := gh.clientFor(ctx)
+
+	githubHooks, err := listHooks(client, repository)
+	if err != nil {
+		if IsNotFound(err) {
+			glog.V(2).Infof("Github repository %s not found - nothing to do", repository)
+			return 0, nil
+		}
+		return 0, classifyError(err)
+	}
+
+	var firstErr error
+	deleted := 0
+	for _, githubHook := range githubHooks {
+		if githubHook.ID == nil || !hooksContain(hooks, githubHook.Hook, gh.sameURL) {
+			continue
+		}
+		if _, err := deleteHookByID(client, repository, *githubHook.ID); err != nil {
+			glog.Errorf("Failed to delete hook %d from Github repository %s: %v", *githubHook.ID, repository, err)
+			if firstErr == nil {
+				firstErr = classifyError(err)
+			}
+			continue
+		}
+		glog.V(1).Infof("Hook %d deleted on Github repository %s", *githubHook.ID, repository)
+		deleted++
+	}
+	return deleted, firstErr
+}
+
+// hooksContain checks if the given GitHub hook is one of the given hooks
+// (matched by ID, or by URL for the hooks without ID)
+func hooksContain(hooks []api.Hook, githubHook github.Hook, sameURL func(url, otherURL string) bool) bool {
+	for _, hook := range hooks {
+		if hook.ID != 0 {
+			if githubHook.ID != nil && hook.ID == *githubHook.ID {
+				return true
+			}
+			continue
+		}
+		if url, ok := githubHook.Config["url"].(string); ok && sameURL(hook.TargetURL, url) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteHookByID deletes the hook with the given ID from the given repository
+// returns true if the hook has been deleted, false if it did not exist
+func deleteHookByID(client *github.Client, repository api.GithubRepository, id int) (bool, error) {
+	_, err := client.Repositories.DeleteHook(repository.Owner, repository.Name, id)
+	if err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, classifyError(err)
+	}
+	return true, nil
+}
+
+// ListHooksForOrganization returns all the hooks for all the repositories in given github organization.
+// If the hooks of some repositories could not be listed, it returns the hooks of the other repositories
+// with an error: the result is partial, and should not be compared with the desired hooks.
+func (gh *HooksManager) ListHooksForOrganization(ctx context.Context, org string) ([]api.Hook, error) {
 	glog.V(2).Infof("Listing hooks for organization %s ...", org)
-	githubRepositories, err := gh.getOrganizationRepositories(org)
+
+	ctx, cancel := gh.operationContext(ctx)
+	defer cancel()
+	client := gh.clientFor(ctx)
+
+	githubRepositories, err := getOrganizationRepositories(client, org)
 	if err != nil {
-		return []api.Hook{}, err
+		return []api.Hook{}, classifyError(err)
 	}
 	repositories := []api.GithubRepository{}
 	for i := range githubRepositories {
@@ -136,28 +336,51 @@ func (gh *HooksManager) ListHooksForOrganization(org string) ([]api.Hook, error)
 		}
 		repositories = append(repositories, r)
 	}
-	return gh.listHooksForRepositories(repositories)
+	hooks, err := listHooksForRepositories(client, repositories)
+	if err == nil && ctx.Err() != nil {
+		// the hooks of some repositories could not be listed
+		err = ctx.Err()
+	}
+	return hooks, classifyError(err)
 }
 
 // ListHooksForRepository returns all the hooks for the given github repository
-func (gh *HooksManager) ListHooksForRepository(repository api.GithubRepository) ([]api.Hook, error) {
+func (gh *HooksManager) ListHooksForRepository(ctx context.Context, repository api.GithubRepository) ([]api.Hook, error) {
 	glog.V(2).Infof("Listing hooks for repository %s ...", repository)
-	if _, _, err := gh.client.Repositories.Get(repository.Owner, repository.Name); err != nil {
+
+	ctx, cancel := gh.operationContext(ctx)
+	defer cancel()
+
+	hooks, err := listHooksForRepository(gh.clientFor(ctx), repository)
+	return hooks, classifyError(err)
+}
+
+// listHooksForRepository returns all the hooks for the given github repository,
+// or an error if the repository does not exist
+func listHooksForRepository(client *github.Client, repository api.GithubRepository) ([]api.Hook, error) {
+	if _, _, err := client.Repositories.Get(repository.Owner, repository.Name); err != nil {
 		return []api.Hook{}, err
 	}
-	return gh.listHooksForRepositories([]api.GithubRepository{repository})
+	return listHooksForRepositories(client, []api.GithubRepository{repository})
 }
 
 // listHooksForRepositories returns all the non-empty hooks for the given list of github repositories.
-func (gh *HooksManager) listHooksForRepositories(repositories []api.GithubRepository) ([]api.Hook, error) {
+// If the hooks of some repositories could not be listed, it returns the hooks of the other repositories,
+// and an aggregated error of the repositories that failed.
+func listHooksForRepositories(client *github.Client, repositories []api.GithubRepository) ([]api.Hook, error) {
 	hooks := []api.Hook{}
+	errs := []error{}
+	errsLock := sync.Mutex{}
 	wg := &sync.WaitGroup{}
 	c := make(chan api.Hook)
 	// this "limiter" is used to limit the number of parallel requests to github
 	limiter := make(chan struct{}, 5)
 
+	done := make(chan struct{})
+
 	// single goroutine that writes results to the repositoriesAndHooks array
 	go func(c <-chan api.Hook) {
+		defer close(done)
 		for {
 			hook, open := <-c
 			if !open {
@@ -176,9 +399,15 @@ func (gh *HooksManager) listHooksForRepositories(repositories []api.GithubReposi
 			defer func() {
 				<-limiter
 			}()
-			githubHooks, err := gh.listHooks(repository)
+			githubHooks, err := listHooks(client, repository)
 			if err != nil {
 				glog.Errorf("Failed to list hooks for repository %s: %v", repository, err)
+				errsLock.Lock()
+				errs = append(errs, &Error{
+					Type: errorType(err),
+					Err:  fmt.Errorf("Failed to list hooks for repository %s: %v", repository, err),
+				})
+				errsLock.Unlock()
 				return
 			}
 			for h := range githubHooks {
@@ -188,9 +417,12 @@ func (gh *HooksManager) listHooksForRepositories(repositories []api.GithubReposi
 				}
 				if len(hookURL) > 0 {
 					c <- api.Hook{
+						ID:               *githubHooks[h].ID,
 						Enabled:          true,
 						TargetURL:        hookURL,
 						GithubRepository: repository,
+						Inactive:         githubHooks[h].Active != nil && !*githubHooks[h].Active,
+						Details:          githubHooks[h].details(),
 					}
 				} else {
 					glog.V(5).Infof("Ignoring empty hook on repository %s", repository)
@@ -200,22 +432,67 @@ func (gh *HooksManager) listHooksForRepositories(repositories []api.GithubReposi
 	}
 
 	wg.Wait()
-	return hooks, nil
+	// wait for all the results to be written
+	close(c)
+	<-done
+
+	if len(errs) == 1 {
+		// keep the type of the error
+		return hooks, errs[0]
+	}
+	return hooks, utilerrors.NewAggregate(errs)
+}
+
+// repositoryHook is a hook as returned by the github api,
+// with the response of its last delivery (which is not exposed by the github library)
+type repositoryHook struct {
+	github.Hook
+	LastResponse *struct {
+		Code    *int    `json:"code,omitempty"`
+		Status  *string `json:"status,omitempty"`
+		Message *string `json:"message,omitempty"`
+	} `json:"last_response,omitempty"`
+}
+
+// details returns the details of the hook
+func (h repositoryHook) details() *api.HookDetails {
+	details := &api.HookDetails{
+		Events:    h.Events,
+		CreatedAt: h.CreatedAt,
+		UpdatedAt: h.UpdatedAt,
+	}
+	if contentType, ok := h.Config["content_type"].(string); ok {
+		details.ContentType = contentType
+	}
+	if r := h.LastResponse; r != nil {
+		if r.Code != nil {
+			details.LastResponse.Code = *r.Code
+		}
+		if r.Status != nil {
+			details.LastResponse.Status = *r.Status
+		}
+		if r.Message != nil {
+			details.LastResponse.Message = *r.Message
+		}
+	}
+	return details
 }
 
 // listHooks lists the hooks from the github api for the given repository
-func (gh *HooksManager) listHooks(repository api.GithubRepository) ([]github.Hook, error) {
+func listHooks(client *github.Client, repository api.GithubRepository) ([]repositoryHook, error) {
 	glog.V(3).Infof("Listing hooks for repository %s ...", repository)
-	hooks := []github.Hook{}
+	hooks := []repositoryHook{}
 	page := 1
 	for {
-		opts := &github.ListOptions{
-			PerPage: 100,
-			Page:    page,
+		u := fmt.Sprintf("repos/%v/%v/hooks?per_page=%d&page=%d", repository.Owner, repository.Name, 100, page)
+		req, err := client.NewRequest("GET", u, nil)
+		if err != nil {
+			return []repositoryHook{}, err
 		}
-		objs, resp, err := gh.client.Repositories.ListHooks(repository.Owner, repository.Name, opts)
+		objs := []repositoryHook{}
+		resp, err := client.Do(req, &objs)
 		if err != nil {
-			return []github.Hook{}, err
+			return []repositoryHook{}, err
 		}
 		hooks = append(hooks, objs...)
 		page = resp.NextPage
@@ -229,7 +506,7 @@ func (gh *HooksManager) listHooks(repository api.GithubRepository) ([]github.Hoo
 }
 
 // getOrganizationRepositories returns the repositories for the given github organization
-func (gh *HooksManager) getOrganizationRepositories(org string) (repositories []github.Repository, err error) {
+func getOrganizationRepositories(client *github.Client, org string) (repositories []github.Repository, err error) {
 	glog.V(3).Infof("Listing repositories for organization %s ...", org)
 	page := 1
 	for {
@@ -240,7 +517,7 @@ func (gh *HooksManager) getOrganizationRepositories(org string) (repositories []
 				Page:    page,
 			},
 		}
-		repos, resp, err := gh.client.Repositories.ListByOrg(org, opts)
+		repos, resp, err := client.Repositories.ListByOrg(org, opts)
 		if err != nil {
 			return repositories, err
 		}
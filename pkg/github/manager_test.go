@@ -0,0 +1,288 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"golang.org/x/net/context"
+)
+
+func TestHooksManagerListHooksForRepository(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo":
+			fmt.Fprint(w, `{"name": "repo"}`)
+		case "/repos/owner/repo/hooks":
+			fmt.Fprint(w, `[{"id": 1, "config": {"url": "https://openshift/hook"}}, {"id": 2, "config": {}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewHooksManager(server.URL, "token", false, Timeouts{Request: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create the hooks manager: %v", err)
+	}
+
+	hooks, err := manager.ListHooksForRepository(context.Background(), api.GithubRepository{Owner: "owner", Name: "repo"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].ID != 1 || hooks[0].TargetURL != "https://openshift/hook" {
+		t.Errorf("Expected a single hook, but got %+v", hooks)
+	}
+
+	_, err = manager.ListHooksForRepository(context.Background(), api.GithubRepository{Owner: "owner", Name: "unknown"})
+	if !IsNotFound(err) {
+		t.Errorf("Expected a not found error, but got %v", err)
+	}
+}
+
+func TestHooksManagerListHooksForOrganizationWithFailedRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/my-org/repos":
+			fmt.Fprint(w, `[{"name": "repo", "owner": {"login": "my-org"}}, {"name": "broken", "owner": {"login": "my-org"}}]`)
+		case "/repos/my-org/repo/hooks":
+			fmt.Fprint(w, `[{"id": 1, "config": {"url": "https://openshift/hook"}}]`)
+		case "/repos/my-org/broken/hooks":
+			http.Error(w, `{"message": "Server Error"}`, http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewHooksManager(server.URL, "token", false, Timeouts{Request: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create the hooks manager: %v", err)
+	}
+
+	hooks, err := manager.ListHooksForOrganization(context.Background(), "my-org")
+	if err == nil || !strings.Contains(err.Error(), "my-org/broken") {
+		t.Errorf("Expected an error for the repository my-org/broken, but got %v", err)
+	}
+	if !IsTransient(err) {
+		t.Errorf("Expected a transient error, but got %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].ID != 1 {
+		t.Errorf("Expected the hooks of the other repositories, but got %+v", hooks)
+	}
+}
+
+func TestHooksManagerListHooksDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo":
+			fmt.Fprint(w, `{"name": "repo"}`)
+		case "/repos/owner/repo/hooks":
+			fmt.Fprint(w, `[
+				{"id": 1, "active": true, "events": ["push"], "config": {"url": "https://openshift/hook1", "content_type": "json"},
+				 "created_at": "2016-01-02T03:04:05Z", "updated_at": "2016-02-03T04:05:06Z",
+				 "last_response": {"code": 200, "status": "active", "message": "OK"}},
+				{"id": 2, "active": false, "config": {"url": "https://openshift/hook2"},
+				 "last_response": {"code": null, "status": "unused", "message": null}}
+			]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewHooksManager(server.URL, "token", false, Timeouts{Request: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create the hooks manager: %v", err)
+	}
+
+	hooks, err := manager.ListHooksForRepository(context.Background(), api.GithubRepository{Owner: "owner", Name: "repo"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(hooks) != 2 {
+		t.Fatalf("Expected 2 hooks, but got %+v", hooks)
+	}
+
+	details := map[int]*api.HookDetails{}
+	for _, hook := range hooks {
+		if hook.Details == nil {
+			t.Fatalf("Expected details for hook %d, but got nil", hook.ID)
+		}
+		details[hook.ID] = hook.Details
+	}
+
+	first := details[1]
+	if len(first.Events) != 1 || first.Events[0] != "push" {
+		t.Errorf("Expected events [push] for hook 1, but got %v", first.Events)
+	}
+	if first.ContentType != "json" {
+		t.Errorf("Expected content type json for hook 1, but got %s", first.ContentType)
+	}
+	if first.CreatedAt == nil || !first.CreatedAt.Equal(time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected created time 2016-01-02T03:04:05Z for hook 1, but got %v", first.CreatedAt)
+	}
+	if first.UpdatedAt == nil || !first.UpdatedAt.Equal(time.Date(2016, 2, 3, 4, 5, 6, 0, time.UTC)) {
+		t.Errorf("Expected updated time 2016-02-03T04:05:06Z for hook 1, but got %v", first.UpdatedAt)
+	}
+	if expected := (api.HookResponse{Code: 200, Status: "active", Message: "OK"}); first.LastResponse != expected {
+		t.Errorf("Expected last response %+v for hook 1, but got %+v", expected, first.LastResponse)
+	}
+
+	second := details[2]
+	if expected := (api.HookResponse{Status: "unused"}); second.LastResponse != expected {
+		t.Errorf("Expected last response %+v for hook 2, but got %+v", expected, second.LastResponse)
+	}
+	if second.CreatedAt != nil {
+		t.Errorf("Expected no created time for hook 2, but got %v", second.CreatedAt)
+	}
+}
+
+func TestHooksManagerTimeoutsAndCancellation(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// simulates a hung connection
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	repository := api.GithubRepository{Owner: "owner", Name: "repo"}
+
+	tests := []struct {
+		timeouts Timeouts
+		ctx      func() (context.Context, context.CancelFunc)
+	}{
+		{
+			timeouts: Timeouts{Request: 50 * time.Millisecond},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+		},
+		{
+			timeouts: Timeouts{Operation: 50 * time.Millisecond},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+		},
+		{
+			timeouts: Timeouts{},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+		},
+	}
+
+	for count, test := range tests {
+		manager, err := NewHooksManager(server.URL, "token", false, test.timeouts)
+		if err != nil {
+			t.Fatalf("Test[%d] Failed: Failed to create the hooks manager: %v", count, err)
+		}
+
+		ctx, cancel := test.ctx()
+		done := make(chan error, 1)
+		go func() {
+			_, err := manager.ListHooksForRepository(ctx, repository)
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if !IsTransient(err) {
+				t.Errorf("Test[%d] Failed: Expected a transient error, but got %v", count, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("Test[%d] Failed: The call has not been cancelled", count)
+		}
+		cancel()
+	}
+}
+
+func TestHooksManagerDeleteHooks(t *testing.T) {
+	deleted := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/owner/repo/hooks":
+			fmt.Fprint(w, `[{"id": 1, "config": {"url": "https://openshift/ns/a"}}, {"id": 2, "config": {"url": "https://openshift/ns/b"}}, {"id": 3, "config": {"url": "https://jenkins"}}]`)
+		case r.Method == "DELETE":
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewHooksManager(server.URL, "token", false, Timeouts{})
+	if err != nil {
+		t.Fatalf("Failed to create the hooks manager: %v", err)
+	}
+
+	count, err := manager.DeleteHooks(context.Background(), api.GithubRepository{Owner: "owner", Name: "repo"}, []api.Hook{
+		{ID: 1, TargetURL: "https://openshift/ns/a"},
+		{TargetURL: "https://openshift/ns/b"},
+		{ID: 4, TargetURL: "https://openshift/ns/c"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedDeleted := []string{"/repos/owner/repo/hooks/1", "/repos/owner/repo/hooks/2"}
+	if count != 2 || fmt.Sprint(deleted) != fmt.Sprint(expectedDeleted) {
+		t.Errorf("Expected hooks %v to be deleted, but got %d: %v", expectedDeleted, count, deleted)
+	}
+
+	count, err = manager.DeleteHooks(context.Background(), api.GithubRepository{Owner: "owner", Name: "unknown"}, []api.Hook{{ID: 1}})
+	if err != nil || count != 0 {
+		t.Errorf("Expected no deletion for an unknown repository, but got %d: %v", count, err)
+	}
+}
+
+func TestHooksManagerRegisterHookWithEquivalentURL(t *testing.T) {
+	var created, edited bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/owner/repo":
+			fmt.Fprint(w, `{"name": "repo"}`)
+		case r.URL.Path == "/repos/owner/repo/hooks" && r.Method == "GET":
+			fmt.Fprint(w, `[{"id": 1, "config": {"url": "https://openshift/legacy/hook"}}]`)
+		case r.URL.Path == "/repos/owner/repo/hooks" && r.Method == "POST":
+			created = true
+			fmt.Fprint(w, `{"id": 2, "config": {"url": "https://openshift/group/hook"}}`)
+		case r.URL.Path == "/repos/owner/repo/hooks/1" && r.Method == "PATCH":
+			edited = true
+			fmt.Fprint(w, `{"id": 1, "config": {"url": "https://openshift/group/hook"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewHooksManager(server.URL, "token", false, Timeouts{Request: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create the hooks manager: %v", err)
+	}
+	manager.SameURLFunc = func(url, otherURL string) bool {
+		return url == otherURL || (url == "https://openshift/group/hook" && otherURL == "https://openshift/legacy/hook")
+	}
+
+	hook := api.Hook{
+		Enabled:          true,
+		TargetURL:        "https://openshift/group/hook",
+		GithubRepository: api.GithubRepository{Owner: "owner", Name: "repo"},
+	}
+	registeredHook, changed, err := manager.RegisterHook(context.Background(), hook)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !changed || registeredHook == nil || registeredHook.ID != 1 {
+		t.Errorf("Expected the existing hook 1 to be updated, but got %+v (changed: %v)", registeredHook, changed)
+	}
+	if created || !edited {
+		t.Errorf("Expected the existing hook to be edited (and no hook to be created), but created: %v, edited: %v", created, edited)
+	}
+}
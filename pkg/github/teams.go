@@ -0,0 +1,135 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/golang/glog"
+	"github.com/google/go-github/github"
+	"golang.org/x/net/context"
+)
+
+// TeamPermission returns the permission ("admin", "push" or "pull") of the given team (identified by its slug)
+// of the given organization on the given repository, or an empty string if the team has no access to the repository
+func (gh *HooksManager) TeamPermission(ctx context.Context, org string, teamSlug string, repository api.GithubRepository) (string, error) {
+	glog.V(3).Infof("Checking the permission of team %s/%s on repository %s ...", org, teamSlug, repository)
+
+	ctx, cancel := gh.operationContext(ctx)
+	defer cancel()
+	client := gh.clientFor(ctx)
+
+	teamID, err := findTeamID(client, org, teamSlug)
+	if err != nil {
+		return "", classifyError(err)
+	}
+
+	repo, _, err := client.Organizations.IsTeamRepo(teamID, repository.Owner, repository.Name)
+	if err != nil {
+		if IsNotFound(err) {
+			return "", nil
+		}
+		return "", classifyError(err)
+	}
+	if repo.Permissions == nil {
+		return "", nil
+	}
+	for _, permission := range []string{"admin", "push", "pull"} {
+		if (*repo.Permissions)[permission] {
+			return permission, nil
+		}
+	}
+	return "", nil
+}
+
+// findTeamID returns the ID of the team with the given slug in the given organization
+func findTeamID(client *github.Client, org string, teamSlug string) (int, error) {
+	page := 1
+	for {
+		opts := &github.ListOptions{
+			PerPage: 100,
+			Page:    page,
+		}
+		teams, resp, err := client.Organizations.ListTeams(org, opts)
+		if err != nil {
+			return 0, err
+		}
+		for _, team := range teams {
+			if team.Slug != nil && team.ID != nil && strings.ToLower(*team.Slug) == strings.ToLower(teamSlug) {
+				return *team.ID, nil
+			}
+		}
+		page = resp.NextPage
+		if resp.NextPage == 0 {
+			break
+		}
+	}
+	return 0, &Error{Type: NotFoundError, Err: fmt.Errorf("Team %s not found in organization %s", teamSlug, org)}
+}
+
+// TeamAccessChecker checks if the teams of an organization have write access to the repositories,
+// and caches the answers
+type TeamAccessChecker struct {
+	// PermissionFunc returns the permission of the given team on the given repository
+	PermissionFunc func(ctx context.Context, org string, teamSlug string, repository api.GithubRepository) (string, error)
+
+	// Organization is the organization of the teams
+	Organization string
+
+	// TTL is the duration during which an answer is cached
+	TTL time.Duration
+
+	answers map[string]teamAccessAnswer
+	lock    sync.Mutex
+}
+
+// teamAccessAnswer is a cached answer of the TeamAccessChecker
+type teamAccessAnswer struct {
+	permission string
+	expiration time.Time
+}
+
+// NewTeamAccessChecker instantiates a new TeamAccessChecker using the given HooksManager
+func NewTeamAccessChecker(manager *HooksManager, org string, ttl time.Duration) *TeamAccessChecker {
+	return &TeamAccessChecker{
+		PermissionFunc: manager.TeamPermission,
+		Organization:   org,
+		TTL:            ttl,
+	}
+}
+
+// HasWriteAccess checks if the given team has the admin or write (push) permission on the given repository.
+// If the permission can't be retrieved, the last (expired) answer is used if there is one - otherwise the error is returned.
+func (c *TeamAccessChecker) HasWriteAccess(ctx context.Context, teamSlug string, repository api.GithubRepository) (bool, error) {
+	key := strings.ToLower(fmt.Sprintf("%s/%s", teamSlug, repository))
+
+	c.lock.Lock()
+	if c.answers == nil {
+		c.answers = map[string]teamAccessAnswer{}
+	}
+	answer, found := c.answers[key]
+	c.lock.Unlock()
+
+	if !found || time.Now().After(answer.expiration) {
+		permission, err := c.PermissionFunc(ctx, c.Organization, teamSlug, repository)
+		if err != nil {
+			if !found {
+				return false, err
+			}
+			glog.Warningf("Failed to check the permission of team %s on repository %s - using the last answer (%s): %v", teamSlug, repository, answer.permission, err)
+		} else {
+			answer = teamAccessAnswer{
+				permission: permission,
+				expiration: time.Now().Add(c.TTL),
+			}
+			c.lock.Lock()
+			c.answers[key] = answer
+			c.lock.Unlock()
+		}
+	}
+
+	return answer.permission == "admin" || answer.permission == "push", nil
+}
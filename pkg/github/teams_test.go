@@ -0,0 +1,107 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"golang.org/x/net/context"
+)
+
+func TestHooksManagerTeamPermission(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/my-org/teams":
+			fmt.Fprint(w, `[{"id": 1, "slug": "team-a"}, {"id": 2, "slug": "team-b"}]`)
+		case "/teams/1/repos/my-org/app":
+			fmt.Fprint(w, `{"name": "app", "permissions": {"admin": false, "push": true, "pull": true}}`)
+		case "/teams/2/repos/my-org/app":
+			fmt.Fprint(w, `{"name": "app", "permissions": {"admin": false, "push": false, "pull": true}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewHooksManager(server.URL, "token", false, Timeouts{Request: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create the hooks manager: %v", err)
+	}
+
+	tests := []struct {
+		team               string
+		repository         string
+		expectedPermission string
+		expectedNotFound   bool
+	}{
+		{team: "team-a", repository: "app", expectedPermission: "push"},
+		{team: "Team-B", repository: "app", expectedPermission: "pull"},
+		{team: "team-a", repository: "other", expectedPermission: ""},
+		{team: "team-c", repository: "app", expectedNotFound: true},
+	}
+
+	for count, test := range tests {
+		permission, err := manager.TeamPermission(context.Background(), "my-org", test.team, api.GithubRepository{Owner: "my-org", Name: test.repository})
+		if test.expectedNotFound {
+			if !IsNotFound(err) {
+				t.Errorf("Test[%d] Failed: Expected a not found error but got %v", count, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test[%d] Failed: got unexpected error %v", count, err)
+			continue
+		}
+		if permission != test.expectedPermission {
+			t.Errorf("Test[%d] Failed: Expected permission '%s' but got '%s'", count, test.expectedPermission, permission)
+		}
+	}
+}
+
+func TestTeamAccessChecker(t *testing.T) {
+	calls := 0
+	permission := "push"
+	var permissionErr error
+	checker := &TeamAccessChecker{
+		PermissionFunc: func(ctx context.Context, org string, teamSlug string, repository api.GithubRepository) (string, error) {
+			calls++
+			return permission, permissionErr
+		},
+		Organization: "my-org",
+		TTL:          1 * time.Hour,
+	}
+	repository := api.GithubRepository{Owner: "my-org", Name: "app"}
+
+	// first check: retrieved from GitHub
+	if allowed, err := checker.HasWriteAccess(context.Background(), "team-a", repository); err != nil || !allowed || calls != 1 {
+		t.Errorf("Expected the team to have write access after 1 call, but got %v (%v) after %d calls", allowed, err, calls)
+	}
+
+	// second check: cached
+	permission = "pull"
+	if allowed, err := checker.HasWriteAccess(context.Background(), "team-a", repository); err != nil || !allowed || calls != 1 {
+		t.Errorf("Expected the cached answer, but got %v (%v) after %d calls", allowed, err, calls)
+	}
+
+	// expired answer: retrieved again
+	checker.TTL = 0
+	checker.answers = nil
+	if allowed, err := checker.HasWriteAccess(context.Background(), "team-a", repository); err != nil || allowed || calls != 2 {
+		t.Errorf("Expected the team to have no write access after 2 calls, but got %v (%v) after %d calls", allowed, err, calls)
+	}
+
+	// failed check with an expired answer: the last answer is used
+	permissionErr = fmt.Errorf("GitHub is down")
+	if allowed, err := checker.HasWriteAccess(context.Background(), "team-a", repository); err != nil || allowed || calls != 3 {
+		t.Errorf("Expected the last answer, but got %v (%v) after %d calls", allowed, err, calls)
+	}
+
+	// failed check without answer: the error is returned
+	if allowed, err := checker.HasWriteAccess(context.Background(), "team-b", repository); err == nil || allowed {
+		t.Errorf("Expected an error, but got %v (%v)", allowed, err)
+	}
+}
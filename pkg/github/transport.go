@@ -0,0 +1,58 @@
+package github
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// contextTransport is an http.RoundTripper that cancels the requests
+// when its context is done
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+// RoundTrip is for the http.RoundTripper implementation
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case <-t.ctx.Done():
+		return nil, t.ctx.Err()
+	default:
+	}
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	c := make(chan result, 1)
+	go func() {
+		resp, err := t.base.RoundTrip(req)
+		c <- result{resp, err}
+	}()
+
+	select {
+	case r := <-c:
+		return r.resp, r.err
+	case <-t.ctx.Done():
+		t.CancelRequest(req)
+		// make sure the response body is closed, if the request completed anyway
+		go func() {
+			if r := <-c; r.resp != nil {
+				r.resp.Body.Close()
+			}
+		}()
+		return nil, t.ctx.Err()
+	}
+}
+
+// CancelRequest cancels an in-flight request, if the base transport supports it.
+// It is also used by the http.Client to enforce its timeout.
+func (t *contextTransport) CancelRequest(req *http.Request) {
+	type canceler interface {
+		CancelRequest(*http.Request)
+	}
+	if cr, ok := t.base.(canceler); ok {
+		cr.CancelRequest(req)
+	}
+}
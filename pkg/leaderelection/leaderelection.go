@@ -0,0 +1,180 @@
+package leaderelection
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/util/wait"
+)
+
+// Config is the configuration of a leader election
+type Config struct {
+	// Lock is the object used to hold the leader election record
+	Lock ResourceLock
+
+	// Identity is the unique identity of the current candidate (usually the pod name)
+	Identity string
+
+	// LeaseDuration is the duration that non-leader candidates will wait
+	// before trying to acquire the leadership (from the last observed renewal)
+	LeaseDuration time.Duration
+
+	// RenewDeadline is the duration that the leader will retry refreshing its leadership
+	// before giving up
+	RenewDeadline time.Duration
+
+	// RetryPeriod is the duration that candidates should wait between tries
+	RetryPeriod time.Duration
+
+	// OnStartedLeading is called when the current candidate becomes the leader
+	// The given channel is closed when the leadership is lost.
+	OnStartedLeading func(stop <-chan struct{})
+
+	// OnStoppedLeading is called when the current candidate loses the leadership
+	OnStoppedLeading func()
+}
+
+// LeaderElector is used to elect a leader between several candidates
+type LeaderElector struct {
+	config Config
+
+	// observedRecord is the last record observed on the lock,
+	// and observedTime the local time at which it has been observed
+	// (we can't trust the remote times because of clock skews)
+	observedRecord Record
+	observedTime   time.Time
+
+	// now returns the current time - only overwritten in tests
+	now func() time.Time
+}
+
+// NewLeaderElector instantiates a new LeaderElector for the given config
+func NewLeaderElector(config Config) (*LeaderElector, error) {
+	if config.Lock == nil {
+		return nil, fmt.Errorf("Lock must not be nil")
+	}
+	if len(config.Identity) == 0 {
+		return nil, fmt.Errorf("Identity must not be empty")
+	}
+	if config.LeaseDuration <= config.RenewDeadline {
+		return nil, fmt.Errorf("LeaseDuration (%v) must be greater than RenewDeadline (%v)", config.LeaseDuration, config.RenewDeadline)
+	}
+	if config.RenewDeadline <= config.RetryPeriod {
+		return nil, fmt.Errorf("RenewDeadline (%v) must be greater than RetryPeriod (%v)", config.RenewDeadline, config.RetryPeriod)
+	}
+	if config.OnStartedLeading == nil || config.OnStoppedLeading == nil {
+		return nil, fmt.Errorf("OnStartedLeading and OnStoppedLeading callbacks must not be nil")
+	}
+	return &LeaderElector{
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// RunUntil tries to acquire the leadership, and then keeps renewing it,
+// until either the leadership is lost or stopChan is closed.
+// It blocks until then.
+func (le *LeaderElector) RunUntil(stopChan <-chan struct{}) {
+	if !le.acquire(stopChan) {
+		return
+	}
+
+	leadingChan := make(chan struct{})
+	go le.config.OnStartedLeading(leadingChan)
+	le.renew(stopChan)
+	close(leadingChan)
+	le.config.OnStoppedLeading()
+}
+
+// acquire loops until the leadership is acquired (returns true)
+// or stopChan is closed (returns false)
+func (le *LeaderElector) acquire(stopChan <-chan struct{}) bool {
+	glog.Infof("Trying to acquire the leadership on %s as %s ...", le.config.Lock.Describe(), le.config.Identity)
+	for {
+		if le.tryAcquireOrRenew() {
+			glog.Infof("Acquired the leadership on %s as %s", le.config.Lock.Describe(), le.config.Identity)
+			return true
+		}
+		glog.V(4).Infof("Failed to acquire the leadership on %s - current leader is %s", le.config.Lock.Describe(), le.observedRecord.HolderIdentity)
+
+		select {
+		case <-stopChan:
+			return false
+		case <-time.After(le.config.RetryPeriod):
+		}
+	}
+}
+
+// renew loops until the leadership can't be renewed anymore or stopChan is closed
+func (le *LeaderElector) renew(stopChan <-chan struct{}) {
+	for {
+		err := wait.Poll(le.config.RetryPeriod, le.config.RenewDeadline, func() (bool, error) {
+			select {
+			case <-stopChan:
+				return false, fmt.Errorf("stopped")
+			default:
+			}
+			return le.tryAcquireOrRenew(), nil
+		})
+		if err != nil {
+			glog.Warningf("Lost the leadership on %s: %v", le.config.Lock.Describe(), err)
+			return
+		}
+		glog.V(5).Infof("Renewed the leadership on %s", le.config.Lock.Describe())
+
+		select {
+		case <-stopChan:
+			return
+		case <-time.After(le.config.RetryPeriod):
+		}
+	}
+}
+
+// tryAcquireOrRenew tries to acquire the leadership (or renew it if we are already the leader)
+// It returns true on success.
+func (le *LeaderElector) tryAcquireOrRenew() bool {
+	now := le.now()
+	record := Record{
+		HolderIdentity:       le.config.Identity,
+		LeaseDurationSeconds: int(le.config.LeaseDuration / time.Second),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	oldRecord, err := le.config.Lock.Get()
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			glog.Errorf("Failed to retrieve the leader election record from %s: %v", le.config.Lock.Describe(), err)
+			return false
+		}
+		if err = le.config.Lock.Create(record); err != nil {
+			glog.Errorf("Failed to create the leader election record on %s: %v", le.config.Lock.Describe(), err)
+			return false
+		}
+		le.observedRecord = record
+		le.observedTime = now
+		return true
+	}
+
+	if !oldRecord.equal(le.observedRecord) {
+		le.observedRecord = *oldRecord
+		le.observedTime = now
+	}
+	if len(oldRecord.HolderIdentity) > 0 && oldRecord.HolderIdentity != le.config.Identity && le.observedTime.Add(le.config.LeaseDuration).After(now) {
+		return false
+	}
+
+	if oldRecord.HolderIdentity == le.config.Identity {
+		record.AcquireTime = oldRecord.AcquireTime
+	}
+	if err = le.config.Lock.Update(record); err != nil {
+		glog.Errorf("Failed to update the leader election record on %s: %v", le.config.Lock.Describe(), err)
+		return false
+	}
+	le.observedRecord = record
+	le.observedTime = now
+	return true
+}
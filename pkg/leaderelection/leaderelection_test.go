@@ -0,0 +1,156 @@
+package leaderelection
+
+import (
+	"testing"
+	"time"
+
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+// fakeLock is an in-memory ResourceLock
+type fakeLock struct {
+	record *Record
+}
+
+func (l *fakeLock) Get() (*Record, error) {
+	if l.record == nil {
+		return nil, kerrors.NewNotFound(unversioned.GroupResource{Resource: "configmaps"}, "lock")
+	}
+	record := *l.record
+	return &record, nil
+}
+
+func (l *fakeLock) Create(record Record) error {
+	l.record = &record
+	return nil
+}
+
+func (l *fakeLock) Update(record Record) error {
+	l.record = &record
+	return nil
+}
+
+func (l *fakeLock) Describe() string {
+	return "fake lock"
+}
+
+func TestLeaderElectorTryAcquireOrRenew(t *testing.T) {
+	start := time.Date(2016, 5, 10, 14, 30, 0, 0, time.UTC)
+	newElector := func(identity string, lock ResourceLock, now *time.Time) *LeaderElector {
+		return &LeaderElector{
+			config: Config{
+				Lock:          lock,
+				Identity:      identity,
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			now: func() time.Time { return *now },
+		}
+	}
+
+	lock := &fakeLock{}
+	now := start
+	a := newElector("a", lock, &now)
+	b := newElector("b", lock, &now)
+
+	// a creates the lock
+	if !a.tryAcquireOrRenew() {
+		t.Fatalf("Expected 'a' to acquire a new lock")
+	}
+	// b observes a as the leader
+	if b.tryAcquireOrRenew() {
+		t.Fatalf("Expected 'b' to fail to acquire a lock held by 'a'")
+	}
+
+	// a renews its lease
+	now = start.Add(10 * time.Second)
+	if !a.tryAcquireOrRenew() {
+		t.Fatalf("Expected 'a' to renew its lease")
+	}
+	if !lock.record.AcquireTime.Equal(start) {
+		t.Errorf("Expected the acquire time to be kept on renewal, but got %v", lock.record.AcquireTime)
+	}
+	// b observes the renewal, so the lease is still valid
+	now = start.Add(20 * time.Second)
+	if b.tryAcquireOrRenew() {
+		t.Fatalf("Expected 'b' to fail to acquire a lock renewed by 'a'")
+	}
+
+	// a stops renewing, b acquires the lock once the lease expired
+	now = start.Add(30 * time.Second)
+	if b.tryAcquireOrRenew() {
+		t.Fatalf("Expected 'b' to fail to acquire a lock before the lease expired")
+	}
+	now = start.Add(36 * time.Second)
+	if !b.tryAcquireOrRenew() {
+		t.Fatalf("Expected 'b' to acquire an expired lock")
+	}
+	if lock.record.HolderIdentity != "b" {
+		t.Errorf("Expected 'b' to be the holder of the lock, but got '%s'", lock.record.HolderIdentity)
+	}
+
+	// a can't renew anymore
+	if a.tryAcquireOrRenew() {
+		t.Fatalf("Expected 'a' to fail to renew a lock held by 'b'")
+	}
+}
+
+func TestNewLeaderElector(t *testing.T) {
+	validConfig := func() Config {
+		return Config{
+			Lock:             &fakeLock{},
+			Identity:         "a",
+			LeaseDuration:    15 * time.Second,
+			RenewDeadline:    10 * time.Second,
+			RetryPeriod:      2 * time.Second,
+			OnStartedLeading: func(<-chan struct{}) {},
+			OnStoppedLeading: func() {},
+		}
+	}
+
+	tests := []struct {
+		config        func() Config
+		expectedError bool
+	}{
+		{
+			config:        validConfig,
+			expectedError: false,
+		},
+		{
+			config: func() Config {
+				c := validConfig()
+				c.Identity = ""
+				return c
+			},
+			expectedError: true,
+		},
+		{
+			config: func() Config {
+				c := validConfig()
+				c.LeaseDuration = c.RenewDeadline
+				return c
+			},
+			expectedError: true,
+		},
+		{
+			config: func() Config {
+				c := validConfig()
+				c.RetryPeriod = c.RenewDeadline
+				return c
+			},
+			expectedError: true,
+		},
+	}
+
+	for count, test := range tests {
+		_, err := NewLeaderElector(test.config())
+		if err != nil && !test.expectedError {
+			t.Errorf("Test[%d] Failed: Got an unexpected error: %v", count, err)
+		}
+		if err == nil && test.expectedError {
+			t.Errorf("Test[%d] Failed: Expected an error but got none", count)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package leaderelection
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+const (
+	// LeaderAnnotation is the annotation used to store the leader election record
+	// on the lock object (ConfigMap or Endpoints)
+	LeaderAnnotation = "openshift-github-hooks-sync/leader"
+
+	// ConfigMapsLockType is the type of lock stored on a ConfigMap
+	ConfigMapsLockType = "configmaps"
+
+	// EndpointsLockType is the type of lock stored on an Endpoints
+	EndpointsLockType = "endpoints"
+)
+
+// Record is the leader election record, stored in the lock object's annotation
+type Record struct {
+	HolderIdentity       string    `json:"holderIdentity"`
+	LeaseDurationSeconds int       `json:"leaseDurationSeconds"`
+	AcquireTime          time.Time `json:"acquireTime"`
+	RenewTime            time.Time `json:"renewTime"`
+}
+
+// equal checks if the given record is the same as the current one
+// (the times are compared with Equal, because they lose their location once serialized)
+func (r Record) equal(other Record) bool {
+	return r.HolderIdentity == other.HolderIdentity &&
+		r.LeaseDurationSeconds == other.LeaseDurationSeconds &&
+		r.AcquireTime.Equal(other.AcquireTime) &&
+		r.RenewTime.Equal(other.RenewTime)
+}
+
+// ResourceLock is the object used to hold the leader election record
+type ResourceLock interface {
+	// Get returns the current leader election record
+	Get() (*Record, error)
+
+	// Create creates the lock object with the given record
+	Create(record Record) error
+
+	// Update updates the lock object with the given record
+	// It should only be called after a successful Get
+	Update(record Record) error
+
+	// Describe returns a human-readable description of the lock
+	Describe() string
+}
+
+// NewResourceLock instantiates a new ResourceLock of the given type
+func NewResourceLock(lockType, namespace, name string, client *kclient.Client) (ResourceLock, error) {
+	switch lockType {
+	case ConfigMapsLockType:
+		return &configMapLock{
+			client:    client,
+			namespace: namespace,
+			name:      name,
+		}, nil
+	case EndpointsLockType:
+		return &endpointsLock{
+			client:    client,
+			namespace: namespace,
+			name:      name,
+		}, nil
+	}
+	return nil, fmt.Errorf("Invalid lock type %s (expected %s or %s)", lockType, ConfigMapsLockType, EndpointsLockType)
+}
+
+// configMapLock is a ResourceLock stored on a ConfigMap
+type configMapLock struct {
+	client    kclient.ConfigMapsNamespacer
+	namespace string
+	name      string
+	configMap *kapi.ConfigMap
+}
+
+// Get is for the ResourceLock implementation
+func (l *configMapLock) Get() (*Record, error) {
+	configMap, err := l.client.ConfigMaps(l.namespace).Get(l.name)
+	if err != nil {
+		return nil, err
+	}
+	l.configMap = configMap
+	return recordFromAnnotations(configMap.Annotations)
+}
+
+// Create is for the ResourceLock implementation
+func (l *configMapLock) Create(record Record) error {
+	annotations, err := recordToAnnotations(nil, record)
+	if err != nil {
+		return err
+	}
+	l.configMap, err = l.client.ConfigMaps(l.namespace).Create(&kapi.ConfigMap{
+		ObjectMeta: kapi.ObjectMeta{
+			Namespace:   l.namespace,
+			Name:        l.name,
+			Annotations: annotations,
+		},
+	})
+	return err
+}
+
+// Update is for the ResourceLock implementation
+func (l *configMapLock) Update(record Record) error {
+	if l.configMap == nil {
+		return fmt.Errorf("ConfigMap %s not initialized, call Get or Create first", l.Describe())
+	}
+	annotations, err := recordToAnnotations(l.configMap.Annotations, record)
+	if err != nil {
+		return err
+	}
+	l.configMap.Annotations = annotations
+	l.configMap, err = l.client.ConfigMaps(l.namespace).Update(l.configMap)
+	return err
+}
+
+// Describe is for the ResourceLock implementation
+func (l *configMapLock) Describe() string {
+	return fmt.Sprintf("configmap %s/%s", l.namespace, l.name)
+}
+
+// endpointsLock is a ResourceLock stored on an Endpoints
+type endpointsLock struct {
+	client    kclient.EndpointsNamespacer
+	namespace string
+	name      string
+	endpoints *kapi.Endpoints
+}
+
+// Get is for the ResourceLock implementation
+func (l *endpointsLock) Get() (*Record, error) {
+	endpoints, err := l.client.Endpoints(l.namespace).Get(l.name)
+	if err != nil {
+		return nil, err
+	}
+	l.endpoints = endpoints
+	return recordFromAnnotations(endpoints.Annotations)
+}
+
+// Create is for the ResourceLock implementation
+func (l *endpointsLock) Create(record Record) error {
+	annotations, err := recordToAnnotations(nil, record)
+	if err != nil {
+		return err
+	}
+	l.endpoints, err = l.client.Endpoints(l.namespace).Create(&kapi.Endpoints{
+		ObjectMeta: kapi.ObjectMeta{
+			Namespace:   l.namespace,
+			Name:        l.name,
+			Annotations: annotations,
+		},
+	})
+	return err
+}
+
+// Update is for the ResourceLock implementation
+func (l *endpointsLock) Update(record Record) error {
+	if l.endpoints == nil {
+		return fmt.Errorf("Endpoints %s not initialized, call Get or Create first", l.Describe())
+	}
+	annotations, err := recordToAnnotations(l.endpoints.Annotations, record)
+	if err != nil {
+		return err
+	}
+	l.endpoints.Annotations = annotations
+	l.endpoints, err = l.client.Endpoints(l.namespace).Update(l.endpoints)
+	return err
+}
+
+// Describe is for the ResourceLock implementation
+func (l *endpointsLock) Describe() string {
+	return fmt.Sprintf("endpoints %s/%s", l.namespace, l.name)
+}
+
+// recordFromAnnotations extracts the leader election record from the given annotations
+// (an empty record is returned if there is no annotation)
+func recordFromAnnotations(annotations map[string]string) (*Record, error) {
+	record := &Record{}
+	if value, found := annotations[LeaderAnnotation]; found {
+		if err := json.Unmarshal([]byte(value), record); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// recordToAnnotations returns a copy of the given annotations with the given leader election record
+func recordToAnnotations(annotations map[string]string, record Record) (map[string]string, error) {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	for k, v := range annotations {
+		result[k] = v
+	}
+	result[LeaderAnnotation] = string(value)
+	return result, nil
+}
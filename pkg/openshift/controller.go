@@ -1,8 +1,11 @@
 package openshift
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/vbehar/openshift-github-hooks/pkg/api"
@@ -14,8 +17,9 @@ import (
 
 	kapi "k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/client/cache"
+	"k8s.io/kubernetes/pkg/client/record"
 	"k8s.io/kubernetes/pkg/runtime"
-	kutil "k8s.io/kubernetes/pkg/util"
+	"k8s.io/kubernetes/pkg/util/wait"
 	"k8s.io/kubernetes/pkg/watch"
 )
 
@@ -28,42 +32,143 @@ type BuildConfigsController struct {
 	BuildConfigsNamespacer client.BuildConfigsNamespacer
 
 	// HookHandlerFunc is the function that will handle the Hook
-	HookHandlerFunc func(api.Hook) error
+	// It returns the hook as it exists on GitHub (with its ID) once handled,
+	// or nil if the hook has not been handled (or has been deleted),
+	// and true if the hook has been created or deleted on GitHub
+	HookHandlerFunc func(api.Hook) (*api.Hook, bool, error)
 
-	// KeyListFunc is a function that returns the list of keys ("namespace/name" format)
-	// that we "know about" (to get a 2-way sync)
-	KeyListFunc func() []string
-
-	// KeyGetFunc is a function that returns the object that we "know about"
-	// for the given key ("namespace/name" format) - and a boolean if it exists
-	KeyGetFunc func(key string) (interface{}, bool, error)
-
-	// ResyncPeriod is the interval of time at which the controller
-	// will perform of full resync (list) of the BuildConfigs
-	ResyncPeriod time.Duration
+	// Recorder is used to record events against the BuildConfigs
+	// to explain what happened to their hooks (optional)
+	Recorder record.EventRecorder
 
 	// OpenshiftPublicURL is the public URL of the OpenShift instance
 	// used to make sure the hook URL does not use an internal hostname ;-)
 	OpenshiftPublicURL string
+
+	// WebhookAPIPath is the API path of the generated hook URLs
+	// (LegacyWebhookAPIPath or GroupWebhookAPIPath), or empty to keep the path generated by the OpenShift client
+	WebhookAPIPath string
+
+	// RelayURL is the public URL of the relay (see the relay command), or empty if the hooks
+	// should directly target OpenShift. When set, the hooks target the relay
+	// (so that the trigger secrets never leave the cluster), and their deliveries are signed
+	// with a secret derived from the RelayKey.
+	RelayURL string
+	RelayKey []byte
+
+	// SecretReferenceFunc returns the webhook secret referenced by the GitHub trigger of the given BC,
+	// when the trigger references a Secret object instead of setting its secret inline
+	// (optional - the BCs without an inline secret are not synced if not set)
+	SecretReferenceFunc func(bc *buildapi.BuildConfig) (string, error)
+
+	// CheckRepositoryFunc returns an error if the BCs of the given namespace may not hook the given repository,
+	// or a RepositoryCheckUnknownError if it can't be checked (optional - all the repositories are allowed if not set)
+	CheckRepositoryFunc func(namespace string, repository api.GithubRepository) error
+
+	// UpdateStatus defines if the sync status of each BC should be written
+	// in the BC's annotations (hook IDs, repository, last sync, last error)
+	UpdateStatus bool
+
+	// DeletionGracePeriod is the delay before deleting the hook of a deleted BC.
+	// If an equivalent BC (same namespace and name) is created before the end of the period,
+	// the deletion is cancelled and the existing hook is kept (with its delivery history).
+	DeletionGracePeriod time.Duration
+
+	// RetryPolicy defines how the failed BuildConfigs changes are retried
+	// (the DefaultRetryPolicy is used if not set)
+	RetryPolicy RetryPolicy
+
+	// queue is where the BuildConfigs changes are stored until they are handled
+	queue *cache.DeltaFIFO
+
+	// store is the local cache of all the BuildConfigs, used to compute the desired hooks
+	store *buildConfigsStore
+
+	// lastApplied stores the last hook applied for each BC key ("namespace/name" format)
+	lastApplied     map[string]lastAppliedHook
+	lastAppliedLock sync.Mutex
+
+	// pendingDeletions stores the deletions waiting for the end of the grace period
+	pendingDeletions *pendingDeletions
+
+	// retryManager re-queues the failed BuildConfigs changes, and keeps the dead-letter list
+	retryManager *retryManager
 }
 
+// pendingDeletionsCheckPeriod is the interval at which we check for due deletions
+const pendingDeletionsCheckPeriod = 1 * time.Second
+
 // RunUntil runs the controller in a goroutine
 // until stopChan is closed
 func (c *BuildConfigsController) RunUntil(stopChan <-chan struct{}) {
-	queue := cache.NewDeltaFIFO(cache.MetaNamespaceKeyFunc, nil, c)
-	cache.NewReflector(c, &buildapi.BuildConfig{}, queue, c.ResyncPeriod).RunUntil(stopChan)
+	c.WatchUntil(stopChan)
+	c.HandleUntil(stopChan)
+}
+
+// WatchUntil starts watching the BuildConfigs in a goroutine
+// until stopChan is closed. The changes are queued, but not handled
+// until HandleUntil is called - this is used to warm the cache of standby replicas.
+func (c *BuildConfigsController) WatchUntil(stopChan <-chan struct{}) {
+	c.store = newBuildConfigsStore()
+	c.pendingDeletions = newPendingDeletions()
+	c.queue = cache.NewDeltaFIFO(cache.MetaNamespaceKeyFunc, nil, c.store)
+	c.store.queue = c.queue
+	// no resync: the full resync is performed by the reconciler
+	cache.NewReflector(c, &buildapi.BuildConfig{}, c.store, 0).RunUntil(stopChan)
+}
+
+// ListOnce lists all the BuildConfigs once, and stores them in the local cache
+// without watching them - this is used by the one-shot commands, to compute the desired hooks.
+func (c *BuildConfigsController) ListOnce() error {
+	c.store = newBuildConfigsStore()
+	c.queue = cache.NewDeltaFIFO(cache.MetaNamespaceKeyFunc, nil, c.store)
+	c.store.queue = c.queue
+
+	obj, err := c.List(kapi.ListOptions{})
+	if err != nil {
+		return err
+	}
+	list, ok := obj.(*buildapi.BuildConfigList)
+	if !ok {
+		return fmt.Errorf("Unexpected BuildConfigs list type %T", obj)
+	}
+	items := []interface{}{}
+	for i := range list.Items {
+		items = append(items, &list.Items[i])
+	}
+	return c.store.Replace(items, list.ResourceVersion)
+}
+
+// HasSynced returns true once the initial list of BuildConfigs has been retrieved
+func (c *BuildConfigsController) HasSynced() bool {
+	return c.store != nil && c.store.HasSynced()
+}
+
+// HandleUntil starts handling the queued BuildConfigs changes in a goroutine
+// until stopChan is closed. WatchUntil must have been called first.
+func (c *BuildConfigsController) HandleUntil(stopChan <-chan struct{}) {
+	policy := c.RetryPolicy
+	if policy == (RetryPolicy{}) {
+		policy = DefaultRetryPolicy()
+	}
+	c.retryManager = newRetryManager(policy, c.queue)
+	c.retryManager.deadLetterFunc = c.recordDeadLetterEvent
 
 	retryController := &controller.RetryController{
-		Handle: c.handle,
-		Queue:  queue,
-		RetryManager: controller.NewQueueRetryManager(
-			queue,
-			cache.MetaNamespaceKeyFunc,
-			c.retry,
-			kutil.NewTokenBucketRateLimiter(1, 10)),
+		Handle:       c.handle,
+		Queue:        c.queue,
+		RetryManager: c.retryManager,
 	}
 
 	retryController.RunUntil(stopChan)
+
+	if policy.DeadLetterRetryPeriod > 0 {
+		go wait.Until(c.retryManager.retryDeadLetters, policy.DeadLetterRetryPeriod, stopChan)
+	}
+
+	if c.DeletionGracePeriod > 0 {
+		go wait.Until(c.deleteDueHooks, pendingDeletionsCheckPeriod, stopChan)
+	}
 }
 
 // handle handles a BuildConfig change
@@ -72,66 +177,362 @@ func (c *BuildConfigsController) RunUntil(stopChan <-chan struct{}) {
 func (c *BuildConfigsController) handle(obj interface{}) error {
 	deltas := obj.(cache.Deltas)
 	for _, delta := range deltas {
+		changeType := delta.Type
+		bc, ok := delta.Object.(*buildapi.BuildConfig)
+		if !ok {
+			deletedObject, isDeleted := delta.Object.(cache.DeletedFinalStateUnknown)
+			if !isDeleted {
+				glog.Warningf("Un-handled delta type %T (%s)", delta.Object, delta.Type)
+				continue
+			}
+			glog.V(5).Infof("Handling %v DeletedFinalStateUnknown for %s", delta.Type, deletedObject.Key)
+			if bc, ok = deletedObject.Obj.(*buildapi.BuildConfig); !ok {
+				glog.Warningf("Un-handled %v DeletedFinalStateUnknown for %s: %+v", delta.Type, deletedObject.Key, deletedObject.Obj)
+				continue
+			}
+			changeType = cache.Deleted
+		}
 
-		if bc, ok := delta.Object.(*buildapi.BuildConfig); ok {
-			glog.V(5).Infof("Handling %v for BC %s/%s", delta.Type, bc.Namespace, bc.Name)
+		if err := c.handleBuildConfig(bc, changeType); err != nil {
+			return err
+		}
+	}
 
-			if c.acceptBuildConfig(bc) {
-				glog.V(3).Infof("Accepting BC %s/%s", bc.Namespace, bc.Name)
-				hook, err := c.newHook(bc, delta.Type)
-				if err != nil {
-					return err
-				}
+	return nil
+}
 
-				if err = c.HookHandlerFunc(*hook); err != nil {
-					return err
-				}
+// handleBuildConfig handles a single BuildConfig change
+func (c *BuildConfigsController) handleBuildConfig(bc *buildapi.BuildConfig, changeType cache.DeltaType) error {
+	glog.V(5).Infof("Handling %v for BC %s/%s", changeType, bc.Namespace, bc.Name)
+
+	decision := c.hookActionFor(bc)
+	if decision.action != syncHooks {
+		if changeType != cache.Deleted && len(decision.rejectedReason) > 0 && c.Recorder != nil {
+			c.Recorder.Event(bc, kapi.EventTypeWarning, decision.rejectedReason, decision.err.Error())
+		}
+		return nil
+	}
+
+	glog.V(3).Infof("Accepting BC %s/%s", bc.Namespace, bc.Name)
+	hook, err := c.newHook(bc, decision.secret, changeType)
+	if err != nil {
+		return err
+	}
+
+	if changeType == cache.Deleted && c.DeletionGracePeriod > 0 {
+		glog.V(3).Infof("Deleting hook %s for BC %s/%s in %v", hook.TargetURL, bc.Namespace, bc.Name, c.DeletionGracePeriod)
+		c.pendingDeletions.add(buildConfigKey(bc), pendingDeletion{
+			bc:       bc,
+			hook:     *hook,
+			deadline: time.Now().Add(c.DeletionGracePeriod),
+		})
+		return nil
+	}
+
+	previousID := 0
+	if previous := c.lastAppliedHookFor(bc); previous != nil {
+		previousID = previous.ID
+	}
+	if changeType != cache.Deleted {
+		if c.pendingDeletions != nil && c.pendingDeletions.cancel(buildConfigKey(bc)) {
+			glog.V(3).Infof("BC %s/%s has been re-created - cancelled the deletion of its hook", bc.Namespace, bc.Name)
+		}
+		for _, previousHook := range c.previousHooks(bc, hook) {
+			_, changed, err := c.HookHandlerFunc(previousHook)
+			c.recordHookEvent(bc, previousHook, changed, err)
+			if err != nil {
+				return err
 			}
+		}
+	}
 
+	handledHook, changed, err := c.HookHandlerFunc(*hook)
+	c.recordHookEvent(bc, updatedHook(*hook, handledHook, changed, previousID), changed, err)
+	if c.UpdateStatus && changeType != cache.Deleted && (handledHook != nil || err != nil) {
+		c.updateStatus(bc, handledHook, err)
+	}
+	if err != nil {
+		return err
+	}
+	if changeType == cache.Deleted {
+		c.rememberHook(buildConfigKey(bc), nil)
+	} else if handledHook != nil {
+		c.rememberHook(buildConfigKey(bc), handledHook)
+	}
+	return nil
+}
+
+// DeadLetters returns the BuildConfigs changes that failed after all their retries,
+// and that are waiting to be retried later
+func (c *BuildConfigsController) DeadLetters() []DeadLetter {
+	if c.retryManager == nil {
+		return []DeadLetter{}
+	}
+	return c.retryManager.DeadLetters()
+}
+
+// UnprocessedKeys returns the keys ("namespace/name" format) of the BuildConfigs
+// whose changes are queued, waiting to be handled,
+// or have been refused because we are shutting down
+func (c *BuildConfigsController) UnprocessedKeys() []string {
+	unprocessed := map[string]bool{}
+	if c.queue != nil {
+		for _, key := range c.queue.ListKeys() {
+			unprocessed[key] = true
+		}
+	}
+	if c.retryManager != nil {
+		for _, key := range c.retryManager.RefusedKeys() {
+			unprocessed[key] = true
+		}
+	}
+
+	keys := []string{}
+	for key := range unprocessed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PendingDeletions returns the keys ("namespace/name" format) of the deleted BuildConfigs
+// whose hooks are waiting for the end of the deletion grace period
+func (c *BuildConfigsController) PendingDeletions() []string {
+	if c.pendingDeletions == nil {
+		return []string{}
+	}
+	return c.pendingDeletions.keys()
+}
+
+// HandleHook handles a hook change that does not come from a BuildConfig change
+// (for example from the reconciler), and records the result on the targeted BuildConfig
+func (c *BuildConfigsController) HandleHook(hook api.Hook) (*api.Hook, bool, error) {
+	handledHook, changed, err := c.HookHandlerFunc(hook)
+
+	bc := c.buildConfigFor(hook)
+	if bc == nil || !hook.Enabled {
+		c.recordHookEvent(buildConfigReference(hook), hook, changed, err)
+		return handledHook, changed, err
+	}
+
+	c.recordHookEvent(bc, hook, changed, err)
+	if c.UpdateStatus && (handledHook != nil || err != nil) {
+		c.updateStatus(bc, handledHook, err)
+	}
+	if err == nil && handledHook != nil {
+		c.rememberHook(buildConfigKey(bc), handledHook)
+	}
+	return handledHook, changed, err
+}
+
+// DesiredHooks returns the hooks that should exist on GitHub,
+// for all the BuildConfigs in the local cache.
+// It also returns the keys ("namespace/name" format) of the BuildConfigs that exist but are not managed
+// (ignored, or whose hook can't be computed): their existing hooks must be left untouched.
+func (c *BuildConfigsController) DesiredHooks() ([]api.Hook, map[string]bool, error) {
+	hooks := []api.Hook{}
+	unmanaged := map[string]bool{}
+	if c.store == nil {
+		return hooks, unmanaged, nil
+	}
+
+	for _, obj := range c.store.List() {
+		bc, ok := obj.(*buildapi.BuildConfig)
+		if !ok {
+			continue
+		}
+		decision := c.hookActionFor(bc)
+		switch decision.action {
+		case deleteHooks:
+			continue
+		case leaveHooks:
+			unmanaged[buildConfigKey(bc)] = true
+			continue
+		}
+		hook, err := c.newHook(bc, decision.secret, cache.Sync)
+		if err != nil {
+			glog.Warningf("Failed to compute the hook for BC %s/%s - its existing hooks are left untouched: %v", bc.Namespace, bc.Name, err)
+			unmanaged[buildConfigKey(bc)] = true
 			continue
 		}
+		hooks = append(hooks, *hook)
+	}
+
+	// the hooks of the recently deleted BCs should be kept until their deletion is due
+	if c.pendingDeletions != nil {
+		hooks = append(hooks, c.pendingDeletions.hooks()...)
+	}
+	return hooks, unmanaged, nil
+}
 
-		if deletedObject, ok := delta.Object.(cache.DeletedFinalStateUnknown); ok {
-			glog.V(5).Infof("Handling %v DeletedFinalStateUnknown for %s: %+v", delta.Type, deletedObject.Key, deletedObject.Obj)
+// HasBuildConfig returns true if the BC with the given key ("namespace/name" format)
+// exists in the local cache
+func (c *BuildConfigsController) HasBuildConfig(key string) bool {
+	if c.store == nil {
+		return false
+	}
+	_, exists, err := c.store.GetByKey(key)
+	return err == nil && exists
+}
 
-			if hook, ok := deletedObject.Obj.(api.Hook); ok {
-				hook.Enabled = false // make sure the hook is marked has not enabled, so that it will be deleted
-				glog.V(3).Infof("Processing hook %+v for key %s", hook, deletedObject.Key)
-				if err := c.HookHandlerFunc(hook); err != nil {
-					return err
-				}
+// RepositoriesForNamespace returns the GitHub repositories that may have hooks
+// targeting the BuildConfigs of the given namespace: the repositories of its BuildConfigs
+// in the local cache, of their last applied hooks, and of their pending deletions.
+func (c *BuildConfigsController) RepositoriesForNamespace(namespace string) []api.GithubRepository {
+	prefix := namespace + "/"
+	found := map[api.GithubRepository]bool{}
+
+	if c.store != nil {
+		for _, obj := range c.store.List() {
+			bc, ok := obj.(*buildapi.BuildConfig)
+			if !ok || bc.Namespace != namespace {
 				continue
 			}
+			if bc.Spec.Source.Git != nil {
+				if repo, err := api.ParseGithubRepository(bc.Spec.Source.Git.URI); err == nil {
+					found[*repo] = true
+				}
+			}
+			if previous := lastAppliedHookFromAnnotations(bc.Annotations); previous != nil {
+				found[previous.GithubRepository] = true
+			}
+		}
+	}
 
-			glog.Warningf("Un-handled %v DeletedFinalStateUnknown for %s: %+v", delta.Type, deletedObject.Key, deletedObject.Obj)
-			continue
+	c.lastAppliedLock.Lock()
+	for key, previous := range c.lastApplied {
+		if strings.HasPrefix(key, prefix) {
+			found[previous.GithubRepository] = true
 		}
+	}
+	c.lastAppliedLock.Unlock()
 
-		glog.Warningf("Un-handled delta type %T (%s)", delta.Object, delta.Type)
+	if c.pendingDeletions != nil {
+		c.pendingDeletions.lock.Lock()
+		for key, deletion := range c.pendingDeletions.deletions {
+			if strings.HasPrefix(key, prefix) {
+				found[deletion.hook.GithubRepository] = true
+			}
+		}
+		c.pendingDeletions.lock.Unlock()
+	}
 
+	repositories := []api.GithubRepository{}
+	for repo := range found {
+		repositories = append(repositories, repo)
 	}
+	sort.Sort(byRepository(repositories))
+	return repositories
+}
 
-	return nil
+// byRepository sorts the GitHub repositories by owner and name
+type byRepository []api.GithubRepository
+
+func (r byRepository) Len() int      { return len(r) }
+func (r byRepository) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byRepository) Less(i, j int) bool {
+	if r[i].Owner != r[j].Owner {
+		return r[i].Owner < r[j].Owner
+	}
+	return r[i].Name < r[j].Name
+}
+
+// RequeueBuildConfigs queues the BuildConfigs with the given keys ("namespace/name" format) again,
+// from the local cache - for example when the Secret referenced by their github trigger changed,
+// so that their hooks are updated
+func (c *BuildConfigsController) RequeueBuildConfigs(keys []string) {
+	if c.store == nil || c.queue == nil {
+		return
+	}
+	for _, key := range keys {
+		obj, exists, err := c.store.GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		glog.V(3).Infof("Re-queueing BC %s", key)
+		if err := c.queue.Update(obj); err != nil {
+			glog.Errorf("Failed to re-queue BC %s: %v", key, err)
+		}
+	}
+}
+
+// buildConfigFor returns the BC targeted by the given hook, from the local cache
+// or nil if it does not exist
+func (c *BuildConfigsController) buildConfigFor(hook api.Hook) *buildapi.BuildConfig {
+	if c.store == nil {
+		return nil
+	}
+	namespace, name, _ := ExplodeOpenshiftWebhookURL(hook.TargetURL)
+	if len(namespace) == 0 || len(name) == 0 {
+		return nil
+	}
+	obj, exists, err := c.store.GetByKey(fmt.Sprintf("%s/%s", namespace, name))
+	if err != nil || !exists {
+		return nil
+	}
+	bc, _ := obj.(*buildapi.BuildConfig)
+	return bc
+}
+
+// buildConfigKey returns the key ("namespace/name" format) of the given BC
+func buildConfigKey(bc *buildapi.BuildConfig) string {
+	return fmt.Sprintf("%s/%s", bc.Namespace, bc.Name)
+}
+
+// hookAction is what should be done with the hooks of a BC
+type hookAction int
+
+const (
+	// deleteHooks means that the BC should not have any hook: its existing hooks are deleted
+	deleteHooks hookAction = iota
+
+	// syncHooks means that the hook of the BC is created or updated
+	syncHooks
+
+	// leaveHooks means that the BC is not managed: its existing hooks are left untouched
+	leaveHooks
+)
+
+// hookDecision is what should be done with the hooks of a BC, and why
+type hookDecision struct {
+	action hookAction
+
+	// secret is the secret of the github trigger of the BC (only for syncHooks)
+	secret string
+
+	// rejectedReason is the reason of the event explaining to the users why the BC is rejected,
+	// or empty if there is nothing to explain (for example for a BC without github trigger)
+	rejectedReason string
+
+	// err explains why the BC is rejected (only with a rejectedReason)
+	err error
 }
 
 // acceptBuildConfig checks if the given BC is acceptable or not
 // an acceptable BC is one that has a valid github trigger
 func (c *BuildConfigsController) acceptBuildConfig(bc *buildapi.BuildConfig) bool {
+	return c.hookActionFor(bc).action == syncHooks
+}
+
+// hookActionFor returns what should be done with the hooks of the given BC, and why.
+// The secret of the github trigger is only resolved (and the repository only checked)
+// if the BC has a github trigger and is not ignored.
+func (c *BuildConfigsController) hookActionFor(bc *buildapi.BuildConfig) hookDecision {
 	// filter out invalid BC
 	if bc == nil {
 		glog.V(4).Infof("Ignoring empty BC")
-		return false
+		return hookDecision{action: deleteHooks}
 	}
 
 	// filter out non-git sources
 	if bc.Spec.Source.Git == nil {
 		glog.V(4).Infof("Ignoring BC %s/%s with non-git sources", bc.Namespace, bc.Name)
-		return false
+		return hookDecision{action: deleteHooks}
 	}
 	// filter out non-github sources
 	if !strings.Contains(bc.Spec.Source.Git.URI, "github") {
 		glog.V(4).Infof("Ignoring BC %s/%s with non-github sources", bc.Namespace, bc.Name)
-		return false
+		return hookDecision{action: deleteHooks}
 	}
 
 	// filter out BC without github trigger
@@ -144,26 +545,160 @@ func (c *BuildConfigsController) acceptBuildConfig(bc *buildapi.BuildConfig) boo
 	}
 	if !githubTriggerFound {
 		glog.V(4).Infof("Ignoring BC %s/%s with no github trigger", bc.Namespace, bc.Name)
+		return hookDecision{action: deleteHooks}
+	}
+
+	// filter out BC because of "ignore" annotation (its hooks are managed by hand)
+	if isIgnored(bc) {
+		glog.V(4).Infof("Ignoring BC %s/%s because of annotation %s", bc.Namespace, bc.Name, api.IgnoreAnnotation)
+		return hookDecision{action: leaveHooks}
+	}
+
+	// filter out BC whose github triggers have no secret
+	// (or whose secret is referenced from a Secret object that can't be read)
+	secret, err := c.triggerSecret(bc)
+	if err != nil {
+		glog.Warningf("Ignoring BC %s/%s whose github trigger secret can't be read: %v", bc.Namespace, bc.Name, err)
+		return hookDecision{
+			action:         leaveHooks,
+			rejectedReason: SecretReferenceFailedReason,
+			err:            fmt.Errorf("Failed to get the secret of the GitHub trigger, its hook is left untouched: %v", err),
+		}
+	}
+	if len(secret) == 0 {
+		glog.Warningf("Ignoring BC %s/%s whose github trigger has no secret", bc.Namespace, bc.Name)
+		if c.SecretReferenceFunc == nil && hasGithubTriggerWithoutSecret(bc) {
+			return hookDecision{
+				action:         leaveHooks,
+				rejectedReason: SecretNotSupportedReason,
+				err:            fmt.Errorf("The GitHub trigger has no inline secret: secrets referenced from Secret objects are not enabled, no hook will be created"),
+			}
+		}
+		return hookDecision{action: leaveHooks}
+	}
+
+	// filter out BC whose namespace is not allowed to hook its repository
+	// (or for which it can't be checked right now: its existing hooks are kept)
+	switch err := c.checkRepository(bc); {
+	case IsRepositoryCheckUnknown(err):
+		glog.Warningf("Ignoring BC %s/%s for now: %v", bc.Namespace, bc.Name, err)
+		return hookDecision{
+			action:         leaveHooks,
+			rejectedReason: RepositoryCheckFailedReason,
+			err:            fmt.Errorf("Failed to check if the namespace may hook the repository, its hook is left untouched: %v", err),
+		}
+	case err != nil:
+		glog.Warningf("Ignoring BC %s/%s: %v", bc.Namespace, bc.Name, err)
+		return hookDecision{
+			action:         deleteHooks,
+			rejectedReason: PolicyDeniedReason,
+			err:            fmt.Errorf("No hook will be created: %v", err),
+		}
+	}
+
+	return hookDecision{action: syncHooks, secret: secret}
+}
+
+// isIgnored checks if the given BC has the "ignore" annotation set to true
+func isIgnored(bc *buildapi.BuildConfig) bool {
+	ignoreStr, found := bc.Annotations[api.IgnoreAnnotation]
+	if !found {
 		return false
 	}
+	ignore, err := strconv.ParseBool(ignoreStr)
+	if err != nil {
+		glog.Errorf("Failed to parse annotation value '%v' for %s on BC %s/%s: %v", ignoreStr, api.IgnoreAnnotation, bc.Namespace, bc.Name, err)
+	}
+	return ignore
+}
 
-	// filter out BC because of "ignore" annotation
-	if ignoreStr, found := bc.Annotations[api.IgnoreAnnotation]; found {
-		ignore, err := strconv.ParseBool(ignoreStr)
-		if err != nil {
-			glog.Errorf("Failed to parse annotation value '%v' for %s on BC %s/%s: %v", ignoreStr, api.IgnoreAnnotation, bc.Namespace, bc.Name, err)
+// checkRepository returns an error if the namespace of the given BC may not hook its repository
+func (c *BuildConfigsController) checkRepository(bc *buildapi.BuildConfig) error {
+	if c.CheckRepositoryFunc == nil || bc == nil || bc.Spec.Source.Git == nil {
+		return nil
+	}
+	repo, err := api.ParseGithubRepository(bc.Spec.Source.Git.URI)
+	if err != nil {
+		return nil
+	}
+	return c.CheckRepositoryFunc(bc.Namespace, *repo)
+}
+
+// RepositoryCheckUnknownError is returned by a CheckRepositoryFunc when it can't tell
+// if a namespace may hook a repository (for example because of an API error)
+type RepositoryCheckUnknownError struct {
+	Err error
+}
+
+// Error is for the error implementation
+func (e *RepositoryCheckUnknownError) Error() string {
+	return e.Err.Error()
+}
+
+// IsRepositoryCheckUnknown returns true if the given error is a RepositoryCheckUnknownError
+func IsRepositoryCheckUnknown(err error) bool {
+	_, ok := err.(*RepositoryCheckUnknownError)
+	return ok
+}
+
+// githubTriggerSecret returns the inline secret of the first github trigger of the given BC
+// that has one, or an empty string
+func githubTriggerSecret(bc *buildapi.BuildConfig) string {
+	for _, trigger := range bc.Spec.Triggers {
+		if trigger.Type == buildapi.GitHubWebHookBuildTriggerType && trigger.GitHubWebHook != nil && len(trigger.GitHubWebHook.Secret) > 0 {
+			return trigger.GitHubWebHook.Secret
 		}
-		if ignore {
-			glog.V(4).Infof("Ignoring BC %s/%s because of annotation %s (%v)", bc.Namespace, bc.Name, api.IgnoreAnnotation, ignoreStr)
-			return false
+	}
+	return ""
+}
+
+// triggerSecret returns the secret of the github trigger of the given BC:
+// its inline secret, or the secret referenced from a Secret object (if supported)
+func (c *BuildConfigsController) triggerSecret(bc *buildapi.BuildConfig) (string, error) {
+	if secret := githubTriggerSecret(bc); len(secret) > 0 {
+		return secret, nil
+	}
+	if c.SecretReferenceFunc == nil || !hasGithubTriggerWithoutSecret(bc) {
+		return "", nil
+	}
+	return c.SecretReferenceFunc(bc)
+}
+
+// hasGithubTriggerWithoutSecret checks if the given BC has a github trigger, but no inline secret
+func hasGithubTriggerWithoutSecret(bc *buildapi.BuildConfig) bool {
+	if bc == nil {
+		return false
+	}
+	for _, trigger := range bc.Spec.Triggers {
+		if trigger.Type == buildapi.GitHubWebHookBuildTriggerType {
+			return len(githubTriggerSecret(bc)) == 0
 		}
 	}
+	return false
+}
 
-	return true
+// HookFor returns the hook that should exist on GitHub for the given BC,
+// without checking if the BC is synced (ignore annotation, policy, ...) - this is used to manage a single hook manually.
+// It returns an error if the BC has no github source, or no github trigger with a secret.
+func (c *BuildConfigsController) HookFor(bc *buildapi.BuildConfig) (*api.Hook, error) {
+	if bc.Spec.Source.Git == nil {
+		return nil, fmt.Errorf("The BuildConfig %s/%s has no git source", bc.Namespace, bc.Name)
+	}
+	if _, err := api.ParseGithubRepository(bc.Spec.Source.Git.URI); err != nil {
+		return nil, fmt.Errorf("The BuildConfig %s/%s has no github source: %v", bc.Namespace, bc.Name, err)
+	}
+	secret, err := c.triggerSecret(bc)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the github trigger secret of the BuildConfig %s/%s: %v", bc.Namespace, bc.Name, err)
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("The BuildConfig %s/%s has no github trigger with a secret", bc.Namespace, bc.Name)
+	}
+	return c.newHook(bc, secret, cache.Sync)
 }
 
 // newHook instantiates a new Hook object for the given BC
-func (c *BuildConfigsController) newHook(bc *buildapi.BuildConfig, changeType cache.DeltaType) (*api.Hook, error) {
+func (c *BuildConfigsController) newHook(bc *buildapi.BuildConfig, secret string, changeType cache.DeltaType) (*api.Hook, error) {
 	hook := &api.Hook{}
 
 	switch changeType {
@@ -176,11 +711,20 @@ func (c *BuildConfigsController) newHook(bc *buildapi.BuildConfig, changeType ca
 	for _, trigger := range bc.Spec.Triggers {
 		switch trigger.Type {
 		case buildapi.GitHubWebHookBuildTriggerType:
+			if len(secret) == 0 {
+				continue
+			}
+			// the secret may be referenced from a Secret object, unknown to the OpenShift client
+			trigger.GitHubWebHook = &buildapi.WebHookTrigger{Secret: secret}
 			hookURL, err := c.BuildConfigsNamespacer.BuildConfigs(bc.Namespace).WebHookURL(bc.Name, &trigger)
 			if err != nil {
 				return nil, err
 			}
-			hook.TargetURL = fixOpenshiftHookURL(hookURL, c.OpenshiftPublicURL)
+			hook.TargetURL = setOpenshiftHookAPIPath(fixOpenshiftHookURL(hookURL, c.OpenshiftPublicURL), c.WebhookAPIPath)
+			if len(c.RelayURL) > 0 {
+				hook.Secret = RelayHookSecret(c.RelayKey, bc.Namespace, bc.Name)
+				hook.TargetURL = RelayWebhookURL(c.RelayURL, bc.Namespace, bc.Name, hook.Secret)
+			}
 			break
 		}
 	}
@@ -196,13 +740,6 @@ func (c *BuildConfigsController) newHook(bc *buildapi.BuildConfig, changeType ca
 	return hook, nil
 }
 
-// retry is a controller.RetryFunc that should return true if the given object and error
-// should be retried after the provided number of times.
-func (c *BuildConfigsController) retry(obj interface{}, err error, retries controller.Retry) bool {
-	// let's retry a few times...
-	return retries.Count < 5
-}
-
 // List is for the cache.ListerWatcher implementation
 // List should return a list type object; the Items field will be extracted, and the
 // ResourceVersion field will be used to start the watch in the right place.
@@ -217,17 +754,3 @@ func (c *BuildConfigsController) Watch(options kapi.ListOptions) (watch.Interfac
 	glog.V(3).Infof("Watching BuildConfigs with options %+v", options)
 	return c.BuildConfigsNamespacer.BuildConfigs(kapi.NamespaceAll).Watch(options)
 }
-
-// ListKeys implements the cache.KeyLister interface
-// It is a function that returns the list of keys ("namespace/name" format)
-// that we "know about" (to get a 2-way sync)
-func (c *BuildConfigsController) ListKeys() []string {
-	return c.KeyListFunc()
-}
-
-// GetByKey implements the cache.KeyGetter interface
-// It is a function that returns the object that we "know about"
-// for the given key ("namespace/name" format) - and a boolean if it exists
-func (c *BuildConfigsController) GetByKey(key string) (interface{}, bool, error) {
-	return c.KeyGetFunc(key)
-}
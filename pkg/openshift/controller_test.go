@@ -1,6 +1,9 @@
 package openshift
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/vbehar/openshift-github-hooks/pkg/api"
@@ -8,6 +11,8 @@ import (
 	buildapi "github.com/openshift/origin/pkg/build/api"
 
 	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/cache"
+	"k8s.io/kubernetes/pkg/client/record"
 )
 
 func TestBuildConfigsControllerAcceptBuildConfig(t *testing.T) {
@@ -112,6 +117,27 @@ func TestBuildConfigsControllerAcceptBuildConfig(t *testing.T) {
 			},
 			expectedResult: true,
 		},
+		// should ignore bc whose github trigger has no inline secret
+		{
+			bc: &buildapi.BuildConfig{
+				Spec: buildapi.BuildConfigSpec{
+					BuildSpec: buildapi.BuildSpec{
+						Source: buildapi.BuildSource{
+							Git: &buildapi.GitBuildSource{
+								URI: "git@github.com:owner/name.git",
+							},
+						},
+					},
+					Triggers: []buildapi.BuildTriggerPolicy{
+						{
+							Type:          buildapi.GitHubWebHookBuildTriggerType,
+							GitHubWebHook: &buildapi.WebHookTrigger{},
+						},
+					},
+				},
+			},
+			expectedResult: false,
+		},
 		// should accept a BC with a github source and a valid github trigger
 		{
 			bc: &buildapi.BuildConfig{
@@ -145,3 +171,401 @@ func TestBuildConfigsControllerAcceptBuildConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildConfigsControllerAcceptBuildConfigWithPolicy(t *testing.T) {
+	tests := []struct {
+		namespace      string
+		uri            string
+		expectedResult bool
+	}{
+		{
+			namespace:      "team-a",
+			uri:            "git@github.com:my-org/team-a-app.git",
+			expectedResult: true,
+		},
+		{
+			namespace:      "team-b",
+			uri:            "git@github.com:my-org/team-a-app.git",
+			expectedResult: false,
+		},
+	}
+
+	controller := &BuildConfigsController{
+		CheckRepositoryFunc: func(namespace string, repository api.GithubRepository) error {
+			if !strings.HasPrefix(repository.Name, namespace) {
+				return fmt.Errorf("namespace %s may not hook %s", namespace, repository)
+			}
+			return nil
+		},
+	}
+	for count, test := range tests {
+		bc := &buildapi.BuildConfig{
+			ObjectMeta: kapi.ObjectMeta{Namespace: test.namespace, Name: "bc"},
+			Spec: buildapi.BuildConfigSpec{
+				BuildSpec: buildapi.BuildSpec{
+					Source: buildapi.BuildSource{
+						Git: &buildapi.GitBuildSource{
+							URI: test.uri,
+						},
+					},
+				},
+				Triggers: []buildapi.BuildTriggerPolicy{
+					{
+						Type: buildapi.GitHubWebHookBuildTriggerType,
+						GitHubWebHook: &buildapi.WebHookTrigger{
+							Secret: "secret",
+						},
+					},
+				},
+			},
+		}
+		result := controller.acceptBuildConfig(bc)
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%v' but got '%v'", count, test.expectedResult, result)
+		}
+	}
+}
+
+func TestBuildConfigsControllerHookForInvalidBuildConfig(t *testing.T) {
+	tests := []*buildapi.BuildConfig{
+		// no git source
+		{},
+		// no github source
+		{
+			Spec: buildapi.BuildConfigSpec{
+				BuildSpec: buildapi.BuildSpec{
+					Source: buildapi.BuildSource{
+						Git: &buildapi.GitBuildSource{
+							URI: "git@bitbucket.org:owner/name.git",
+						},
+					},
+				},
+			},
+		},
+		// no github trigger secret
+		{
+			Spec: buildapi.BuildConfigSpec{
+				BuildSpec: buildapi.BuildSpec{
+					Source: buildapi.BuildSource{
+						Git: &buildapi.GitBuildSource{
+							URI: "git@github.com:owner/name.git",
+						},
+					},
+				},
+				Triggers: []buildapi.BuildTriggerPolicy{
+					{
+						Type:          buildapi.GitHubWebHookBuildTriggerType,
+						GitHubWebHook: &buildapi.WebHookTrigger{},
+					},
+				},
+			},
+		},
+	}
+
+	controller := &BuildConfigsController{}
+	for count, bc := range tests {
+		if hook, err := controller.HookFor(bc); err == nil {
+			t.Errorf("Test[%d] Failed: Expected an error but got hook %+v", count, hook)
+		}
+	}
+}
+
+func TestBuildConfigsControllerDesiredHooksUnmanaged(t *testing.T) {
+	newBuildConfig := func(name string, annotations map[string]string, secret string) *buildapi.BuildConfig {
+		return &buildapi.BuildConfig{
+			ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: name, Annotations: annotations},
+			Spec: buildapi.BuildConfigSpec{
+				BuildSpec: buildapi.BuildSpec{
+					Source: buildapi.BuildSource{
+						Git: &buildapi.GitBuildSource{
+							URI: "git@github.com:owner/" + name + ".git",
+						},
+					},
+				},
+				Triggers: []buildapi.BuildTriggerPolicy{
+					{
+						Type: buildapi.GitHubWebHookBuildTriggerType,
+						GitHubWebHook: &buildapi.WebHookTrigger{
+							Secret: secret,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	controller := &BuildConfigsController{
+		CheckRepositoryFunc: func(namespace string, repository api.GithubRepository) error {
+			if repository.Name == "unknown" {
+				return &RepositoryCheckUnknownError{Err: fmt.Errorf("github is down")}
+			}
+			return fmt.Errorf("namespace %s may not hook %s", namespace, repository)
+		},
+	}
+	controller.store = newBuildConfigsStore()
+	for _, bc := range []*buildapi.BuildConfig{
+		newBuildConfig("ignored", map[string]string{api.IgnoreAnnotation: "true"}, "secret"),
+		newBuildConfig("no-secret", nil, ""),
+		newBuildConfig("denied", nil, "secret"),
+		newBuildConfig("unknown", nil, "secret"),
+		{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "no-source"}},
+	} {
+		if err := controller.store.Store.Add(bc); err != nil {
+			t.Fatalf("Failed to add BC %s: %v", bc.Name, err)
+		}
+	}
+
+	hooks, unmanaged, err := controller.DesiredHooks()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(hooks) != 0 {
+		t.Errorf("Expected no desired hooks, but got %+v", hooks)
+	}
+
+	expectedUnmanaged := map[string]bool{
+		"ns/ignored":   true,
+		"ns/no-secret": true,
+		"ns/denied":    false,
+		"ns/unknown":   true,
+		"ns/no-source": false,
+	}
+	for key, expected := range expectedUnmanaged {
+		if unmanaged[key] != expected {
+			t.Errorf("Expected %s to be unmanaged: %v, but got %v", key, expected, unmanaged[key])
+		}
+	}
+}
+
+func TestBuildConfigsControllerRepositoriesForNamespace(t *testing.T) {
+	newBuildConfig := func(namespace, name, uri string, annotations map[string]string) *buildapi.BuildConfig {
+		return &buildapi.BuildConfig{
+			ObjectMeta: kapi.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+			Spec: buildapi.BuildConfigSpec{
+				BuildSpec: buildapi.BuildSpec{
+					Source: buildapi.BuildSource{
+						Git: &buildapi.GitBuildSource{URI: uri},
+					},
+				},
+			},
+		}
+	}
+
+	controller := &BuildConfigsController{}
+	controller.store = newBuildConfigsStore()
+	controller.pendingDeletions = newPendingDeletions()
+	for _, bc := range []*buildapi.BuildConfig{
+		newBuildConfig("ns", "current", "https://github.com/owner/current.git", nil),
+		newBuildConfig("ns", "moved", "https://github.com/owner/new.git", map[string]string{
+			api.HookIDsAnnotation:    "1",
+			api.RepositoryAnnotation: "owner/old",
+		}),
+		newBuildConfig("ns", "not-github", "https://gitlab.com/owner/repo.git", nil),
+		newBuildConfig("other", "other", "https://github.com/owner/other.git", nil),
+	} {
+		if err := controller.store.Store.Add(bc); err != nil {
+			t.Fatalf("Failed to add BC %s: %v", bc.Name, err)
+		}
+	}
+	controller.rememberHook("ns/remembered", &api.Hook{ID: 2, GithubRepository: api.GithubRepository{Owner: "owner", Name: "remembered"}})
+	controller.rememberHook("other/remembered", &api.Hook{ID: 3, GithubRepository: api.GithubRepository{Owner: "owner", Name: "other-remembered"}})
+	controller.pendingDeletions.add("ns/deleted", pendingDeletion{
+		hook: api.Hook{GithubRepository: api.GithubRepository{Owner: "owner", Name: "deleted"}},
+	})
+	controller.pendingDeletions.add("ns-other/deleted", pendingDeletion{
+		hook: api.Hook{GithubRepository: api.GithubRepository{Owner: "owner", Name: "other-deleted"}},
+	})
+
+	tests := []struct {
+		namespace            string
+		expectedRepositories []string
+	}{
+		{
+			namespace:            "ns",
+			expectedRepositories: []string{"owner/current", "owner/deleted", "owner/new", "owner/old", "owner/remembered"},
+		},
+		{
+			namespace:            "other",
+			expectedRepositories: []string{"owner/other", "owner/other-remembered"},
+		},
+		{
+			namespace:            "unknown",
+			expectedRepositories: []string{},
+		},
+	}
+
+	for count, test := range tests {
+		repositories := []string{}
+		for _, repo := range controller.RepositoriesForNamespace(test.namespace) {
+			repositories = append(repositories, repo.String())
+		}
+		if strings.Join(repositories, ",") != strings.Join(test.expectedRepositories, ",") {
+			t.Errorf("Test[%d] Failed: Expected repositories %v but got %v", count, test.expectedRepositories, repositories)
+		}
+	}
+}
+
+func TestBuildConfigsControllerHookActionWithSecretReference(t *testing.T) {
+	bc := &buildapi.BuildConfig{
+		ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "bc"},
+		Spec: buildapi.BuildConfigSpec{
+			BuildSpec: buildapi.BuildSpec{
+				Source: buildapi.BuildSource{
+					Git: &buildapi.GitBuildSource{
+						URI: "git@github.com:owner/name.git",
+					},
+				},
+			},
+			Triggers: []buildapi.BuildTriggerPolicy{
+				{
+					Type:          buildapi.GitHubWebHookBuildTriggerType,
+					GitHubWebHook: &buildapi.WebHookTrigger{},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		secretReferenceFunc func(bc *buildapi.BuildConfig) (string, error)
+		expectedAction      hookAction
+	}{
+		// secret references not enabled
+		{
+			secretReferenceFunc: nil,
+			expectedAction:      leaveHooks,
+		},
+		// referenced secret
+		{
+			secretReferenceFunc: func(bc *buildapi.BuildConfig) (string, error) {
+				return "referenced-secret", nil
+			},
+			expectedAction: syncHooks,
+		},
+		// no secret reference
+		{
+			secretReferenceFunc: func(bc *buildapi.BuildConfig) (string, error) {
+				return "", nil
+			},
+			expectedAction: leaveHooks,
+		},
+		// referenced secret that can't be read
+		{
+			secretReferenceFunc: func(bc *buildapi.BuildConfig) (string, error) {
+				return "", fmt.Errorf("secret not found")
+			},
+			expectedAction: leaveHooks,
+		},
+	}
+
+	for count, test := range tests {
+		controller := &BuildConfigsController{
+			SecretReferenceFunc: test.secretReferenceFunc,
+		}
+		if action := controller.hookActionFor(bc).action; action != test.expectedAction {
+			t.Errorf("Test[%d] Failed: Expected action %v but got %v", count, test.expectedAction, action)
+		}
+	}
+}
+
+func TestBuildConfigsControllerHandleRejectedBuildConfig(t *testing.T) {
+	newBuildConfig := func(trigger *buildapi.WebHookTrigger) *buildapi.BuildConfig {
+		bc := &buildapi.BuildConfig{
+			ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "bc"},
+			Spec: buildapi.BuildConfigSpec{
+				BuildSpec: buildapi.BuildSpec{
+					Source: buildapi.BuildSource{
+						Git: &buildapi.GitBuildSource{
+							URI: "git@github.com:owner/name.git",
+						},
+					},
+				},
+			},
+		}
+		if trigger != nil {
+			bc.Spec.Triggers = []buildapi.BuildTriggerPolicy{
+				{
+					Type:          buildapi.GitHubWebHookBuildTriggerType,
+					GitHubWebHook: trigger,
+				},
+			}
+		}
+		return bc
+	}
+
+	tests := []struct {
+		bc                  *buildapi.BuildConfig
+		checkErr            error
+		secretReferenceFunc func(bc *buildapi.BuildConfig) (string, error)
+		expectedChecks      int
+		expectedEvents      []string
+	}{
+		// no github trigger: nothing to check, nothing to explain
+		{
+			bc:             newBuildConfig(nil),
+			checkErr:       fmt.Errorf("denied"),
+			expectedChecks: 0,
+			expectedEvents: []string{},
+		},
+		// no secret: the repository is not checked
+		{
+			bc:             newBuildConfig(&buildapi.WebHookTrigger{}),
+			checkErr:       fmt.Errorf("denied"),
+			expectedChecks: 0,
+			expectedEvents: []string{
+				"Warning SecretNotSupported The GitHub trigger has no inline secret: secrets referenced from Secret objects are not enabled, no hook will be created",
+			},
+		},
+		// referenced secret that can't be read: the repository is not checked
+		{
+			bc:       newBuildConfig(&buildapi.WebHookTrigger{}),
+			checkErr: fmt.Errorf("denied"),
+			secretReferenceFunc: func(bc *buildapi.BuildConfig) (string, error) {
+				return "", fmt.Errorf("secret not found")
+			},
+			expectedChecks: 0,
+			expectedEvents: []string{
+				"Warning SecretReferenceFailed Failed to get the secret of the GitHub trigger, its hook is left untouched: secret not found",
+			},
+		},
+		// denied by the policy
+		{
+			bc:             newBuildConfig(&buildapi.WebHookTrigger{Secret: "secret"}),
+			checkErr:       fmt.Errorf("denied"),
+			expectedChecks: 1,
+			expectedEvents: []string{
+				"Warning PolicyDenied No hook will be created: denied",
+			},
+		},
+		// the repository can't be checked
+		{
+			bc:             newBuildConfig(&buildapi.WebHookTrigger{Secret: "secret"}),
+			checkErr:       &RepositoryCheckUnknownError{Err: fmt.Errorf("the API is down")},
+			expectedChecks: 1,
+			expectedEvents: []string{
+				"Warning RepositoryCheckFailed Failed to check if the namespace may hook the repository, its hook is left untouched: the API is down",
+			},
+		},
+	}
+
+	for count, test := range tests {
+		checks := 0
+		recorder := &record.FakeRecorder{Events: []string{}}
+		controller := &BuildConfigsController{
+			Recorder:            recorder,
+			SecretReferenceFunc: test.secretReferenceFunc,
+			CheckRepositoryFunc: func(namespace string, repository api.GithubRepository) error {
+				checks++
+				return test.checkErr
+			},
+		}
+		if err := controller.handleBuildConfig(test.bc, cache.Updated); err != nil {
+			t.Errorf("Test[%d] Failed: Unexpected error: %v", count, err)
+		}
+		if checks != test.expectedChecks {
+			t.Errorf("Test[%d] Failed: Expected %d repository checks but got %d", count, test.expectedChecks, checks)
+		}
+		if !reflect.DeepEqual(recorder.Events, test.expectedEvents) {
+			t.Errorf("Test[%d] Failed: Expected events %v but got %v", count, test.expectedEvents, recorder.Events)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package openshift
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/golang/glog"
+	buildapi "github.com/openshift/origin/pkg/build/api"
+)
+
+// pendingDeletion is the deletion of the hook of a deleted BC,
+// waiting for the end of the deletion grace period
+type pendingDeletion struct {
+	bc       *buildapi.BuildConfig
+	hook     api.Hook
+	deadline time.Time
+}
+
+// pendingDeletions stores the pending deletions, by BC key ("namespace/name" format)
+type pendingDeletions struct {
+	deletions map[string]pendingDeletion
+	lock      sync.Mutex
+}
+
+// newPendingDeletions instantiates a new (empty) pendingDeletions
+func newPendingDeletions() *pendingDeletions {
+	return &pendingDeletions{
+		deletions: map[string]pendingDeletion{},
+	}
+}
+
+// add queues the given deletion, replacing any existing deletion for the same key
+func (p *pendingDeletions) add(key string, deletion pendingDeletion) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.deletions[key] = deletion
+}
+
+// cancel removes the pending deletion for the given key,
+// and returns true if there was one
+func (p *pendingDeletions) cancel(key string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	_, found := p.deletions[key]
+	delete(p.deletions, key)
+	return found
+}
+
+// due removes and returns the deletions whose deadline is before the given time,
+// sorted by key
+func (p *pendingDeletions) due(now time.Time) []pendingDeletion {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	keys := []string{}
+	for key, deletion := range p.deletions {
+		if !deletion.deadline.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	deletions := []pendingDeletion{}
+	for _, key := range keys {
+		deletions = append(deletions, p.deletions[key])
+		delete(p.deletions, key)
+	}
+	return deletions
+}
+
+// keys returns the keys of the pending deletions, sorted
+func (p *pendingDeletions) keys() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	keys := []string{}
+	for key := range p.deletions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hooks returns the hooks that are waiting to be deleted,
+// enabled - because they should still exist until their deletion is due
+func (p *pendingDeletions) hooks() []api.Hook {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	hooks := []api.Hook{}
+	for _, deletion := range p.deletions {
+		hook := deletion.hook
+		hook.Enabled = true
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// deleteDueHooks deletes the hooks whose deletion grace period has expired.
+// If a deletion fails, it is not retried here: the orphan hook will be deleted
+// by the next full reconciliation.
+func (c *BuildConfigsController) deleteDueHooks() {
+	for _, deletion := range c.pendingDeletions.due(time.Now()) {
+		bc, hook := deletion.bc, deletion.hook
+		glog.V(3).Infof("Deletion grace period expired for BC %s/%s - deleting hook %s", bc.Namespace, bc.Name, hook.TargetURL)
+
+		_, changed, err := c.HookHandlerFunc(hook)
+		c.recordHookEvent(bc, hook, changed, err)
+		if err != nil {
+			glog.Errorf("Failed to delete hook %s on repository %s for BC %s/%s: %v", hook.TargetURL, hook.GithubRepository, bc.Namespace, bc.Name, err)
+			continue
+		}
+		c.rememberHook(buildConfigKey(bc), nil)
+	}
+}
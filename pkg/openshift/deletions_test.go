@@ -0,0 +1,46 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+)
+
+func TestPendingDeletions(t *testing.T) {
+	now := time.Now()
+	deletions := newPendingDeletions()
+	deletions.add("ns/a", pendingDeletion{hook: api.Hook{TargetURL: "https://openshift/a"}, deadline: now.Add(-1 * time.Second)})
+	deletions.add("ns/b", pendingDeletion{hook: api.Hook{TargetURL: "https://openshift/b"}, deadline: now.Add(1 * time.Minute)})
+	deletions.add("ns/c", pendingDeletion{hook: api.Hook{TargetURL: "https://openshift/c"}, deadline: now})
+
+	if hooks := deletions.hooks(); len(hooks) != 3 {
+		t.Errorf("Expected 3 pending hooks, but got %+v", hooks)
+	} else {
+		for _, hook := range hooks {
+			if !hook.Enabled {
+				t.Errorf("Expected pending hook %s to be enabled", hook.TargetURL)
+			}
+		}
+	}
+
+	if !deletions.cancel("ns/c") {
+		t.Errorf("Expected the deletion of ns/c to be cancelled")
+	}
+	if deletions.cancel("ns/c") {
+		t.Errorf("Expected no deletion to cancel for ns/c")
+	}
+
+	due := deletions.due(now)
+	if len(due) != 1 || due[0].hook.TargetURL != "https://openshift/a" {
+		t.Errorf("Expected only the deletion of ns/a to be due, but got %+v", due)
+	}
+	if due := deletions.due(now); len(due) != 0 {
+		t.Errorf("Expected no more due deletions, but got %+v", due)
+	}
+
+	due = deletions.due(now.Add(1 * time.Minute))
+	if len(due) != 1 || due[0].hook.TargetURL != "https://openshift/b" {
+		t.Errorf("Expected the deletion of ns/b to be due, but got %+v", due)
+	}
+}
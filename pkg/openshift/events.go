@@ -0,0 +1,123 @@
+package openshift
+
+import (
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/github"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// Reasons of the events recorded against the BuildConfigs
+const (
+	// HookCreatedReason is used when a hook has been created on GitHub
+	HookCreatedReason = "HookCreated"
+
+	// HookUpdatedReason is used when a previous hook has been updated on GitHub
+	// (because the BuildConfig's secret changed)
+	HookUpdatedReason = "HookUpdated"
+
+	// HookDeletedReason is used when a hook has been deleted from GitHub
+	HookDeletedReason = "HookDeleted"
+
+	// HookCreateFailedReason is used when a hook could not be created on GitHub
+	HookCreateFailedReason = "HookCreateFailed"
+
+	// HookDeleteFailedReason is used when a hook could not be deleted from GitHub
+	HookDeleteFailedReason = "HookDeleteFailed"
+
+	// RepositoryNotFoundReason is used when the GitHub repository does not exist
+	// (or the token can't access it)
+	RepositoryNotFoundReason = "RepositoryNotFound"
+
+	// PermissionDeniedReason is used when the GitHub token does not have the required permissions
+	PermissionDeniedReason = "PermissionDenied"
+
+	// HookRejectedReason is used when GitHub refused the hook
+	// (for example because the hook limit has been reached for the repository)
+	HookRejectedReason = "HookRejected"
+
+	// SecretNotSupportedReason is used when the GitHub trigger has no inline secret
+	// (its secret is referenced from a Secret object, and the secret references are not enabled)
+	SecretNotSupportedReason = "SecretNotSupported"
+
+	// SecretReferenceFailedReason is used when the Secret referenced by the GitHub trigger
+	// can't be read (or has no webhook secret)
+	SecretReferenceFailedReason = "SecretReferenceFailed"
+
+	// PolicyDeniedReason is used when the policy does not allow the BC's namespace to hook its repository
+	PolicyDeniedReason = "PolicyDenied"
+
+	// RepositoryCheckFailedReason is used when it can't be checked if the BC's namespace may hook its repository
+	// (for example because the GitHub team permission can't be retrieved)
+	RepositoryCheckFailedReason = "RepositoryCheckFailed"
+
+	// RateLimitedReason is used when the GitHub token exceeded its rate limit
+	RateLimitedReason = "RateLimited"
+
+	// HookRetriesExhaustedReason is used when a BuildConfig change failed after all its retries
+	// (it has been moved to the dead-letter list, to be retried later)
+	HookRetriesExhaustedReason = "HookRetriesExhausted"
+)
+
+// recordHookEvent records an event against the given object (a BC or a reference to a BC)
+// that explains what happened to the given hook
+func (c *BuildConfigsController) recordHookEvent(obj runtime.Object, hook api.Hook, changed bool, err error) {
+	// a change refused while shutting down has not been sent to GitHub: there is nothing to report
+	if c.Recorder == nil || github.IsShuttingDown(err) {
+		return
+	}
+
+	if err != nil {
+		switch {
+		case github.IsNotFound(err):
+			c.Recorder.Eventf(obj, kapi.EventTypeWarning, RepositoryNotFoundReason, "GitHub repository %s not found (or not accessible with the current token): %v", hook.GithubRepository, err)
+		case github.IsForbidden(err):
+			c.Recorder.Eventf(obj, kapi.EventTypeWarning, PermissionDeniedReason, "Permission denied on GitHub repository %s (the token requires the admin:repo_hook scope): %v", hook.GithubRepository, err)
+		case github.IsValidation(err):
+			c.Recorder.Eventf(obj, kapi.EventTypeWarning, HookRejectedReason, "GitHub repository %s refused the hook (the hook limit may have been reached): %v", hook.GithubRepository, err)
+		case github.IsRateLimited(err):
+			c.Recorder.Eventf(obj, kapi.EventTypeWarning, RateLimitedReason, "GitHub API rate limit exceeded while syncing the hook on repository %s (will retry later): %v", hook.GithubRepository, err)
+		case hook.Enabled:
+			c.Recorder.Eventf(obj, kapi.EventTypeWarning, HookCreateFailedReason, "Failed to create hook on GitHub repository %s: %v", hook.GithubRepository, err)
+		default:
+			c.Recorder.Eventf(obj, kapi.EventTypeWarning, HookDeleteFailedReason, "Failed to delete hook from GitHub repository %s: %v", hook.GithubRepository, err)
+		}
+		return
+	}
+
+	if !changed {
+		return
+	}
+
+	switch {
+	case hook.Enabled && hook.ID != 0:
+		c.Recorder.Eventf(obj, kapi.EventTypeNormal, HookUpdatedReason, "Updated hook %d on GitHub repository %s", hook.ID, hook.GithubRepository)
+	case hook.Enabled:
+		c.Recorder.Eventf(obj, kapi.EventTypeNormal, HookCreatedReason, "Created hook on GitHub repository %s", hook.GithubRepository)
+	default:
+		c.Recorder.Eventf(obj, kapi.EventTypeNormal, HookDeletedReason, "Deleted hook from GitHub repository %s", hook.GithubRepository)
+	}
+}
+
+// updatedHook returns the given hook with the ID of the handled hook
+// if the handled hook is the previous hook of the BC (with the given ID),
+// so that its event reports an update instead of a creation:
+// a new hook is not created if an equivalent hook (the previous one) exists, it is updated
+func updatedHook(hook api.Hook, handledHook *api.Hook, changed bool, previousID int) api.Hook {
+	if changed && hook.Enabled && hook.ID == 0 && handledHook != nil && previousID != 0 && handledHook.ID == previousID {
+		hook.ID = handledHook.ID
+	}
+	return hook
+}
+
+// buildConfigReference returns a reference to the BC targeted by the given hook,
+// to record events when we don't have the BC anymore (only its hook)
+func buildConfigReference(hook api.Hook) *kapi.ObjectReference {
+	namespace, name, _ := ExplodeOpenshiftWebhookURL(hook.TargetURL)
+	return &kapi.ObjectReference{
+		Kind:      "BuildConfig",
+		Namespace: namespace,
+		Name:      name,
+	}
+}
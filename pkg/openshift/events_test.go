@@ -0,0 +1,164 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	hooksgithub "github.com/vbehar/openshift-github-hooks/pkg/github"
+
+	"github.com/google/go-github/github"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/record"
+)
+
+func TestUpdatedHook(t *testing.T) {
+	tests := []struct {
+		hook        api.Hook
+		handledHook *api.Hook
+		changed     bool
+		previousID  int
+		expectedID  int
+	}{
+		// the previous hook has been updated instead of creating a new one
+		{
+			hook:        api.Hook{Enabled: true},
+			handledHook: &api.Hook{ID: 1, Enabled: true},
+			changed:     true,
+			previousID:  1,
+			expectedID:  1,
+		},
+		// a new hook has been created
+		{
+			hook:        api.Hook{Enabled: true},
+			handledHook: &api.Hook{ID: 2, Enabled: true},
+			changed:     true,
+			previousID:  1,
+			expectedID:  0,
+		},
+		// no previous hook
+		{
+			hook:        api.Hook{Enabled: true},
+			handledHook: &api.Hook{ID: 2, Enabled: true},
+			changed:     true,
+			expectedID:  0,
+		},
+		// nothing changed
+		{
+			hook:        api.Hook{Enabled: true},
+			handledHook: &api.Hook{ID: 1, Enabled: true},
+			previousID:  1,
+			expectedID:  0,
+		},
+		// failed
+		{
+			hook:       api.Hook{Enabled: true},
+			changed:    true,
+			previousID: 1,
+			expectedID: 0,
+		},
+	}
+
+	for count, test := range tests {
+		hook := updatedHook(test.hook, test.handledHook, test.changed, test.previousID)
+		if hook.ID != test.expectedID {
+			t.Errorf("Test[%d] Failed: Expected hook ID %d but got %d", count, test.expectedID, hook.ID)
+		}
+	}
+}
+
+func TestBuildConfigsControllerRecordHookEvent(t *testing.T) {
+	githubError := func(statusCode int) error {
+		return &github.ErrorResponse{
+			Response: &http.Response{
+				StatusCode: statusCode,
+			},
+		}
+	}
+
+	tests := []struct {
+		enabled        bool
+		changed        bool
+		err            error
+		expectedReason string
+	}{
+		{
+			enabled:        true,
+			changed:        true,
+			expectedReason: HookCreatedReason,
+		},
+		{
+			enabled:        true,
+			changed:        false,
+			expectedReason: "",
+		},
+		{
+			enabled:        false,
+			changed:        true,
+			expectedReason: HookDeletedReason,
+		},
+		{
+			enabled:        true,
+			err:            fmt.Errorf("some error"),
+			expectedReason: HookCreateFailedReason,
+		},
+		{
+			enabled:        false,
+			err:            fmt.Errorf("some error"),
+			expectedReason: HookDeleteFailedReason,
+		},
+		{
+			enabled:        true,
+			err:            githubError(http.StatusNotFound),
+			expectedReason: RepositoryNotFoundReason,
+		},
+		{
+			enabled:        true,
+			err:            githubError(http.StatusForbidden),
+			expectedReason: PermissionDeniedReason,
+		},
+		{
+			enabled:        true,
+			err:            githubError(422),
+			expectedReason: HookRejectedReason,
+		},
+		{
+			enabled:        true,
+			err:            &github.RateLimitError{},
+			expectedReason: RateLimitedReason,
+		},
+		{
+			enabled:        true,
+			err:            hooksgithub.ErrShuttingDown,
+			expectedReason: "",
+		},
+	}
+
+	for count, test := range tests {
+		recorder := &record.FakeRecorder{}
+		controller := &BuildConfigsController{
+			Recorder: recorder,
+		}
+		hook := api.Hook{
+			Enabled: test.enabled,
+		}
+		controller.recordHookEvent(&kapi.ObjectReference{}, hook, test.changed, test.err)
+
+		if len(test.expectedReason) == 0 {
+			if len(recorder.Events) > 0 {
+				t.Errorf("Test[%d] Failed: Expected no events but got %v", count, recorder.Events)
+			}
+			continue
+		}
+		if len(recorder.Events) != 1 {
+			t.Errorf("Test[%d] Failed: Expected 1 event but got %v", count, recorder.Events)
+			continue
+		}
+		if !strings.Contains(recorder.Events[0], " "+test.expectedReason+" ") {
+			t.Errorf("Test[%d] Failed: Expected an event with reason '%s' but got '%s'", count, test.expectedReason, recorder.Events[0])
+		}
+	}
+}
@@ -6,17 +6,33 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync"
 
 	"github.com/emicklei/go-restful/swagger"
 	"github.com/golang/glog"
 )
 
+// defaultOpenshiftPublicURL caches the default openshift public URL,
+// which is used as the default value of the flags of multiple commands
+var (
+	defaultOpenshiftPublicURL     string
+	defaultOpenshiftPublicURLOnce sync.Once
+)
+
 // DefaultOpenshiftPublicURL returns the openshift public URL as defined
 // in the master config - and exposed in the swagger API.
-// Internally, this method will retrieve the swagger API to extract the public URL.
+// Internally, this method will retrieve the swagger API to extract the public URL (only once).
 // If it can't be retrieved, it will either return an empty string,
 // or the host of the server as defined by the client config.
 func DefaultOpenshiftPublicURL() string {
+	defaultOpenshiftPublicURLOnce.Do(func() {
+		defaultOpenshiftPublicURL = retrieveOpenshiftPublicURL()
+	})
+	return defaultOpenshiftPublicURL
+}
+
+// retrieveOpenshiftPublicURL retrieves the openshift public URL from the swagger API
+func retrieveOpenshiftPublicURL() string {
 	config, err := Factory.OpenShiftClientConfig.ClientConfig()
 	if err != nil {
 		glog.Warningf("Failed to get Openshift Config: %v", err)
@@ -7,23 +7,75 @@ import (
 	"strings"
 )
 
-// openshiftWebhookRegexp is a regexp that can extract the namespace, buildconfig and secret from an Openshift Webhook URI
-var openshiftWebhookRegexp = regexp.MustCompile(`oapi/v1/namespaces/([^/]+)/buildconfigs/([^/]+)/webhooks/([^/]+)/github`)
+const (
+	// LegacyWebhookAPIPath is the API path of the legacy (oapi) webhook URLs
+	LegacyWebhookAPIPath = "oapi/v1"
 
-// ExplodeOpenshiftWebhookURL explodes the given openshift webhook url
-// and returns the namespace, buildconfig and webhook secret
+	// GroupWebhookAPIPath is the API path of the API group (build.openshift.io) webhook URLs
+	GroupWebhookAPIPath = "apis/build.openshift.io/v1"
+)
+
+// openshiftWebhookRegexp is a regexp that can extract the API path, namespace, buildconfig and secret from an Openshift Webhook URI
+// (with either the legacy oapi path or the API group path)
+var openshiftWebhookRegexp = regexp.MustCompile(`(oapi/v1|apis/build\.openshift\.io/v1)/namespaces/([^/]+)/buildconfigs/([^/]+)/webhooks/([^/]+)/github`)
+
+// ExplodeOpenshiftWebhookURL explodes the given openshift (or relay) webhook url
+// and returns the namespace, buildconfig and webhook secret (empty for a relay webhook url)
 func ExplodeOpenshiftWebhookURL(url string) (namespace, buildconfig, secret string) {
 	switch matches := openshiftWebhookRegexp.FindStringSubmatch(url); len(matches) {
-	case 4:
-		namespace = matches[1]
-		buildconfig = matches[2]
-		secret = matches[3]
+	case 5:
+		namespace = matches[2]
+		buildconfig = matches[3]
+		secret = matches[4]
+	default:
+		// the relay webhook urls don't contain the secret
+		namespace, buildconfig = ExplodeRelayWebhookURL(url)
 	}
 	return
 }
 
+// SameOpenshiftHook returns true if the given hook URLs target the same webhook,
+// whatever their API path (legacy oapi or API group)
+func SameOpenshiftHook(hookURL, otherHookURL string) bool {
+	if hookURL == otherHookURL {
+		return true
+	}
+	return normalizeOpenshiftHookURL(hookURL) == normalizeOpenshiftHookURL(otherHookURL)
+}
+
+// normalizeOpenshiftHookURL returns the given hook URL with the legacy oapi path
+func normalizeOpenshiftHookURL(hookURL string) string {
+	return setOpenshiftHookAPIPath(hookURL, LegacyWebhookAPIPath)
+}
+
+// setOpenshiftHookAPIPath returns the given hook URL with the given API path
+// (LegacyWebhookAPIPath or GroupWebhookAPIPath).
+// The URL is returned as-is if it is not an openshift webhook URL, or if the API path is empty.
+func setOpenshiftHookAPIPath(hookURL string, apiPath string) string {
+	if len(apiPath) == 0 {
+		return hookURL
+	}
+	loc := openshiftWebhookRegexp.FindStringSubmatchIndex(hookURL)
+	if loc == nil {
+		return hookURL
+	}
+	return hookURL[:loc[2]] + apiPath + hookURL[loc[3]:]
+}
+
+// MaskOpenshiftHookSecret returns the given hook URL with its secret masked,
+// so that it can be printed or logged.
+// The URL is returned as-is if it is not an openshift webhook URL (for example a relay webhook URL).
+func MaskOpenshiftHookSecret(hookURL string) string {
+	loc := openshiftWebhookRegexp.FindStringSubmatchIndex(hookURL)
+	if loc == nil {
+		return hookURL
+	}
+	return hookURL[:loc[8]] + "*****" + hookURL[loc[9]:]
+}
+
 // IsOpenshiftHook returns true if the given hook URL is an Openshift hook URL
-// that targets the given openshift instance (identified by its public URL)
+// that targets the given openshift instance (identified by its public URL,
+// which may be an external base URL with a path prefix)
 func IsOpenshiftHook(hookURL string, openshiftPublicURL string) bool {
 	if !strings.Contains(hookURL, openshiftPublicURL) {
 		return false
@@ -35,6 +87,7 @@ func IsOpenshiftHook(hookURL string, openshiftPublicURL string) bool {
 }
 
 // fixOpenshiftHookURL tranforms the hook URL to make sure it is available through the given public (host) URL
+// (which may have a path prefix)
 func fixOpenshiftHookURL(hookURL *url.URL, openshiftPublicURL string) string {
 	if len(openshiftPublicURL) == 0 {
 		return hookURL.String()
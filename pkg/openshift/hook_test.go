@@ -18,6 +18,24 @@ func TestExplodeOpenshiftWebhookURL(t *testing.T) {
 			expectedBuildConfig: "mybc",
 			expectedSecret:      "mysecret",
 		},
+		{
+			url:                 "https://my.openshift.master:8443/apis/build.openshift.io/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			expectedNamespace:   "mynamespace",
+			expectedBuildConfig: "mybc",
+			expectedSecret:      "mysecret",
+		},
+		{
+			url:                 "https://hooks.example.com/prefix/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			expectedNamespace:   "mynamespace",
+			expectedBuildConfig: "mybc",
+			expectedSecret:      "mysecret",
+		},
+		{
+			url:                 "https://my.openshift.master:8443/apis/apps.openshift.io/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			expectedNamespace:   "",
+			expectedBuildConfig: "",
+			expectedSecret:      "",
+		},
 		{
 			url:                 "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/generic",
 			expectedNamespace:   "",
@@ -40,6 +58,106 @@ func TestExplodeOpenshiftWebhookURL(t *testing.T) {
 	}
 }
 
+func TestSameOpenshiftHook(t *testing.T) {
+	tests := []struct {
+		hookURL        string
+		otherHookURL   string
+		expectedResult bool
+	}{
+		{
+			hookURL:        "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			otherHookURL:   "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			expectedResult: true,
+		},
+		{
+			hookURL:        "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			otherHookURL:   "https://my.openshift.master:8443/apis/build.openshift.io/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			expectedResult: true,
+		},
+		{
+			hookURL:        "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			otherHookURL:   "https://my.openshift.master:8443/apis/build.openshift.io/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/othersecret/github",
+			expectedResult: false,
+		},
+		{
+			hookURL:        "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			otherHookURL:   "https://other.openshift.master:8443/apis/build.openshift.io/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			expectedResult: false,
+		},
+		{
+			hookURL:        "https://somewhere.com/some/path",
+			otherHookURL:   "https://somewhere.com/other/path",
+			expectedResult: false,
+		},
+	}
+
+	for count, test := range tests {
+		result := SameOpenshiftHook(test.hookURL, test.otherHookURL)
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%v' but got '%v'", count, test.expectedResult, result)
+		}
+	}
+}
+
+func TestSetOpenshiftHookAPIPath(t *testing.T) {
+	tests := []struct {
+		hookURL        string
+		apiPath        string
+		expectedResult string
+	}{
+		{
+			hookURL:        "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			apiPath:        "",
+			expectedResult: "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+		},
+		{
+			hookURL:        "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			apiPath:        GroupWebhookAPIPath,
+			expectedResult: "https://my.openshift.master:8443/apis/build.openshift.io/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+		},
+		{
+			hookURL:        "https://my.openshift.master:8443/apis/build.openshift.io/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			apiPath:        LegacyWebhookAPIPath,
+			expectedResult: "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+		},
+		{
+			hookURL:        "https://somewhere.com/some/path",
+			apiPath:        GroupWebhookAPIPath,
+			expectedResult: "https://somewhere.com/some/path",
+		},
+	}
+
+	for count, test := range tests {
+		result := setOpenshiftHookAPIPath(test.hookURL, test.apiPath)
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%s' but got '%s'", count, test.expectedResult, result)
+		}
+	}
+}
+
+func TestMaskOpenshiftHookSecret(t *testing.T) {
+	tests := []struct {
+		hookURL        string
+		expectedResult string
+	}{
+		{
+			hookURL:        "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			expectedResult: "https://my.openshift.master:8443/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/*****/github",
+		},
+		{
+			hookURL:        "https://relay.example.com/namespaces/mynamespace/buildconfigs/mybc/github",
+			expectedResult: "https://relay.example.com/namespaces/mynamespace/buildconfigs/mybc/github",
+		},
+	}
+
+	for count, test := range tests {
+		result := MaskOpenshiftHookSecret(test.hookURL)
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%s' but got '%s'", count, test.expectedResult, result)
+		}
+	}
+}
+
 func TestIsOpenshiftHook(t *testing.T) {
 	tests := []struct {
 		hookURL            string
@@ -66,6 +184,21 @@ func TestIsOpenshiftHook(t *testing.T) {
 			openshiftPublicURL: "https://my.openshift.master:8443",
 			expectedResult:     true,
 		},
+		{
+			hookURL:            "https://my.openshift.master:8443/apis/build.openshift.io/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			openshiftPublicURL: "https://my.openshift.master:8443",
+			expectedResult:     true,
+		},
+		{
+			hookURL:            "https://hooks.example.com/prefix/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			openshiftPublicURL: "https://hooks.example.com/prefix",
+			expectedResult:     true,
+		},
+		{
+			hookURL:            "https://hooks.example.com/oapi/v1/namespaces/mynamespace/buildconfigs/mybc/webhooks/mysecret/github",
+			openshiftPublicURL: "https://hooks.example.com/prefix",
+			expectedResult:     false,
+		},
 	}
 
 	for count, test := range tests {
@@ -102,6 +235,11 @@ func TestFixOpenshiftHookURL(t *testing.T) {
 			openshiftPublicURL: "http://my.openshift.master",
 			expectedResult:     "http://my.openshift.master/some/path",
 		},
+		{
+			hookURL:            "https://127.0.0.1:443/some/path",
+			openshiftPublicURL: "https://hooks.example.com/prefix",
+			expectedResult:     "https://hooks.example.com/prefix/some/path",
+		},
 	}
 
 	for count, test := range tests {
@@ -0,0 +1,125 @@
+package openshift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/golang/glog"
+	buildapi "github.com/openshift/origin/pkg/build/api"
+)
+
+// lastAppliedHook is what we remember about the last hook applied for a BC,
+// to be able to clean it up when the BC's repository or secret changes
+type lastAppliedHook struct {
+	ID               int
+	GithubRepository api.GithubRepository
+	URLHash          string
+}
+
+// rememberHook remembers the given hook as the last hook applied for the given BC key
+// (or forgets it if the hook is nil)
+func (c *BuildConfigsController) rememberHook(key string, hook *api.Hook) {
+	c.lastAppliedLock.Lock()
+	defer c.lastAppliedLock.Unlock()
+
+	if c.lastApplied == nil {
+		c.lastApplied = map[string]lastAppliedHook{}
+	}
+	if hook == nil {
+		delete(c.lastApplied, key)
+		return
+	}
+	c.lastApplied[key] = lastAppliedHook{
+		ID:               hook.ID,
+		GithubRepository: hook.GithubRepository,
+		URLHash:          hookURLHash(hook.TargetURL),
+	}
+}
+
+// lastAppliedHookFor returns the last hook applied for the given BC, or nil if we don't know it.
+// It is first retrieved from memory, and then from the BC's annotations
+// (written by a previous run, so that it survives restarts).
+func (c *BuildConfigsController) lastAppliedHookFor(bc *buildapi.BuildConfig) *lastAppliedHook {
+	c.lastAppliedLock.Lock()
+	previous, found := c.lastApplied[buildConfigKey(bc)]
+	c.lastAppliedLock.Unlock()
+	if found {
+		return &previous
+	}
+
+	return lastAppliedHookFromAnnotations(bc.Annotations)
+}
+
+// previousHooks returns the hooks that should be handled before the given hook for the given BC,
+// because the BC's repository or secret changed since the last applied hook:
+// - if the repository changed, the previous hook is returned (disabled) so that it gets deleted
+// - if only the URL changed, the ID of the previous hook is set on the given hook, so that it gets updated
+func (c *BuildConfigsController) previousHooks(bc *buildapi.BuildConfig, hook *api.Hook) []api.Hook {
+	previous := c.lastAppliedHookFor(bc)
+	if previous == nil || previous.ID == 0 {
+		return nil
+	}
+
+	if previous.GithubRepository != hook.GithubRepository {
+		glog.V(3).Infof("Repository of BC %s/%s changed from %s to %s - previous hook %d will be deleted", bc.Namespace, bc.Name, previous.GithubRepository, hook.GithubRepository, previous.ID)
+		return []api.Hook{
+			{
+				ID:               previous.ID,
+				Enabled:          false,
+				GithubRepository: previous.GithubRepository,
+			},
+		}
+	}
+
+	if len(previous.URLHash) > 0 && previous.URLHash != hookURLHash(hook.TargetURL) {
+		glog.V(3).Infof("Hook URL of BC %s/%s changed - previous hook %d will be updated", bc.Namespace, bc.Name, previous.ID)
+		hook.ID = previous.ID
+	}
+	return nil
+}
+
+// lastAppliedHookFromAnnotations returns the last hook applied, as stored in the given annotations
+// or nil if there is no such hook
+func lastAppliedHookFromAnnotations(annotations map[string]string) *lastAppliedHook {
+	ids := hookIDsFromAnnotations(annotations)
+	if len(ids) == 0 {
+		return nil
+	}
+	parts := strings.SplitN(annotations[api.RepositoryAnnotation], "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil
+	}
+	return &lastAppliedHook{
+		ID: ids[0],
+		GithubRepository: api.GithubRepository{
+			Owner: parts[0],
+			Name:  parts[1],
+		},
+		URLHash: annotations[api.HookURLHashAnnotation],
+	}
+}
+
+// hookIDsFromAnnotations returns the hook IDs stored in the given annotations
+func hookIDsFromAnnotations(annotations map[string]string) []int {
+	ids := []int{}
+	for _, value := range strings.Split(annotations[api.HookIDsAnnotation], ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// hookURLHash returns the (hex-encoded) SHA-256 hash of the given hook URL
+// or an empty string if the URL is empty
+func hookURLHash(hookURL string) string {
+	if len(hookURL) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(hookURL))
+	return hex.EncodeToString(hash[:])
+}
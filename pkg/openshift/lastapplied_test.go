@@ -0,0 +1,159 @@
+package openshift
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	buildapi "github.com/openshift/origin/pkg/build/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+)
+
+func TestBuildConfigsControllerPreviousHooks(t *testing.T) {
+	oldURL := "https://my.openshift.master:8443/oapi/v1/namespaces/ns/buildconfigs/bc/webhooks/oldsecret/github"
+	newURL := "https://my.openshift.master:8443/oapi/v1/namespaces/ns/buildconfigs/bc/webhooks/newsecret/github"
+	repository := api.GithubRepository{
+		Owner: "owner",
+		Name:  "name",
+	}
+	otherRepository := api.GithubRepository{
+		Owner: "owner",
+		Name:  "other",
+	}
+	bcWithAnnotations := func(annotations map[string]string) *buildapi.BuildConfig {
+		return &buildapi.BuildConfig{
+			ObjectMeta: kapi.ObjectMeta{
+				Namespace:   "ns",
+				Name:        "bc",
+				Annotations: annotations,
+			},
+		}
+	}
+
+	tests := []struct {
+		bc                    *buildapi.BuildConfig
+		remembered            *api.Hook
+		hook                  api.Hook
+		expectedPreviousHooks []api.Hook
+		expectedHookID        int
+	}{
+		// nothing known about the BC
+		{
+			bc: bcWithAnnotations(nil),
+			hook: api.Hook{
+				Enabled:          true,
+				TargetURL:        newURL,
+				GithubRepository: repository,
+			},
+			expectedPreviousHooks: nil,
+			expectedHookID:        0,
+		},
+		// nothing changed since the remembered hook
+		{
+			bc: bcWithAnnotations(nil),
+			remembered: &api.Hook{
+				ID:               42,
+				TargetURL:        newURL,
+				GithubRepository: repository,
+			},
+			hook: api.Hook{
+				Enabled:          true,
+				TargetURL:        newURL,
+				GithubRepository: repository,
+			},
+			expectedPreviousHooks: nil,
+			expectedHookID:        0,
+		},
+		// the secret changed since the remembered hook: update it
+		{
+			bc: bcWithAnnotations(nil),
+			remembered: &api.Hook{
+				ID:               42,
+				TargetURL:        oldURL,
+				GithubRepository: repository,
+			},
+			hook: api.Hook{
+				Enabled:          true,
+				TargetURL:        newURL,
+				GithubRepository: repository,
+			},
+			expectedPreviousHooks: nil,
+			expectedHookID:        42,
+		},
+		// the repository changed since the remembered hook: delete it
+		{
+			bc: bcWithAnnotations(nil),
+			remembered: &api.Hook{
+				ID:               42,
+				TargetURL:        oldURL,
+				GithubRepository: otherRepository,
+			},
+			hook: api.Hook{
+				Enabled:          true,
+				TargetURL:        newURL,
+				GithubRepository: repository,
+			},
+			expectedPreviousHooks: []api.Hook{
+				{
+					ID:               42,
+					Enabled:          false,
+					GithubRepository: otherRepository,
+				},
+			},
+			expectedHookID: 0,
+		},
+		// the secret changed since the hook stored in the annotations (after a restart)
+		{
+			bc: bcWithAnnotations(map[string]string{
+				api.HookIDsAnnotation:     "42",
+				api.RepositoryAnnotation:  "owner/name",
+				api.HookURLHashAnnotation: hookURLHash(oldURL),
+			}),
+			hook: api.Hook{
+				Enabled:          true,
+				TargetURL:        newURL,
+				GithubRepository: repository,
+			},
+			expectedPreviousHooks: nil,
+			expectedHookID:        42,
+		},
+		// the repository changed since the hook stored in the annotations (after a restart)
+		{
+			bc: bcWithAnnotations(map[string]string{
+				api.HookIDsAnnotation:     "42",
+				api.RepositoryAnnotation:  "owner/other",
+				api.HookURLHashAnnotation: hookURLHash(newURL),
+			}),
+			hook: api.Hook{
+				Enabled:          true,
+				TargetURL:        newURL,
+				GithubRepository: repository,
+			},
+			expectedPreviousHooks: []api.Hook{
+				{
+					ID:               42,
+					Enabled:          false,
+					GithubRepository: otherRepository,
+				},
+			},
+			expectedHookID: 0,
+		},
+	}
+
+	for count, test := range tests {
+		controller := &BuildConfigsController{}
+		if test.remembered != nil {
+			controller.rememberHook(buildConfigKey(test.bc), test.remembered)
+		}
+		hook := test.hook
+		previousHooks := controller.previousHooks(test.bc, &hook)
+		if !reflect.DeepEqual(previousHooks, test.expectedPreviousHooks) {
+			t.Errorf("Test[%d] Failed: Expected previous hooks %+v but got %+v", count, test.expectedPreviousHooks, previousHooks)
+		}
+		if hook.ID != test.expectedHookID {
+			t.Errorf("Test[%d] Failed: Expected hook ID %d but got %d", count, test.expectedHookID, hook.ID)
+		}
+	}
+}
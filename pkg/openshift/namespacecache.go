@@ -0,0 +1,74 @@
+package openshift
+
+import (
+	"sync"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/cache"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/watch"
+)
+
+// NamespaceCache gives access to the namespaces (projects) of the cluster, to read their labels and annotations,
+// either from a local cache kept up to date by watching the namespaces, or from the API
+type NamespaceCache struct {
+	client kclient.NamespacesInterface
+
+	// store is the local cache of the namespaces (only if watching)
+	store cache.Store
+
+	// fetched stores the namespaces retrieved from the API (only if not watching)
+	fetched     map[string]*kapi.Namespace
+	fetchedLock sync.Mutex
+}
+
+// NewNamespaceCache instantiates a new NamespaceCache that gets each namespace once
+// from the API - for short-lived commands
+func NewNamespaceCache(client kclient.NamespacesInterface) *NamespaceCache {
+	return &NamespaceCache{
+		client:  client,
+		fetched: map[string]*kapi.Namespace{},
+	}
+}
+
+// NewWatchingNamespaceCache instantiates a new NamespaceCache that watches the namespaces
+// until stopChan is closed - for daemons
+func NewWatchingNamespaceCache(client kclient.NamespacesInterface, stopChan <-chan struct{}) *NamespaceCache {
+	c := &NamespaceCache{
+		client: client,
+		store:  cache.NewStore(cache.MetaNamespaceKeyFunc),
+	}
+	cache.NewReflector(&cache.ListWatch{
+		ListFunc: func(options kapi.ListOptions) (runtime.Object, error) {
+			return client.Namespaces().List(options)
+		},
+		WatchFunc: func(options kapi.ListOptions) (watch.Interface, error) {
+			return client.Namespaces().Watch(options)
+		},
+	}, &kapi.Namespace{}, c.store, 0).RunUntil(stopChan)
+	return c
+}
+
+// Get returns the given namespace, from the local cache if possible, or from the API
+func (c *NamespaceCache) Get(name string) (*kapi.Namespace, error) {
+	if c.store != nil {
+		if obj, exists, err := c.store.GetByKey(name); err == nil && exists {
+			return obj.(*kapi.Namespace), nil
+		}
+		// the cache may not be synced yet
+		return c.client.Namespaces().Get(name)
+	}
+
+	c.fetchedLock.Lock()
+	defer c.fetchedLock.Unlock()
+	if namespace, found := c.fetched[name]; found {
+		return namespace, nil
+	}
+	namespace, err := c.client.Namespaces().Get(name)
+	if err != nil {
+		return nil, err
+	}
+	c.fetched[name] = namespace
+	return namespace, nil
+}
@@ -0,0 +1,136 @@
+package openshift
+
+import (
+	"sync"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/github"
+
+	"github.com/golang/glog"
+	"github.com/openshift/origin/pkg/controller"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/cache"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/runtime"
+	kutil "k8s.io/kubernetes/pkg/util"
+	"k8s.io/kubernetes/pkg/watch"
+)
+
+// NamespacesController represents a controller that will react to namespace (project) deletions,
+// to cleanup all the hooks targeting the BuildConfigs of the deleted namespaces at once
+// (instead of relying on the stream of BuildConfigs deletions).
+type NamespacesController struct {
+
+	// NamespacesInterface is used to list/watch the namespaces
+	NamespacesInterface kclient.NamespacesInterface
+
+	// NamespaceHandlerFunc is the function that will delete all the hooks
+	// targeting the BuildConfigs of the given (terminating or deleted) namespace
+	NamespaceHandlerFunc func(namespace string) error
+
+	// MaxRetries is the number of times a failed cleanup will be retried
+	MaxRetries int
+
+	// cleaned stores the terminating namespaces that have already been cleaned up,
+	// to avoid cleaning them up on every status update
+	cleaned     map[string]bool
+	cleanedLock sync.Mutex
+}
+
+// RunUntil starts watching the namespaces and handling their deletions in a goroutine
+// until stopChan is closed
+func (c *NamespacesController) RunUntil(stopChan <-chan struct{}) {
+	c.cleaned = map[string]bool{}
+
+	queue := cache.NewDeltaFIFO(cache.MetaNamespaceKeyFunc, nil, nil)
+	cache.NewReflector(c, &kapi.Namespace{}, queue, 0).RunUntil(stopChan)
+
+	retryController := &controller.RetryController{
+		Handle: c.handle,
+		Queue:  queue,
+		RetryManager: controller.NewQueueRetryManager(
+			queue,
+			queue.KeyOf,
+			func(obj interface{}, err error, retries controller.Retry) bool {
+				return retries.Count < c.MaxRetries && github.IsRetryable(err)
+			},
+			kutil.NewTokenBucketRateLimiter(1, 10)),
+	}
+
+	retryController.RunUntil(stopChan)
+}
+
+// handle handles a namespace change:
+// the hooks are cleaned up once when the namespace starts terminating
+// (while its BuildConfigs still exist, to know their repositories),
+// or when it is deleted if the terminating cleanup failed or has been missed
+func (c *NamespacesController) handle(obj interface{}) error {
+	deltas := obj.(cache.Deltas)
+	for _, delta := range deltas {
+		namespace, ok := delta.Object.(*kapi.Namespace)
+		if !ok {
+			deletedObject, isDeleted := delta.Object.(cache.DeletedFinalStateUnknown)
+			if !isDeleted {
+				glog.Warningf("Un-handled delta type %T (%s)", delta.Object, delta.Type)
+				continue
+			}
+			if namespace, ok = deletedObject.Obj.(*kapi.Namespace); !ok {
+				glog.Warningf("Un-handled %v DeletedFinalStateUnknown for %s: %+v", delta.Type, deletedObject.Key, deletedObject.Obj)
+				continue
+			}
+		}
+
+		switch {
+		case delta.Type == cache.Deleted:
+			if c.isCleaned(namespace.Name) {
+				glog.V(4).Infof("Namespace %s has been deleted - its hooks have already been cleaned up", namespace.Name)
+				c.setCleaned(namespace.Name, false)
+				continue
+			}
+			glog.V(3).Infof("Namespace %s has been deleted - cleaning up its hooks", namespace.Name)
+			if err := c.NamespaceHandlerFunc(namespace.Name); err != nil {
+				return err
+			}
+		case namespace.Status.Phase == kapi.NamespaceTerminating:
+			if c.isCleaned(namespace.Name) {
+				continue
+			}
+			glog.V(3).Infof("Namespace %s is terminating - cleaning up its hooks", namespace.Name)
+			if err := c.NamespaceHandlerFunc(namespace.Name); err != nil {
+				return err
+			}
+			c.setCleaned(namespace.Name, true)
+		}
+	}
+	return nil
+}
+
+// isCleaned returns true if the given terminating namespace has already been cleaned up
+func (c *NamespacesController) isCleaned(namespace string) bool {
+	c.cleanedLock.Lock()
+	defer c.cleanedLock.Unlock()
+	return c.cleaned[namespace]
+}
+
+// setCleaned remembers (or forgets) that the given terminating namespace has been cleaned up
+func (c *NamespacesController) setCleaned(namespace string, cleaned bool) {
+	c.cleanedLock.Lock()
+	defer c.cleanedLock.Unlock()
+	if cleaned {
+		c.cleaned[namespace] = true
+	} else {
+		delete(c.cleaned, namespace)
+	}
+}
+
+// List is for the cache.ListerWatcher implementation
+func (c *NamespacesController) List(options kapi.ListOptions) (runtime.Object, error) {
+	glog.V(3).Infof("Listing Namespaces with options %+v", options)
+	return c.NamespacesInterface.Namespaces().List(options)
+}
+
+// Watch is for the cache.ListerWatcher implementation
+func (c *NamespacesController) Watch(options kapi.ListOptions) (watch.Interface, error) {
+	glog.V(3).Infof("Watching Namespaces with options %+v", options)
+	return c.NamespacesInterface.Namespaces().Watch(options)
+}
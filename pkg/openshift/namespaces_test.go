@@ -0,0 +1,49 @@
+package openshift
+
+import (
+	"reflect"
+	"testing"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/cache"
+)
+
+func TestNamespacesControllerHandle(t *testing.T) {
+	namespace := func(name string, phase kapi.NamespacePhase) *kapi.Namespace {
+		return &kapi.Namespace{
+			ObjectMeta: kapi.ObjectMeta{Name: name},
+			Status:     kapi.NamespaceStatus{Phase: phase},
+		}
+	}
+
+	cleaned := []string{}
+	controller := &NamespacesController{
+		NamespaceHandlerFunc: func(namespace string) error {
+			cleaned = append(cleaned, namespace)
+			return nil
+		},
+		cleaned: map[string]bool{},
+	}
+
+	deltas := []cache.Deltas{
+		{{Type: cache.Sync, Object: namespace("active", kapi.NamespaceActive)}},
+		{{Type: cache.Updated, Object: namespace("terminating", kapi.NamespaceTerminating)}},
+		{{Type: cache.Updated, Object: namespace("terminating", kapi.NamespaceTerminating)}},
+		{{Type: cache.Deleted, Object: namespace("terminating", kapi.NamespaceTerminating)}},
+		{{Type: cache.Deleted, Object: cache.DeletedFinalStateUnknown{Key: "gone", Obj: namespace("gone", kapi.NamespaceActive)}}},
+	}
+	for _, delta := range deltas {
+		if err := controller.handle(delta); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	// the deleted namespace has already been cleaned up while terminating
+	expectedCleaned := []string{"terminating", "gone"}
+	if !reflect.DeepEqual(cleaned, expectedCleaned) {
+		t.Errorf("Expected the namespaces %v to be cleaned up, but got %v", expectedCleaned, cleaned)
+	}
+	if len(controller.cleaned) != 0 {
+		t.Errorf("Expected the deleted namespaces to be forgotten, but got %v", controller.cleaned)
+	}
+}
@@ -0,0 +1,49 @@
+package openshift
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// relayWebhookRegexp is a regexp that can extract the namespace and buildconfig from a relay Webhook URI
+// (the relay URIs don't contain the trigger secret, only an optional fingerprint of the hook secret)
+var relayWebhookRegexp = regexp.MustCompile(`/namespaces/([^/]+)/buildconfigs/([^/]+)/(?:webhooks/[0-9a-f]+/)?github$`)
+
+// RelayWebhookURL returns the URL of the hook targeting the given relay, for the given BC.
+// The URL contains a fingerprint of the given hook secret, so that the hooks
+// are updated with their new secret when the relay key changes.
+func RelayWebhookURL(relayURL, namespace, buildconfig, secret string) string {
+	return fmt.Sprintf("%s/namespaces/%s/buildconfigs/%s/webhooks/%s/github", strings.TrimSuffix(relayURL, "/"), namespace, buildconfig, relaySecretFingerprint(secret))
+}
+
+// relaySecretFingerprint returns a short fingerprint of the given hook secret,
+// that can't be used to guess the secret
+func relaySecretFingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:4])
+}
+
+// ExplodeRelayWebhookURL explodes the given relay webhook url (or path)
+// and returns the namespace and buildconfig
+func ExplodeRelayWebhookURL(url string) (namespace, buildconfig string) {
+	switch matches := relayWebhookRegexp.FindStringSubmatch(url); len(matches) {
+	case 3:
+		namespace = matches[1]
+		buildconfig = matches[2]
+	}
+	return
+}
+
+// RelayHookSecret returns the secret used by GitHub to sign the deliveries
+// of the hook targeting the relay for the given BC.
+// It is derived from the relay key, so that the relay can check the signature
+// without storing the secret of each hook.
+func RelayHookSecret(key []byte, namespace, buildconfig string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(fmt.Sprintf("%s/%s", namespace, buildconfig)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
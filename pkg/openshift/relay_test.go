@@ -0,0 +1,103 @@
+package openshift
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRelayWebhookURL(t *testing.T) {
+	tests := []struct {
+		relayURL            string
+		expectedURL         string
+		expectedNamespace   string
+		expectedBuildConfig string
+	}{
+		{
+			relayURL:            "https://relay.example.com",
+			expectedURL:         "https://relay.example.com/namespaces/mynamespace/buildconfigs/mybc/webhooks/2bb80d53/github",
+			expectedNamespace:   "mynamespace",
+			expectedBuildConfig: "mybc",
+		},
+		{
+			relayURL:            "https://relay.example.com/prefix/",
+			expectedURL:         "https://relay.example.com/prefix/namespaces/mynamespace/buildconfigs/mybc/webhooks/2bb80d53/github",
+			expectedNamespace:   "mynamespace",
+			expectedBuildConfig: "mybc",
+		},
+	}
+
+	for count, test := range tests {
+		url := RelayWebhookURL(test.relayURL, "mynamespace", "mybc", "secret")
+		if url != test.expectedURL {
+			t.Errorf("Test[%d] Failed: Expected URL '%s' but got '%s'", count, test.expectedURL, url)
+		}
+		ns, bc, secret := ExplodeOpenshiftWebhookURL(url)
+		if ns != test.expectedNamespace || bc != test.expectedBuildConfig || len(secret) > 0 {
+			t.Errorf("Test[%d] Failed: Expected '%s/%s' without secret but got '%s/%s' with secret '%s'", count, test.expectedNamespace, test.expectedBuildConfig, ns, bc, secret)
+		}
+		if !IsOpenshiftHook(url, strings.TrimSuffix(test.relayURL, "/")) {
+			t.Errorf("Test[%d] Failed: Expected URL '%s' to be recognized as an OpenShift hook", count, url)
+		}
+	}
+}
+
+func TestRelayWebhookURLChangesWithTheSecret(t *testing.T) {
+	url := RelayWebhookURL("https://relay.example.com", "mynamespace", "mybc", "secret")
+	if url == RelayWebhookURL("https://relay.example.com", "mynamespace", "mybc", "other-secret") {
+		t.Errorf("Expected a different URL for another secret")
+	}
+	if strings.Contains(url, "secret") {
+		t.Errorf("Expected the URL '%s' not to contain the secret", url)
+	}
+}
+
+func TestExplodeRelayWebhookURL(t *testing.T) {
+	tests := []struct {
+		url                 string
+		expectedNamespace   string
+		expectedBuildConfig string
+	}{
+		// with a fingerprint
+		{
+			url:                 "/namespaces/mynamespace/buildconfigs/mybc/webhooks/2bb80d53/github",
+			expectedNamespace:   "mynamespace",
+			expectedBuildConfig: "mybc",
+		},
+		// without fingerprint (hooks created before the fingerprints)
+		{
+			url:                 "https://relay.example.com/namespaces/mynamespace/buildconfigs/mybc/github",
+			expectedNamespace:   "mynamespace",
+			expectedBuildConfig: "mybc",
+		},
+		// invalid
+		{
+			url: "/namespaces/mynamespace/buildconfigs/mybc/webhooks/github",
+		},
+		{
+			url: "/namespaces/mynamespace/buildconfigs/mybc/generic",
+		},
+	}
+
+	for count, test := range tests {
+		ns, bc := ExplodeRelayWebhookURL(test.url)
+		if ns != test.expectedNamespace || bc != test.expectedBuildConfig {
+			t.Errorf("Test[%d] Failed: Expected '%s/%s' but got '%s/%s'", count, test.expectedNamespace, test.expectedBuildConfig, ns, bc)
+		}
+	}
+}
+
+func TestRelayHookSecret(t *testing.T) {
+	secret := RelayHookSecret([]byte("key"), "mynamespace", "mybc")
+	if len(secret) != 64 {
+		t.Errorf("Expected a 64 chars (hex-encoded SHA-256) secret but got '%s'", secret)
+	}
+	if secret != RelayHookSecret([]byte("key"), "mynamespace", "mybc") {
+		t.Errorf("Expected the secret to be stable")
+	}
+	if secret == RelayHookSecret([]byte("key"), "mynamespace", "otherbc") {
+		t.Errorf("Expected a different secret for another BC")
+	}
+	if secret == RelayHookSecret([]byte("otherkey"), "mynamespace", "mybc") {
+		t.Errorf("Expected a different secret for another key")
+	}
+}
@@ -0,0 +1,263 @@
+package openshift
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/github"
+
+	"github.com/golang/glog"
+	buildapi "github.com/openshift/origin/pkg/build/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/cache"
+	kutil "k8s.io/kubernetes/pkg/util"
+)
+
+// RetryPolicy defines how the failed BuildConfigs changes are retried
+type RetryPolicy struct {
+
+	// MaxRetries is the number of times a failed change is retried,
+	// before being moved to the dead-letter list
+	MaxRetries int
+
+	// InitialBackoff is the delay before the first retry
+	// (it is doubled on each retry)
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between 2 retries
+	MaxBackoff time.Duration
+
+	// QPS and Burst limit how fast the failed changes are re-queued
+	QPS   float32
+	Burst int
+
+	// DeadLetterRetryPeriod is the interval at which the changes of the dead-letter list
+	// are retried (0 means never)
+	DeadLetterRetryPeriod time.Duration
+}
+
+// DefaultRetryPolicy returns the default RetryPolicy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:            5,
+		InitialBackoff:        1 * time.Second,
+		MaxBackoff:            5 * time.Minute,
+		QPS:                   1,
+		Burst:                 10,
+		DeadLetterRetryPeriod: 15 * time.Minute,
+	}
+}
+
+// Backoff returns the delay before the given retry (starting at 0)
+func (p RetryPolicy) Backoff(retries int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 0; i < retries && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// DeadLetter is a BuildConfig change that failed after all its retries
+type DeadLetter struct {
+	// Key is the key of the BuildConfig ("namespace/name" format)
+	Key string
+
+	// Error is the last error
+	Error error
+
+	// Retries is the number of retries
+	Retries int
+
+	// Time is the time of the last failure
+	Time time.Time
+
+	resource interface{}
+}
+
+// retryManager is a controller.RetryManager that re-queues the failed changes
+// with an exponential backoff, and moves them to a dead-letter list once
+// they exhausted their retries
+type retryManager struct {
+	policy  RetryPolicy
+	queue   *cache.DeltaFIFO
+	limiter kutil.RateLimiter
+
+	// deadLetterFunc is called when a change is moved to the dead-letter list (optional)
+	deadLetterFunc func(deadLetter DeadLetter)
+
+	retries     map[string]int
+	deadLetters map[string]DeadLetter
+	// refused are the keys of the changes refused while shutting down
+	// (they have not been processed)
+	refused map[string]bool
+	lock    sync.Mutex
+}
+
+// newRetryManager instantiates a new retryManager for the given queue
+func newRetryManager(policy RetryPolicy, queue *cache.DeltaFIFO) *retryManager {
+	return &retryManager{
+		policy:      policy,
+		queue:       queue,
+		limiter:     kutil.NewTokenBucketRateLimiter(policy.QPS, policy.Burst),
+		retries:     map[string]int{},
+		deadLetters: map[string]DeadLetter{},
+		refused:     map[string]bool{},
+	}
+}
+
+// Retry is for the controller.RetryManager implementation
+// It re-queues the given resource after a backoff delay,
+// or moves it to the dead-letter list if it exhausted its retries.
+// Permanent errors (GitHub not found, forbidden or validation errors) are not retried.
+func (r *retryManager) Retry(resource interface{}, err error) {
+	key, keyErr := r.queue.KeyOf(resource)
+	if keyErr != nil {
+		glog.Errorf("Failed to get the key of %+v - dropping it: %v", resource, keyErr)
+		return
+	}
+
+	if github.IsShuttingDown(err) {
+		glog.Warningf("Not retrying BC %s while shutting down: its change has not been processed", key)
+		r.lock.Lock()
+		r.refused[key] = true
+		r.lock.Unlock()
+		return
+	}
+	if !github.IsRetryable(err) {
+		glog.Warningf("Not retrying BC %s because of a permanent error: %v", key, err)
+		r.Forget(resource)
+		return
+	}
+
+	r.lock.Lock()
+	retries := r.retries[key]
+	if retries >= r.policy.MaxRetries {
+		delete(r.retries, key)
+		deadLetter := DeadLetter{
+			Key:      key,
+			Error:    err,
+			Retries:  retries,
+			Time:     time.Now(),
+			resource: resource,
+		}
+		r.deadLetters[key] = deadLetter
+		r.lock.Unlock()
+
+		glog.Errorf("Giving up on BC %s after %d retries - moved to the dead-letter list: %v", key, retries, err)
+		if r.deadLetterFunc != nil {
+			r.deadLetterFunc(deadLetter)
+		}
+		return
+	}
+	r.retries[key] = retries + 1
+	r.lock.Unlock()
+
+	backoff := r.policy.Backoff(retries)
+	if retryAfter := github.RetryAfter(err); retryAfter > backoff {
+		backoff = retryAfter
+	}
+	glog.V(2).Infof("Retrying BC %s in %v (retry %d/%d): %v", key, backoff, retries+1, r.policy.MaxRetries, err)
+	time.AfterFunc(backoff, func() {
+		r.limiter.Accept()
+		// AddIfNotPresent does not overwrite a newer state that may have been queued in the meantime
+		r.queue.AddIfNotPresent(resource)
+	})
+}
+
+// Forget is for the controller.RetryManager implementation
+// It resets the retries of the given resource, and removes it from the dead-letter list
+func (r *retryManager) Forget(resource interface{}) {
+	key, err := r.queue.KeyOf(resource)
+	if err != nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.retries, key)
+	delete(r.deadLetters, key)
+	delete(r.refused, key)
+}
+
+// RefusedKeys returns the keys of the changes refused while shutting down, sorted
+func (r *retryManager) RefusedKeys() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	keys := []string{}
+	for key := range r.refused {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DeadLetters returns the content of the dead-letter list, sorted by key
+func (r *retryManager) DeadLetters() []DeadLetter {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	keys := []string{}
+	for key := range r.deadLetters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	deadLetters := []DeadLetter{}
+	for _, key := range keys {
+		deadLetters = append(deadLetters, r.deadLetters[key])
+	}
+	return deadLetters
+}
+
+// retryDeadLetters re-queues all the changes of the dead-letter list,
+// with a fresh retries count
+func (r *retryManager) retryDeadLetters() {
+	r.lock.Lock()
+	deadLetters := r.deadLetters
+	r.deadLetters = map[string]DeadLetter{}
+	r.lock.Unlock()
+
+	if len(deadLetters) > 0 {
+		glog.Infof("Retrying %d BuildConfigs changes from the dead-letter list", len(deadLetters))
+	}
+	for key, deadLetter := range deadLetters {
+		glog.V(2).Infof("Retrying BC %s from the dead-letter list (last error at %v: %v)", key, deadLetter.Time, deadLetter.Error)
+		r.limiter.Accept()
+		r.queue.AddIfNotPresent(deadLetter.resource)
+	}
+}
+
+// buildConfigFromDeltas returns the newest BC of the given Deltas,
+// or nil if there is none
+func buildConfigFromDeltas(resource interface{}) *buildapi.BuildConfig {
+	deltas, ok := resource.(cache.Deltas)
+	if !ok || deltas.Newest() == nil {
+		return nil
+	}
+	switch obj := deltas.Newest().Object.(type) {
+	case *buildapi.BuildConfig:
+		return obj
+	case cache.DeletedFinalStateUnknown:
+		bc, _ := obj.Obj.(*buildapi.BuildConfig)
+		return bc
+	}
+	return nil
+}
+
+// recordDeadLetterEvent records an event against the BC of the given dead letter
+func (c *BuildConfigsController) recordDeadLetterEvent(deadLetter DeadLetter) {
+	if c.Recorder == nil {
+		return
+	}
+	bc := buildConfigFromDeltas(deadLetter.resource)
+	if bc == nil {
+		return
+	}
+	c.Recorder.Eventf(bc, kapi.EventTypeWarning, HookRetriesExhaustedReason, "Gave up syncing the hook after %d retries (will retry later): %v", deadLetter.Retries, deadLetter.Error)
+}
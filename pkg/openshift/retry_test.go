@@ -0,0 +1,149 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	hooksgithub "github.com/vbehar/openshift-github-hooks/pkg/github"
+
+	"github.com/google/go-github/github"
+	buildapi "github.com/openshift/origin/pkg/build/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/client/cache"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     10 * time.Second,
+	}
+	tests := []struct {
+		retries         int
+		expectedBackoff time.Duration
+	}{
+		{retries: 0, expectedBackoff: 1 * time.Second},
+		{retries: 1, expectedBackoff: 2 * time.Second},
+		{retries: 3, expectedBackoff: 8 * time.Second},
+		{retries: 4, expectedBackoff: 10 * time.Second},
+		{retries: 100, expectedBackoff: 10 * time.Second},
+	}
+
+	for count, test := range tests {
+		backoff := policy.Backoff(test.retries)
+		if backoff != test.expectedBackoff {
+			t.Errorf("Test[%d] Failed: Expected backoff %v for %d retries, but got %v", count, test.expectedBackoff, test.retries, backoff)
+		}
+	}
+}
+
+func TestRetryManager(t *testing.T) {
+	queue := cache.NewDeltaFIFO(cache.MetaNamespaceKeyFunc, nil, nil)
+	manager := newRetryManager(RetryPolicy{
+		MaxRetries:     1,
+		InitialBackoff: 1 * time.Millisecond,
+		MaxBackoff:     1 * time.Millisecond,
+		QPS:            1000,
+		Burst:          10,
+	}, queue)
+	deadLetters := []DeadLetter{}
+	manager.deadLetterFunc = func(deadLetter DeadLetter) {
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	bc := &buildapi.BuildConfig{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "bc"}}
+	queue.Add(bc)
+	resource := queue.Pop()
+
+	// first failure: re-queued
+	manager.Retry(resource, fmt.Errorf("first failure"))
+	resource = queue.Pop()
+	if buildConfigFromDeltas(resource) != bc {
+		t.Errorf("Expected the BC to be re-queued, but got %+v", resource)
+	}
+
+	// second failure: moved to the dead-letter list
+	manager.Retry(resource, fmt.Errorf("second failure"))
+	if len(deadLetters) != 1 || deadLetters[0].Key != "ns/bc" || deadLetters[0].Retries != 1 {
+		t.Errorf("Expected the BC to be moved to the dead-letter list, but got %+v", deadLetters)
+	}
+	if list := manager.DeadLetters(); len(list) != 1 || list[0].Key != "ns/bc" {
+		t.Errorf("Expected the BC in the dead-letter list, but got %+v", list)
+	}
+	if keys := queue.ListKeys(); len(keys) != 0 {
+		t.Errorf("Expected an empty queue, but got %v", keys)
+	}
+
+	// dead letters retry: re-queued with a fresh retries count
+	manager.retryDeadLetters()
+	if list := manager.DeadLetters(); len(list) != 0 {
+		t.Errorf("Expected an empty dead-letter list, but got %+v", list)
+	}
+	resource = queue.Pop()
+	if buildConfigFromDeltas(resource) != bc {
+		t.Errorf("Expected the BC to be re-queued from the dead-letter list, but got %+v", resource)
+	}
+
+	// failure again, and then success: forgotten
+	manager.Retry(resource, fmt.Errorf("third failure"))
+	resource = queue.Pop()
+	manager.Forget(resource)
+	if len(deadLetters) != 1 {
+		t.Errorf("Expected no new dead letter, but got %+v", deadLetters)
+	}
+}
+
+func TestRetryManagerPermanentError(t *testing.T) {
+	tests := []error{
+		&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}},
+		hooksgithub.ErrShuttingDown,
+	}
+
+	for count, err := range tests {
+		queue := cache.NewDeltaFIFO(cache.MetaNamespaceKeyFunc, nil, nil)
+		manager := newRetryManager(DefaultRetryPolicy(), queue)
+
+		bc := &buildapi.BuildConfig{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "bc"}}
+		queue.Add(bc)
+		resource := queue.Pop()
+
+		manager.Retry(resource, err)
+		if keys := queue.ListKeys(); len(keys) != 0 {
+			t.Errorf("Test[%d] Failed: Expected the BC not to be re-queued, but got %v", count, keys)
+		}
+		if list := manager.DeadLetters(); len(list) != 0 {
+			t.Errorf("Test[%d] Failed: Expected an empty dead-letter list, but got %+v", count, list)
+		}
+		if len(manager.retries) != 0 {
+			t.Errorf("Test[%d] Failed: Expected no retries, but got %+v", count, manager.retries)
+		}
+	}
+}
+
+func TestRetryManagerShuttingDown(t *testing.T) {
+	queue := cache.NewDeltaFIFO(cache.MetaNamespaceKeyFunc, nil, nil)
+	manager := newRetryManager(DefaultRetryPolicy(), queue)
+	controller := &BuildConfigsController{queue: queue, retryManager: manager}
+
+	refused := &buildapi.BuildConfig{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "refused"}}
+	queued := &buildapi.BuildConfig{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "queued"}}
+	queue.Add(refused)
+	resource := queue.Pop()
+	queue.Add(queued)
+
+	// the change refused while shutting down is reported with the queued changes
+	manager.Retry(resource, hooksgithub.ErrShuttingDown)
+	if keys := manager.RefusedKeys(); len(keys) != 1 || keys[0] != "ns/refused" {
+		t.Errorf("Expected the BC to be refused, but got %v", keys)
+	}
+	if keys := controller.UnprocessedKeys(); len(keys) != 2 || keys[0] != "ns/queued" || keys[1] != "ns/refused" {
+		t.Errorf("Expected the queued and the refused BCs to be unprocessed, but got %v", keys)
+	}
+
+	manager.Forget(resource)
+	if keys := manager.RefusedKeys(); len(keys) != 0 {
+		t.Errorf("Expected no refused BC, but got %v", keys)
+	}
+}
@@ -0,0 +1,287 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	buildapi "github.com/openshift/origin/pkg/build/api"
+	"github.com/openshift/origin/pkg/client"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/util/wait"
+)
+
+// WebHookSecretKey is the key of the Secret data holding the webhook secret,
+// for the GitHub triggers that reference a Secret object
+const WebHookSecretKey = "WebHookSecretKey"
+
+// SecretCache gives access to the Secrets referenced by the GitHub triggers.
+// Only the referenced Secrets are retrieved (by name), and only their webhook secret is kept:
+// either for a single call (for short-lived commands), or in a local cache refreshed periodically.
+type SecretCache struct {
+	client kclient.SecretsNamespacer
+
+	// updateFunc is called with the namespace and name of each cached Secret whose webhook secret changed
+	// (created, updated or deleted) since the previous refresh (optional)
+	updateFunc func(namespace, name string)
+
+	// secrets stores the webhook secret of each referenced Secret, by key ("namespace/name" format)
+	// (only if refreshing)
+	secrets     map[string]cachedSecret
+	secretsLock sync.Mutex
+}
+
+// cachedSecret is the webhook secret of a Secret, as last retrieved from the API
+type cachedSecret struct {
+	namespace string
+	name      string
+	value     []byte
+	err       error
+}
+
+// NewSecretCache instantiates a new SecretCache that gets each Secret from the API
+// every time it is needed - for short-lived commands
+func NewSecretCache(client kclient.SecretsNamespacer) *SecretCache {
+	return &SecretCache{
+		client: client,
+	}
+}
+
+// NewRefreshingSecretCache instantiates a new SecretCache that keeps the webhook secret of the Secrets
+// it has been asked for, and gets them again from the API every period until stopChan is closed - for daemons.
+// The updateFunc is called with the namespace and name of each Secret whose webhook secret changed (optional).
+func NewRefreshingSecretCache(client kclient.SecretsNamespacer, period time.Duration, updateFunc func(namespace, name string), stopChan <-chan struct{}) *SecretCache {
+	c := &SecretCache{
+		client:     client,
+		updateFunc: updateFunc,
+		secrets:    map[string]cachedSecret{},
+	}
+	go wait.Until(c.refresh, period, stopChan)
+	return c
+}
+
+// Get returns the given Secret, with only its webhook secret data,
+// from the local cache if possible, or from the API
+func (c *SecretCache) Get(namespace, name string) (*kapi.Secret, error) {
+	key := namespace + "/" + name
+	if c.secrets != nil {
+		c.secretsLock.Lock()
+		cached, found := c.secrets[key]
+		c.secretsLock.Unlock()
+		if found {
+			return cached.secret()
+		}
+	}
+
+	cached := c.fetch(namespace, name)
+	if c.secrets != nil {
+		c.secretsLock.Lock()
+		c.secrets[key] = cached
+		c.secretsLock.Unlock()
+	}
+	return cached.secret()
+}
+
+// fetch gets the given Secret from the API, and keeps only its webhook secret
+func (c *SecretCache) fetch(namespace, name string) cachedSecret {
+	cached := cachedSecret{namespace: namespace, name: name}
+	secret, err := c.client.Secrets(namespace).Get(name)
+	if err != nil {
+		cached.err = err
+		return cached
+	}
+	cached.value = secret.Data[WebHookSecretKey]
+	return cached
+}
+
+// refresh gets again all the cached Secrets from the API,
+// and calls the updateFunc for the ones whose webhook secret changed
+// (including the Secrets created or deleted since the previous refresh)
+func (c *SecretCache) refresh() {
+	c.secretsLock.Lock()
+	previous := make([]cachedSecret, 0, len(c.secrets))
+	for _, cached := range c.secrets {
+		previous = append(previous, cached)
+	}
+	c.secretsLock.Unlock()
+
+	for _, old := range previous {
+		cached := c.fetch(old.namespace, old.name)
+		if cached.err != nil && !kerrors.IsNotFound(cached.err) {
+			// keep the last known value until the API answers again
+			glog.V(4).Infof("Failed to refresh Secret %s/%s: %v", old.namespace, old.name, cached.err)
+			continue
+		}
+
+		c.secretsLock.Lock()
+		c.secrets[old.namespace+"/"+old.name] = cached
+		c.secretsLock.Unlock()
+
+		if c.updateFunc != nil && (old.exists() != cached.exists() || !bytes.Equal(old.value, cached.value)) {
+			glog.V(3).Infof("Webhook secret of Secret %s/%s changed", old.namespace, old.name)
+			c.updateFunc(old.namespace, old.name)
+		}
+	}
+}
+
+// exists checks if the Secret existed when it was retrieved
+func (s cachedSecret) exists() bool {
+	return s.err == nil
+}
+
+// secret returns the Secret with only its webhook secret data, or the error returned by the API
+func (s cachedSecret) secret() (*kapi.Secret, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	secret := &kapi.Secret{
+		ObjectMeta: kapi.ObjectMeta{Namespace: s.namespace, Name: s.name},
+		Data:       map[string][]byte{},
+	}
+	if s.value != nil {
+		secret.Data[WebHookSecretKey] = s.value
+	}
+	return secret, nil
+}
+
+// SecretReferences resolves the webhook secrets of the GitHub triggers that reference a Secret object
+// (secretReference) instead of setting it inline. The OpenShift client we use does not know about
+// the secretReference field, so it is read from the raw BuildConfig - once per BuildConfig version.
+type SecretReferences struct {
+	// RawBuildConfigFunc returns the raw JSON of the given BuildConfig, as returned by the API
+	RawBuildConfigFunc func(namespace, name string) ([]byte, error)
+
+	// SecretFunc returns the given Secret
+	SecretFunc func(namespace, name string) (*kapi.Secret, error)
+
+	// references stores the name of the Secret referenced by each BC key ("namespace/name" format)
+	references     map[string]secretReference
+	referencesLock sync.Mutex
+}
+
+// secretReference is the Secret referenced by a given version of a BC
+type secretReference struct {
+	resourceVersion string
+	secretName      string
+}
+
+// NewSecretReferences instantiates a new SecretReferences that reads the raw BuildConfigs
+// with the given OpenShift client - its SecretFunc must be set before it is used
+func NewSecretReferences(oclient *client.Client) *SecretReferences {
+	return &SecretReferences{
+		RawBuildConfigFunc: func(namespace, name string) ([]byte, error) {
+			return oclient.Get().Namespace(namespace).Resource("buildConfigs").Name(name).DoRaw()
+		},
+	}
+}
+
+// Resolve returns the webhook secret referenced by the GitHub trigger of the given BC,
+// or an empty string if its GitHub trigger does not reference a Secret
+func (r *SecretReferences) Resolve(bc *buildapi.BuildConfig) (string, error) {
+	name, err := r.secretName(bc)
+	if err != nil || len(name) == 0 {
+		return "", err
+	}
+
+	secret, err := r.SecretFunc(bc.Namespace, name)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get the Secret %s/%s referenced by the GitHub trigger: %v", bc.Namespace, name, err)
+	}
+	value := secret.Data[WebHookSecretKey]
+	if len(value) == 0 {
+		return "", fmt.Errorf("The Secret %s/%s referenced by the GitHub trigger has no %s key", bc.Namespace, name, WebHookSecretKey)
+	}
+	return string(value), nil
+}
+
+// BuildConfigsReferencing returns the keys ("namespace/name" format) of the BCs
+// whose GitHub trigger references the given Secret, sorted
+func (r *SecretReferences) BuildConfigsReferencing(namespace, name string) []string {
+	r.referencesLock.Lock()
+	defer r.referencesLock.Unlock()
+
+	keys := []string{}
+	for key, reference := range r.references {
+		if reference.secretName == name && strings.HasPrefix(key, namespace+"/") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// secretName returns the name of the Secret referenced by the GitHub trigger of the given BC,
+// reading the raw BC only if this version of the BC has not been read yet
+func (r *SecretReferences) secretName(bc *buildapi.BuildConfig) (string, error) {
+	key := buildConfigKey(bc)
+
+	r.referencesLock.Lock()
+	reference, found := r.references[key]
+	r.referencesLock.Unlock()
+	if found && reference.resourceVersion == bc.ResourceVersion {
+		return reference.secretName, nil
+	}
+
+	raw, err := r.RawBuildConfigFunc(bc.Namespace, bc.Name)
+	if err != nil {
+		if found {
+			// the BC may have been deleted: its last known reference is the best we have
+			glog.V(4).Infof("Failed to read BC %s/%s - using its last known secret reference: %v", bc.Namespace, bc.Name, err)
+			return reference.secretName, nil
+		}
+		return "", fmt.Errorf("Failed to read BC %s/%s to get its secret reference: %v", bc.Namespace, bc.Name, err)
+	}
+	name, err := secretReferenceName(raw)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get the secret reference of BC %s/%s: %v", bc.Namespace, bc.Name, err)
+	}
+
+	r.referencesLock.Lock()
+	defer r.referencesLock.Unlock()
+	if r.references == nil {
+		r.references = map[string]secretReference{}
+	}
+	r.references[key] = secretReference{
+		resourceVersion: bc.ResourceVersion,
+		secretName:      name,
+	}
+	return name, nil
+}
+
+// secretReferenceName returns the name of the Secret referenced by the first GitHub trigger
+// of the given raw BC that has one, or an empty string
+func secretReferenceName(raw []byte) (string, error) {
+	var bc struct {
+		Spec struct {
+			Triggers []struct {
+				Type   string `json:"type"`
+				GitHub *struct {
+					SecretReference *struct {
+						Name string `json:"name"`
+					} `json:"secretReference"`
+				} `json:"github"`
+			} `json:"triggers"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &bc); err != nil {
+		return "", err
+	}
+
+	for _, trigger := range bc.Spec.Triggers {
+		if trigger.Type != string(buildapi.GitHubWebHookBuildTriggerType) || trigger.GitHub == nil || trigger.GitHub.SecretReference == nil {
+			continue
+		}
+		if len(trigger.GitHub.SecretReference.Name) > 0 {
+			return trigger.GitHub.SecretReference.Name, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,249 @@
+package openshift
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	buildapi "github.com/openshift/origin/pkg/build/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+func TestSecretReferenceName(t *testing.T) {
+	tests := []struct {
+		raw          string
+		expectedName string
+		expectedErr  bool
+	}{
+		{
+			raw:          `{"spec": {"triggers": [{"type": "GitHub", "github": {"secretReference": {"name": "webhook"}}}]}}`,
+			expectedName: "webhook",
+		},
+		{
+			raw:          `{"spec": {"triggers": [{"type": "Generic", "generic": {"secretReference": {"name": "generic"}}}, {"type": "GitHub", "github": {"secretReference": {"name": "webhook"}}}]}}`,
+			expectedName: "webhook",
+		},
+		{
+			raw:          `{"spec": {"triggers": [{"type": "GitHub", "github": {"secret": "inline"}}]}}`,
+			expectedName: "",
+		},
+		{
+			raw:          `{"spec": {}}`,
+			expectedName: "",
+		},
+		{
+			raw:         `not json`,
+			expectedErr: true,
+		},
+	}
+
+	for count, test := range tests {
+		name, err := secretReferenceName([]byte(test.raw))
+		if test.expectedErr != (err != nil) {
+			t.Errorf("Test[%d] Failed: Expected error %v but got %v", count, test.expectedErr, err)
+		}
+		if name != test.expectedName {
+			t.Errorf("Test[%d] Failed: Expected name '%s' but got '%s'", count, test.expectedName, name)
+		}
+	}
+}
+
+func TestSecretReferencesResolve(t *testing.T) {
+	reads := 0
+	references := &SecretReferences{
+		RawBuildConfigFunc: func(namespace, name string) ([]byte, error) {
+			reads++
+			switch name {
+			case "referenced", "rotated":
+				return []byte(`{"spec": {"triggers": [{"type": "GitHub", "github": {"secretReference": {"name": "webhook"}}}]}}`), nil
+			case "missing-secret":
+				return []byte(`{"spec": {"triggers": [{"type": "GitHub", "github": {"secretReference": {"name": "missing"}}}]}}`), nil
+			case "missing-key":
+				return []byte(`{"spec": {"triggers": [{"type": "GitHub", "github": {"secretReference": {"name": "other"}}}]}}`), nil
+			case "inline":
+				return []byte(`{"spec": {"triggers": [{"type": "GitHub", "github": {"secret": "inline"}}]}}`), nil
+			}
+			return nil, fmt.Errorf("buildconfig %s not found", name)
+		},
+		SecretFunc: func(namespace, name string) (*kapi.Secret, error) {
+			switch name {
+			case "webhook":
+				return &kapi.Secret{Data: map[string][]byte{WebHookSecretKey: []byte("referenced-secret")}}, nil
+			case "other":
+				return &kapi.Secret{Data: map[string][]byte{"token": []byte("value")}}, nil
+			}
+			return nil, fmt.Errorf("secret %s not found", name)
+		},
+	}
+
+	tests := []struct {
+		name           string
+		expectedSecret string
+		expectedErr    bool
+	}{
+		{
+			name:           "referenced",
+			expectedSecret: "referenced-secret",
+		},
+		{
+			name:        "missing-secret",
+			expectedErr: true,
+		},
+		{
+			name:        "missing-key",
+			expectedErr: true,
+		},
+		{
+			name:           "inline",
+			expectedSecret: "",
+		},
+		{
+			name:        "unknown",
+			expectedErr: true,
+		},
+	}
+
+	for count, test := range tests {
+		bc := &buildapi.BuildConfig{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: test.name, ResourceVersion: "1"}}
+		secret, err := references.Resolve(bc)
+		if test.expectedErr != (err != nil) {
+			t.Errorf("Test[%d] Failed: Expected error %v but got %v", count, test.expectedErr, err)
+		}
+		if secret != test.expectedSecret {
+			t.Errorf("Test[%d] Failed: Expected secret '%s' but got '%s'", count, test.expectedSecret, secret)
+		}
+	}
+
+	// the raw BC is only read again for a new version
+	reads = 0
+	bc := &buildapi.BuildConfig{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "referenced", ResourceVersion: "1"}}
+	references.Resolve(bc)
+	if reads != 0 {
+		t.Errorf("Expected the raw BC not to be read again for the same version, but got %d reads", reads)
+	}
+	bc.ResourceVersion = "2"
+	references.Resolve(bc)
+	if reads != 1 {
+		t.Errorf("Expected the raw BC to be read again for a new version, but got %d reads", reads)
+	}
+
+	references.Resolve(&buildapi.BuildConfig{ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "rotated", ResourceVersion: "1"}})
+	references.Resolve(&buildapi.BuildConfig{ObjectMeta: kapi.ObjectMeta{Namespace: "other", Name: "referenced", ResourceVersion: "1"}})
+	expectedKeys := []string{"ns/referenced", "ns/rotated"}
+	if keys := references.BuildConfigsReferencing("ns", "webhook"); !reflect.DeepEqual(keys, expectedKeys) {
+		t.Errorf("Expected the BCs %v to reference the secret, but got %v", expectedKeys, keys)
+	}
+}
+
+// fakeSecrets is a kclient.SecretsNamespacer that gets the Secrets it stores, and counts the calls
+type fakeSecrets struct {
+	kclient.SecretsInterface
+	secrets map[string]*kapi.Secret
+	gets    int
+}
+
+func (f *fakeSecrets) Secrets(namespace string) kclient.SecretsInterface {
+	return &fakeNamespacedSecrets{fakeSecrets: f, namespace: namespace}
+}
+
+type fakeNamespacedSecrets struct {
+	*fakeSecrets
+	namespace string
+}
+
+func (f *fakeNamespacedSecrets) Get(name string) (*kapi.Secret, error) {
+	f.gets++
+	if secret, found := f.secrets[f.namespace+"/"+name]; found {
+		return secret, nil
+	}
+	return nil, kerrors.NewNotFound(kapi.Resource("secrets"), name)
+}
+
+func newTestSecret(name, value string) *kapi.Secret {
+	return &kapi.Secret{
+		ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: name},
+		Data: map[string][]byte{
+			WebHookSecretKey: []byte(value),
+			"token":          []byte("not-needed"),
+		},
+	}
+}
+
+func TestSecretCacheGet(t *testing.T) {
+	client := &fakeSecrets{secrets: map[string]*kapi.Secret{"ns/webhook": newTestSecret("webhook", "v1")}}
+
+	for _, test := range []struct {
+		cache         *SecretCache
+		expectedCalls int
+	}{
+		{cache: NewSecretCache(client), expectedCalls: 2},
+		{cache: &SecretCache{client: client, secrets: map[string]cachedSecret{}}, expectedCalls: 1},
+	} {
+		client.gets = 0
+		for count := 0; count < 2; count++ {
+			secret, err := test.cache.Get("ns", "webhook")
+			if err != nil {
+				t.Fatalf("Test[%d] Failed: Unexpected error: %v", count, err)
+			}
+			expectedData := map[string][]byte{WebHookSecretKey: []byte("v1")}
+			if !reflect.DeepEqual(secret.Data, expectedData) {
+				t.Errorf("Test[%d] Failed: Expected only the webhook secret %v but got %v", count, expectedData, secret.Data)
+			}
+		}
+		if client.gets != test.expectedCalls {
+			t.Errorf("Expected %d calls to the API but got %d", test.expectedCalls, client.gets)
+		}
+	}
+
+	if _, err := NewSecretCache(client).Get("ns", "missing"); !kerrors.IsNotFound(err) {
+		t.Errorf("Expected a not found error for a missing Secret, but got %v", err)
+	}
+}
+
+func TestSecretCacheRefresh(t *testing.T) {
+	client := &fakeSecrets{secrets: map[string]*kapi.Secret{
+		"ns/rotated":   newTestSecret("rotated", "v1"),
+		"ns/unchanged": newTestSecret("unchanged", "v1"),
+		"ns/deleted":   newTestSecret("deleted", "v1"),
+	}}
+	updated := []string{}
+	c := &SecretCache{
+		client: client,
+		updateFunc: func(namespace, name string) {
+			updated = append(updated, namespace+"/"+name)
+		},
+		secrets: map[string]cachedSecret{},
+	}
+
+	// the "created" Secret is referenced before it is created
+	for _, name := range []string{"rotated", "unchanged", "deleted", "created"} {
+		c.Get("ns", name)
+	}
+	client.secrets["ns/rotated"] = newTestSecret("rotated", "v2")
+	client.secrets["ns/created"] = newTestSecret("created", "v1")
+	delete(client.secrets, "ns/deleted")
+
+	c.refresh()
+	sort.Strings(updated)
+	expectedUpdated := []string{"ns/created", "ns/deleted", "ns/rotated"}
+	if !reflect.DeepEqual(updated, expectedUpdated) {
+		t.Errorf("Expected the secrets %v to be updated, but got %v", expectedUpdated, updated)
+	}
+
+	if secret, err := c.Get("ns", "created"); err != nil || string(secret.Data[WebHookSecretKey]) != "v1" {
+		t.Errorf("Expected the created Secret to be cached, but got %v (error: %v)", secret, err)
+	}
+	if _, err := c.Get("ns", "deleted"); !kerrors.IsNotFound(err) {
+		t.Errorf("Expected a not found error for the deleted Secret, but got %v", err)
+	}
+
+	updated = []string{}
+	c.refresh()
+	if len(updated) != 0 {
+		t.Errorf("Expected no secrets to be updated by a second refresh, but got %v", updated)
+	}
+}
@@ -0,0 +1,112 @@
+package openshift
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/github"
+
+	"github.com/golang/glog"
+	buildapi "github.com/openshift/origin/pkg/build/api"
+
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+)
+
+// maxStatusUpdateRetries is the number of times we will try to update a BC status
+// when we get conflicts because the BC has been updated concurrently
+const maxStatusUpdateRetries = 3
+
+// updateStatus writes the sync status of the given BC in its annotations,
+// based on the hook returned by the HookHandlerFunc and its error.
+// It will only update the BC if the status changed, to avoid watch churn.
+func (c *BuildConfigsController) updateStatus(bc *buildapi.BuildConfig, hook *api.Hook, hookErr error) {
+	if github.IsShuttingDown(hookErr) {
+		glog.V(5).Infof("Not updating the status of BC %s/%s while shutting down", bc.Namespace, bc.Name)
+		return
+	}
+	if _, changed := statusAnnotations(bc.Annotations, hook, hookErr, time.Now()); !changed {
+		glog.V(5).Infof("Status of BC %s/%s did not change - nothing to update", bc.Namespace, bc.Name)
+		return
+	}
+
+	for i := 0; i < maxStatusUpdateRetries; i++ {
+		// always work on a fresh copy, the given BC might come from the cache
+		latest, err := c.BuildConfigsNamespacer.BuildConfigs(bc.Namespace).Get(bc.Name)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				glog.Warningf("Failed to retrieve BC %s/%s to update its status: %v", bc.Namespace, bc.Name, err)
+			}
+			return
+		}
+
+		annotations, changed := statusAnnotations(latest.Annotations, hook, hookErr, time.Now())
+		if !changed {
+			return
+		}
+		latest.Annotations = annotations
+
+		if _, err = c.BuildConfigsNamespacer.BuildConfigs(bc.Namespace).Update(latest); err == nil {
+			glog.V(4).Infof("Updated status of BC %s/%s", bc.Namespace, bc.Name)
+			return
+		}
+		if !kerrors.IsConflict(err) {
+			glog.Warningf("Failed to update status of BC %s/%s: %v", bc.Namespace, bc.Name, err)
+			return
+		}
+		glog.V(4).Infof("Conflict while updating status of BC %s/%s - retrying", bc.Namespace, bc.Name)
+	}
+
+	glog.Warningf("Failed to update status of BC %s/%s after %d retries", bc.Namespace, bc.Name, maxStatusUpdateRetries)
+}
+
+// statusAnnotations returns a copy of the given annotations with the sync status
+// for the given hook and error, and true if the status changed.
+// On success, the hook IDs, repository and last sync time are set, and the last error is removed.
+// On failure, the last error and its time are set, and the previous hook IDs and repository are kept.
+func statusAnnotations(annotations map[string]string, hook *api.Hook, hookErr error, now time.Time) (map[string]string, bool) {
+	result := map[string]string{}
+	for key, value := range annotations {
+		result[key] = value
+	}
+
+	if hookErr != nil {
+		if result[api.LastErrorAnnotation] == hookErr.Error() {
+			return result, false
+		}
+		result[api.LastErrorAnnotation] = hookErr.Error()
+		result[api.LastErrorTimeAnnotation] = now.UTC().Format(time.RFC3339)
+		return result, true
+	}
+
+	hookIDs, repository, urlHash := "", "", ""
+	if hook != nil {
+		if hook.ID != 0 {
+			hookIDs = strconv.Itoa(hook.ID)
+		}
+		repository = hook.GithubRepository.String()
+		urlHash = hookURLHash(hook.TargetURL)
+	}
+
+	_, errorFound := result[api.LastErrorAnnotation]
+	if !errorFound && result[api.HookIDsAnnotation] == hookIDs && result[api.RepositoryAnnotation] == repository && result[api.HookURLHashAnnotation] == urlHash {
+		return result, false
+	}
+
+	delete(result, api.LastErrorAnnotation)
+	delete(result, api.LastErrorTimeAnnotation)
+	setOrDelete(result, api.HookIDsAnnotation, hookIDs)
+	setOrDelete(result, api.RepositoryAnnotation, repository)
+	setOrDelete(result, api.HookURLHashAnnotation, urlHash)
+	result[api.LastSyncAnnotation] = now.UTC().Format(time.RFC3339)
+	return result, true
+}
+
+// setOrDelete sets the given key in the map, or deletes it if the value is empty
+func setOrDelete(m map[string]string, key, value string) {
+	if len(value) == 0 {
+		delete(m, key)
+		return
+	}
+	m[key] = value
+}
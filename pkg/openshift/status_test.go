@@ -0,0 +1,116 @@
+package openshift
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+)
+
+func TestStatusAnnotations(t *testing.T) {
+	now := time.Date(2016, 5, 10, 14, 30, 0, 0, time.UTC)
+	nowStr := "2016-05-10T14:30:00Z"
+	hook := &api.Hook{
+		ID: 42,
+		GithubRepository: api.GithubRepository{
+			Owner: "owner",
+			Name:  "name",
+		},
+	}
+
+	tests := []struct {
+		annotations         map[string]string
+		hook                *api.Hook
+		hookErr             error
+		expectedAnnotations map[string]string
+		expectedChanged     bool
+	}{
+		// should set the status on a fresh BC
+		{
+			annotations: nil,
+			hook:        hook,
+			expectedAnnotations: map[string]string{
+				api.HookIDsAnnotation:    "42",
+				api.RepositoryAnnotation: "owner/name",
+				api.LastSyncAnnotation:   nowStr,
+			},
+			expectedChanged: true,
+		},
+		// should not change anything if the status is the same
+		{
+			annotations: map[string]string{
+				"foo":                    "bar",
+				api.HookIDsAnnotation:    "42",
+				api.RepositoryAnnotation: "owner/name",
+				api.LastSyncAnnotation:   "2016-01-01T00:00:00Z",
+			},
+			hook: hook,
+			expectedAnnotations: map[string]string{
+				"foo":                    "bar",
+				api.HookIDsAnnotation:    "42",
+				api.RepositoryAnnotation: "owner/name",
+				api.LastSyncAnnotation:   "2016-01-01T00:00:00Z",
+			},
+			expectedChanged: false,
+		},
+		// should set the error and keep the previous status
+		{
+			annotations: map[string]string{
+				api.HookIDsAnnotation:    "42",
+				api.RepositoryAnnotation: "owner/name",
+				api.LastSyncAnnotation:   "2016-01-01T00:00:00Z",
+			},
+			hookErr: fmt.Errorf("some error"),
+			expectedAnnotations: map[string]string{
+				api.HookIDsAnnotation:       "42",
+				api.RepositoryAnnotation:    "owner/name",
+				api.LastSyncAnnotation:      "2016-01-01T00:00:00Z",
+				api.LastErrorAnnotation:     "some error",
+				api.LastErrorTimeAnnotation: nowStr,
+			},
+			expectedChanged: true,
+		},
+		// should not change anything if the error is the same
+		{
+			annotations: map[string]string{
+				api.LastErrorAnnotation:     "some error",
+				api.LastErrorTimeAnnotation: "2016-01-01T00:00:00Z",
+			},
+			hookErr: fmt.Errorf("some error"),
+			expectedAnnotations: map[string]string{
+				api.LastErrorAnnotation:     "some error",
+				api.LastErrorTimeAnnotation: "2016-01-01T00:00:00Z",
+			},
+			expectedChanged: false,
+		},
+		// should remove the error once the hook is synced
+		{
+			annotations: map[string]string{
+				api.HookIDsAnnotation:       "42",
+				api.RepositoryAnnotation:    "owner/name",
+				api.LastSyncAnnotation:      "2016-01-01T00:00:00Z",
+				api.LastErrorAnnotation:     "some error",
+				api.LastErrorTimeAnnotation: "2016-01-01T00:00:00Z",
+			},
+			hook: hook,
+			expectedAnnotations: map[string]string{
+				api.HookIDsAnnotation:    "42",
+				api.RepositoryAnnotation: "owner/name",
+				api.LastSyncAnnotation:   nowStr,
+			},
+			expectedChanged: true,
+		},
+	}
+
+	for count, test := range tests {
+		annotations, changed := statusAnnotations(test.annotations, test.hook, test.hookErr, now)
+		if changed != test.expectedChanged {
+			t.Errorf("Test[%d] Failed: Expected changed '%v' but got '%v'", count, test.expectedChanged, changed)
+		}
+		if !reflect.DeepEqual(annotations, test.expectedAnnotations) {
+			t.Errorf("Test[%d] Failed: Expected annotations %v but got %v", count, test.expectedAnnotations, annotations)
+		}
+	}
+}
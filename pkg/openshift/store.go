@@ -0,0 +1,77 @@
+package openshift
+
+import (
+	"sync"
+
+	"k8s.io/kubernetes/pkg/client/cache"
+)
+
+// buildConfigsStore is a cache.Store used by the BuildConfigs reflector.
+// It keeps a local cache of all the BuildConfigs (used to compute the desired hooks),
+// and queues all the changes in a DeltaFIFO (to be handled by the controller).
+// The local cache is also used by the queue as its "known objects",
+// to detect the BuildConfigs deleted while we were not watching.
+type buildConfigsStore struct {
+	cache.Store
+
+	queue *cache.DeltaFIFO
+
+	synced     bool
+	syncedLock sync.RWMutex
+}
+
+// newBuildConfigsStore instantiates a new buildConfigsStore
+// the queue must be set before the store is used
+func newBuildConfigsStore() *buildConfigsStore {
+	return &buildConfigsStore{
+		Store: cache.NewStore(cache.MetaNamespaceKeyFunc),
+	}
+}
+
+// Add is for the cache.Store implementation
+func (s *buildConfigsStore) Add(obj interface{}) error {
+	if err := s.queue.Add(obj); err != nil {
+		return err
+	}
+	return s.Store.Add(obj)
+}
+
+// Update is for the cache.Store implementation
+func (s *buildConfigsStore) Update(obj interface{}) error {
+	if err := s.queue.Update(obj); err != nil {
+		return err
+	}
+	return s.Store.Update(obj)
+}
+
+// Delete is for the cache.Store implementation
+func (s *buildConfigsStore) Delete(obj interface{}) error {
+	if err := s.queue.Delete(obj); err != nil {
+		return err
+	}
+	return s.Store.Delete(obj)
+}
+
+// Replace is for the cache.Store implementation
+// The queue is replaced first, because it uses the (old) local cache
+// to find the objects that have been deleted.
+func (s *buildConfigsStore) Replace(list []interface{}, resourceVersion string) error {
+	if err := s.queue.Replace(list, resourceVersion); err != nil {
+		return err
+	}
+	if err := s.Store.Replace(list, resourceVersion); err != nil {
+		return err
+	}
+
+	s.syncedLock.Lock()
+	defer s.syncedLock.Unlock()
+	s.synced = true
+	return nil
+}
+
+// HasSynced returns true once the initial list of objects has been stored
+func (s *buildConfigsStore) HasSynced() bool {
+	s.syncedLock.RLock()
+	defer s.syncedLock.RUnlock()
+	return s.synced
+}
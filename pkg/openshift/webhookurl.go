@@ -0,0 +1,76 @@
+package openshift
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/openshift/origin/pkg/client"
+	routeapi "github.com/openshift/origin/pkg/route/api"
+)
+
+// WebhookURLOptions defines how the public base URL of the webhooks is built,
+// when GitHub can't reach the OpenShift master directly
+// (for example if only a dedicated router or ingress host is exposed to GitHub)
+type WebhookURLOptions struct {
+	// BaseURL is an external base URL, reachable by GitHub (optional)
+	BaseURL string
+
+	// Route is the "namespace/name" of the Route whose host is used as the base URL (optional)
+	Route string
+
+	// PathPrefix is the path added after the base URL (optional)
+	PathPrefix string
+}
+
+// PublicURL returns the public base URL of the webhooks: the external base URL if defined,
+// or the URL of the Route if defined, or the given OpenShift public URL -
+// followed by the path prefix.
+// The routes namespacer is only used if a Route is defined.
+func (o WebhookURLOptions) PublicURL(routes client.RoutesNamespacer, openshiftPublicURL string) (string, error) {
+	baseURL := openshiftPublicURL
+	switch {
+	case len(o.BaseURL) > 0:
+		baseURL = o.BaseURL
+	case len(o.Route) > 0:
+		parts := strings.Split(o.Route, "/")
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return "", fmt.Errorf("Invalid route %s: the expected format is namespace/name", o.Route)
+		}
+		if routes == nil {
+			return "", fmt.Errorf("Can't get route %s without an OpenShift client", o.Route)
+		}
+		route, err := routes.Routes(parts[0]).Get(parts[1])
+		if err != nil {
+			return "", fmt.Errorf("Failed to get route %s: %v", o.Route, err)
+		}
+		if baseURL, err = routeURL(route); err != nil {
+			return "", err
+		}
+		glog.V(2).Infof("Using route %s URL %s as the webhooks base URL", o.Route, baseURL)
+	}
+
+	publicURL := strings.TrimSuffix(baseURL, "/")
+	if prefix := strings.Trim(o.PathPrefix, "/"); len(prefix) > 0 {
+		publicURL = fmt.Sprintf("%s/%s", publicURL, prefix)
+	}
+	return publicURL, nil
+}
+
+// routeURL returns the URL of the given route: its host (or the host of its first ingress point),
+// and its path - with https if it is secured
+func routeURL(route *routeapi.Route) (string, error) {
+	host := route.Spec.Host
+	if len(host) == 0 && len(route.Status.Ingress) > 0 {
+		host = route.Status.Ingress[0].Host
+	}
+	if len(host) == 0 {
+		return "", fmt.Errorf("Route %s/%s has no host", route.Namespace, route.Name)
+	}
+
+	scheme := "http"
+	if route.Spec.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, host, strings.TrimSuffix(route.Spec.Path, "/")), nil
+}
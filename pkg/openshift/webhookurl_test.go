@@ -0,0 +1,141 @@
+package openshift
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openshift/origin/pkg/client"
+	routeapi "github.com/openshift/origin/pkg/route/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+)
+
+// fakeRoutes is a client.RoutesNamespacer that only knows how to get a single route
+type fakeRoutes struct {
+	client.RouteInterface
+	route *routeapi.Route
+}
+
+func (f *fakeRoutes) Routes(namespace string) client.RouteInterface {
+	return f
+}
+
+func (f *fakeRoutes) Get(name string) (*routeapi.Route, error) {
+	if f.route == nil || f.route.Name != name {
+		return nil, fmt.Errorf("route %s not found", name)
+	}
+	return f.route, nil
+}
+
+func TestWebhookURLOptionsPublicURL(t *testing.T) {
+	routes := &fakeRoutes{
+		route: &routeapi.Route{
+			ObjectMeta: kapi.ObjectMeta{Namespace: "hooks", Name: "github"},
+			Spec: routeapi.RouteSpec{
+				Host: "hooks.example.com",
+				TLS:  &routeapi.TLSConfig{Termination: routeapi.TLSTerminationEdge},
+			},
+		},
+	}
+
+	tests := []struct {
+		options            WebhookURLOptions
+		openshiftPublicURL string
+		expectedResult     string
+		expectedError      bool
+	}{
+		{
+			options:            WebhookURLOptions{},
+			openshiftPublicURL: "https://my.openshift.master:8443",
+			expectedResult:     "https://my.openshift.master:8443",
+		},
+		{
+			options:            WebhookURLOptions{PathPrefix: "/openshift/"},
+			openshiftPublicURL: "https://my.openshift.master:8443",
+			expectedResult:     "https://my.openshift.master:8443/openshift",
+		},
+		{
+			options:            WebhookURLOptions{BaseURL: "https://proxy.example.com/", Route: "hooks/github"},
+			openshiftPublicURL: "https://my.openshift.master:8443",
+			expectedResult:     "https://proxy.example.com",
+		},
+		{
+			options:            WebhookURLOptions{Route: "hooks/github", PathPrefix: "prefix"},
+			openshiftPublicURL: "https://my.openshift.master:8443",
+			expectedResult:     "https://hooks.example.com/prefix",
+		},
+		{
+			options:       WebhookURLOptions{Route: "hooks/unknown"},
+			expectedError: true,
+		},
+		{
+			options:       WebhookURLOptions{Route: "github"},
+			expectedError: true,
+		},
+	}
+
+	for count, test := range tests {
+		result, err := test.options.PublicURL(routes, test.openshiftPublicURL)
+		if test.expectedError {
+			if err == nil {
+				t.Errorf("Test[%d] Failed: Expected an error but got '%s'", count, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test[%d] Failed: got unexpected error %v", count, err)
+			continue
+		}
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%s' but got '%s'", count, test.expectedResult, result)
+		}
+	}
+}
+
+func TestRouteURL(t *testing.T) {
+	tests := []struct {
+		route          *routeapi.Route
+		expectedResult string
+		expectedError  bool
+	}{
+		{
+			route: &routeapi.Route{
+				Spec: routeapi.RouteSpec{Host: "hooks.example.com"},
+			},
+			expectedResult: "http://hooks.example.com",
+		},
+		{
+			route: &routeapi.Route{
+				Spec: routeapi.RouteSpec{Host: "hooks.example.com", Path: "/github/", TLS: &routeapi.TLSConfig{}},
+			},
+			expectedResult: "https://hooks.example.com/github",
+		},
+		{
+			route: &routeapi.Route{
+				Status: routeapi.RouteStatus{Ingress: []routeapi.RouteIngress{{Host: "generated.apps.example.com"}}},
+			},
+			expectedResult: "http://generated.apps.example.com",
+		},
+		{
+			route:         &routeapi.Route{},
+			expectedError: true,
+		},
+	}
+
+	for count, test := range tests {
+		result, err := routeURL(test.route)
+		if test.expectedError {
+			if err == nil {
+				t.Errorf("Test[%d] Failed: Expected an error but got '%s'", count, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test[%d] Failed: got unexpected error %v", count, err)
+			continue
+		}
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%s' but got '%s'", count, test.expectedResult, result)
+		}
+	}
+}
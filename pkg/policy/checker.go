@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// Load loads the policy from the given file, or from the given configmap ("namespace/name" format).
+// It returns a nil policy (that allows everything) if neither is set.
+func Load(client kclient.ConfigMapsNamespacer, filename string, configMap string) (*Policy, error) {
+	switch {
+	case len(filename) > 0:
+		return LoadFile(filename)
+	case len(configMap) > 0:
+		parts := strings.Split(configMap, "/")
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("Invalid policy configmap %s: the expected format is namespace/name", configMap)
+		}
+		return LoadConfigMap(client, parts[0], parts[1])
+	}
+	return nil, nil
+}
+
+// Checker checks the policy for the namespaces of the cluster,
+// retrieving their labels if the policy uses label selectors
+type Checker struct {
+	policy     *Policy
+	namespaces *openshift.NamespaceCache
+}
+
+// NewChecker instantiates a new Checker for the given policy,
+// using the given namespaces to retrieve their labels
+func NewChecker(policy *Policy, namespaces *openshift.NamespaceCache) *Checker {
+	return &Checker{
+		policy:     policy,
+		namespaces: namespaces,
+	}
+}
+
+// Check returns an error if the policy does not allow the given namespace to hook the given repository,
+// or if the labels of the namespace (required by the policy) can't be retrieved
+func (c *Checker) Check(namespace string, repository api.GithubRepository) error {
+	if c == nil || c.policy == nil {
+		return nil
+	}
+
+	var namespaceLabels map[string]string
+	if c.policy.NeedsLabels() {
+		ns, err := c.namespaces.Get(namespace)
+		if err != nil {
+			return &openshift.RepositoryCheckUnknownError{Err: fmt.Errorf("Failed to get namespace %s to check the policy: %v", namespace, err)}
+		}
+		namespaceLabels = ns.Labels
+	}
+
+	if !c.policy.Allows(namespace, namespaceLabels, repository) {
+		return fmt.Errorf("The policy does not allow the namespace %s to hook the repository %s", namespace, repository)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+)
+
+// fakeNamespaces is a kclient.NamespacesInterface that returns the namespaces it stores,
+// or an error for the unknown ones
+type fakeNamespaces struct {
+	kclient.NamespaceInterface
+	namespaces map[string]*kapi.Namespace
+}
+
+func (f *fakeNamespaces) Namespaces() kclient.NamespaceInterface {
+	return f
+}
+
+func (f *fakeNamespaces) Get(name string) (*kapi.Namespace, error) {
+	if ns, found := f.namespaces[name]; found {
+		return ns, nil
+	}
+	return nil, fmt.Errorf("the API is down")
+}
+
+func TestCheckerCheck(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Failed to parse the policy: %v", err)
+	}
+	namespaces := &fakeNamespaces{
+		namespaces: map[string]*kapi.Namespace{
+			"team-b-dev": {ObjectMeta: kapi.ObjectMeta{Name: "team-b-dev", Labels: map[string]string{"team": "b"}}},
+			"team-c-dev": {ObjectMeta: kapi.ObjectMeta{Name: "team-c-dev"}},
+		},
+	}
+	checker := NewChecker(policy, openshift.NewNamespaceCache(namespaces))
+
+	tests := []struct {
+		namespace       string
+		expectedError   string
+		expectedUnknown bool
+	}{
+		// allowed by its labels
+		{
+			namespace: "team-b-dev",
+		},
+		// denied by the policy
+		{
+			namespace:     "team-c-dev",
+			expectedError: "The policy does not allow the namespace team-c-dev to hook the repository my-org/team-b-app",
+		},
+		// the labels can't be retrieved
+		{
+			namespace:       "team-d-dev",
+			expectedError:   "Failed to get namespace team-d-dev to check the policy: the API is down",
+			expectedUnknown: true,
+		},
+	}
+
+	for count, test := range tests {
+		err := checker.Check(test.namespace, api.GithubRepository{Owner: "my-org", Name: "team-b-app"})
+		switch {
+		case len(test.expectedError) == 0 && err != nil:
+			t.Errorf("Test[%d] Failed: Expected no error but got %v", count, err)
+		case len(test.expectedError) > 0 && (err == nil || err.Error() != test.expectedError):
+			t.Errorf("Test[%d] Failed: Expected error '%s' but got %v", count, test.expectedError, err)
+		}
+		if openshift.IsRepositoryCheckUnknown(err) != test.expectedUnknown {
+			t.Errorf("Test[%d] Failed: Expected an unknown result: %v, but got %v", count, test.expectedUnknown, err)
+		}
+	}
+
+	var nilChecker *Checker
+	if err := nilChecker.Check("any", api.GithubRepository{Owner: "any", Name: "repo"}); err != nil {
+		t.Errorf("Expected a nil checker to allow everything, but got %v", err)
+	}
+}
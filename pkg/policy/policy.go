@@ -0,0 +1,145 @@
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/ghodss/yaml"
+
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
+	"k8s.io/kubernetes/pkg/labels"
+)
+
+// ConfigMapKey is the key of the policy in the policy configmap
+const ConfigMapKey = "policy.yaml"
+
+// Policy restricts which namespaces may hook which GitHub repositories.
+// A namespace may only hook the repositories allowed by at least one of the rules matching the namespace:
+// if no rule matches a namespace, it can't hook any repository.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule allows the matching namespaces to hook the matching repositories
+type Rule struct {
+	// Namespaces are the globs (for example "team-a-*") of the namespaces matched by the rule
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector is a label selector (for example "team=a") of the namespaces matched by the rule.
+	// If both the namespaces globs and the selector are defined, a namespace must match both.
+	NamespaceSelector string `json:"namespaceSelector,omitempty"`
+
+	// Repositories are the globs (for example "my-org/team-a-*") of the allowed repositories,
+	// in the "owner/name" format
+	Repositories []string `json:"repositories"`
+
+	selector labels.Selector
+}
+
+// Parse parses the given policy (in YAML or JSON)
+func Parse(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("Invalid policy: %v", err)
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if len(rule.Namespaces) == 0 && len(rule.NamespaceSelector) == 0 {
+			return nil, fmt.Errorf("Invalid policy rule %d: either namespaces or a namespaceSelector is required", i)
+		}
+		for _, glob := range append(rule.Namespaces, rule.Repositories...) {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("Invalid policy rule %d: invalid glob %s: %v", i, glob, err)
+			}
+		}
+		if len(rule.NamespaceSelector) > 0 {
+			selector, err := labels.Parse(rule.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid policy rule %d: invalid namespace selector %s: %v", i, rule.NamespaceSelector, err)
+			}
+			rule.selector = selector
+		}
+	}
+	return policy, nil
+}
+
+// LoadFile loads the policy from the given file
+func LoadFile(filename string) (*Policy, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// LoadConfigMap loads the policy from the given configmap (in the ConfigMapKey key)
+func LoadConfigMap(client kclient.ConfigMapsNamespacer, namespace, name string) (*Policy, error) {
+	configMap, err := client.ConfigMaps(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	data, found := configMap.Data[ConfigMapKey]
+	if !found {
+		return nil, fmt.Errorf("No %s key in configmap %s/%s", ConfigMapKey, namespace, name)
+	}
+	return Parse([]byte(data))
+}
+
+// NeedsLabels returns true if the policy uses namespace label selectors
+// (in which case the labels of the namespaces should be given to Allows)
+func (p *Policy) NeedsLabels() bool {
+	if p == nil {
+		return false
+	}
+	for _, rule := range p.Rules {
+		if rule.selector != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows checks if the given namespace (with the given labels) may hook the given repository.
+// A nil policy allows everything.
+func (p *Policy) Allows(namespace string, namespaceLabels map[string]string, repository api.GithubRepository) bool {
+	if p == nil {
+		return true
+	}
+
+	repo := strings.ToLower(repository.String())
+	for _, rule := range p.Rules {
+		if !rule.matchesNamespace(namespace, namespaceLabels) {
+			continue
+		}
+		if matchesAny(rule.Repositories, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNamespace checks if the rule matches the given namespace (with the given labels)
+func (r Rule) matchesNamespace(namespace string, namespaceLabels map[string]string) bool {
+	if len(r.Namespaces) > 0 && !matchesAny(r.Namespaces, namespace) {
+		return false
+	}
+	if r.selector != nil && !r.selector.Matches(labels.Set(namespaceLabels)) {
+		return false
+	}
+	return true
+}
+
+// matchesAny checks if the given value matches any of the given globs (case-insensitive)
+func matchesAny(globs []string, value string) bool {
+	for _, glob := range globs {
+		if matched, _ := path.Match(strings.ToLower(glob), strings.ToLower(value)); matched {
+			return true
+		}
+	}
+	return false
+}
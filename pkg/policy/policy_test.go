@@ -0,0 +1,79 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+)
+
+const testPolicy = `
+rules:
+- namespaces: ["team-a-*"]
+  repositories: ["my-org/team-a-*", "my-org/shared"]
+- namespaceSelector: "team=b"
+  repositories: ["my-org/team-b-*"]
+- namespaces: ["ops"]
+  namespaceSelector: "env=prod"
+  repositories: ["my-org/*"]
+`
+
+func TestPolicyAllows(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Failed to parse the policy: %v", err)
+	}
+	if !policy.NeedsLabels() {
+		t.Errorf("Expected the policy to need the namespaces labels")
+	}
+
+	tests := []struct {
+		namespace      string
+		labels         map[string]string
+		repository     string
+		expectedResult bool
+	}{
+		{namespace: "team-a-dev", repository: "my-org/team-a-app", expectedResult: true},
+		{namespace: "team-a-dev", repository: "My-Org/Shared", expectedResult: true},
+		{namespace: "team-a-dev", repository: "my-org/team-b-app", expectedResult: false},
+		{namespace: "team-b-dev", labels: map[string]string{"team": "b"}, repository: "my-org/team-b-app", expectedResult: true},
+		{namespace: "team-b-dev", repository: "my-org/team-b-app", expectedResult: false},
+		{namespace: "ops", labels: map[string]string{"env": "prod"}, repository: "my-org/anything", expectedResult: true},
+		{namespace: "ops", labels: map[string]string{"env": "dev"}, repository: "my-org/anything", expectedResult: false},
+		{namespace: "unknown", repository: "my-org/shared", expectedResult: false},
+	}
+
+	for count, test := range tests {
+		parts := strings.SplitN(test.repository, "/", 2)
+		result := policy.Allows(test.namespace, test.labels, api.GithubRepository{Owner: parts[0], Name: parts[1]})
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%v' for %s on %s but got '%v'", count, test.expectedResult, test.namespace, test.repository, result)
+		}
+	}
+
+	var nilPolicy *Policy
+	if !nilPolicy.Allows("any", nil, api.GithubRepository{Owner: "any", Name: "repo"}) {
+		t.Errorf("Expected a nil policy to allow everything")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		policy        string
+		expectedError bool
+	}{
+		{policy: testPolicy, expectedError: false},
+		{policy: `{"rules": [{"namespaces": ["a"], "repositories": ["my-org/a"]}]}`, expectedError: false},
+		{policy: `rules: [{repositories: ["my-org/a"]}]`, expectedError: true},
+		{policy: `rules: [{namespaces: ["[a"], repositories: ["my-org/a"]}]`, expectedError: true},
+		{policy: `rules: [{namespaceSelector: "a in (", repositories: ["my-org/a"]}]`, expectedError: true},
+		{policy: `rules: "invalid"`, expectedError: true},
+	}
+
+	for count, test := range tests {
+		_, err := Parse([]byte(test.policy))
+		if (err != nil) != test.expectedError {
+			t.Errorf("Test[%d] Failed: Expected error '%v' but got %v", count, test.expectedError, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package reconciler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/golang/glog"
+)
+
+// KeyFunc returns the key identifying the owner of a hook
+// (typically the "namespace/name" of the BuildConfig targeted by the hook),
+// or an error if the hook is not managed by us
+type KeyFunc func(hook api.Hook) (string, error)
+
+// Plan is the list of changes to apply on GitHub
+// to go from the actual hooks to the desired hooks
+type Plan struct {
+	// Create contains the hooks to create
+	Create []api.Hook
+
+	// Update contains the hooks to update, with the ID of the existing hook
+	Update []api.Hook
+
+	// Delete contains the hooks to delete, with their ID
+	Delete []api.Hook
+}
+
+// IsEmpty returns true if there is nothing to change
+func (p Plan) IsEmpty() bool {
+	return len(p.Create) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// Diff computes the plan to go from the actual hooks (on GitHub) to the desired hooks (from the BuildConfigs).
+// Hooks are matched by their key (as returned by the given keyFunc):
+// - a desired hook with no actual hook for the same key is created
+// - a desired hook with an actual hook for the same key and repository, but a different URL, is updated
+// - an actual hook with no desired hook for the same key and repository is deleted (including duplicates)
+// - an actual hook whose key is one of the unmanaged keys is left untouched
+func Diff(desired, actual []api.Hook, unmanaged map[string]bool, keyFunc KeyFunc) Plan {
+	plan := Plan{}
+
+	actualByKey := map[string][]api.Hook{}
+	for _, hook := range actual {
+		key, err := keyFunc(hook)
+		if err != nil {
+			glog.V(5).Infof("Ignoring unmanaged hook %s on repository %s: %v", hook.TargetURL, hook.GithubRepository, err)
+			continue
+		}
+		if unmanaged[key] {
+			glog.V(5).Infof("Leaving hook %d on repository %s untouched: %s is not managed", hook.ID, hook.GithubRepository, key)
+			continue
+		}
+		actualByKey[key] = append(actualByKey[key], hook)
+	}
+
+	desiredByKey := map[string]api.Hook{}
+	for _, hook := range desired {
+		key, err := keyFunc(hook)
+		if err != nil {
+			glog.Warningf("Ignoring invalid desired hook %s on repository %s: %v", hook.TargetURL, hook.GithubRepository, err)
+			continue
+		}
+		desiredByKey[key] = hook
+	}
+
+	for _, key := range sortedKeys(desiredByKey) {
+		hook := desiredByKey[key]
+		candidates := actualByKey[key]
+		delete(actualByKey, key)
+
+		if i := indexOf(candidates, func(h api.Hook) bool {
+			return sameRepository(h, hook) && h.TargetURL == hook.TargetURL
+		}); i >= 0 {
+			plan.Delete = append(plan.Delete, without(candidates, i)...)
+			continue
+		}
+
+		if i := indexOf(candidates, func(h api.Hook) bool {
+			return sameRepository(h, hook)
+		}); i >= 0 {
+			update := hook
+			update.ID = candidates[i].ID
+			plan.Update = append(plan.Update, update)
+			plan.Delete = append(plan.Delete, without(candidates, i)...)
+			continue
+		}
+
+		plan.Create = append(plan.Create, hook)
+		plan.Delete = append(plan.Delete, candidates...)
+	}
+
+	for _, key := range sortedActualKeys(actualByKey) {
+		plan.Delete = append(plan.Delete, actualByKey[key]...)
+	}
+
+	for i := range plan.Delete {
+		plan.Delete[i].Enabled = false
+	}
+	return plan
+}
+
+// sameRepository checks if both hooks are on the same GitHub repository
+func sameRepository(a, b api.Hook) bool {
+	return strings.EqualFold(a.GithubRepository.String(), b.GithubRepository.String())
+}
+
+// indexOf returns the index of the first hook matching the given function, or -1
+func indexOf(hooks []api.Hook, matches func(api.Hook) bool) int {
+	for i := range hooks {
+		if matches(hooks[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// without returns a copy of the given hooks without the one at the given index
+func without(hooks []api.Hook, index int) []api.Hook {
+	result := []api.Hook{}
+	for i := range hooks {
+		if i != index {
+			result = append(result, hooks[i])
+		}
+	}
+	return result
+}
+
+// sortedKeys returns the sorted keys of the given map
+func sortedKeys(m map[string]api.Hook) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedActualKeys returns the sorted keys of the given map
+func sortedActualKeys(m map[string][]api.Hook) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,190 @@
+package reconciler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+)
+
+// testKeyFunc identifies the hooks by their URL path, without the secret
+// (URLs are in the format "https://openshift/<namespace>/<name>/<secret>")
+func testKeyFunc(hook api.Hook) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(hook.TargetURL, "https://openshift/"), "/")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("unmanaged hook %s", hook.TargetURL)
+	}
+	return parts[0] + "/" + parts[1], nil
+}
+
+func TestDiff(t *testing.T) {
+	repo := api.GithubRepository{Owner: "owner", Name: "repo"}
+	otherRepo := api.GithubRepository{Owner: "owner", Name: "other"}
+	hook := func(id int, enabled bool, url string, repository api.GithubRepository) api.Hook {
+		return api.Hook{
+			ID:               id,
+			Enabled:          enabled,
+			TargetURL:        url,
+			GithubRepository: repository,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		desired      []api.Hook
+		actual       []api.Hook
+		unmanaged    map[string]bool
+		expectedPlan Plan
+	}{
+		{
+			name:         "nothing to do",
+			desired:      []api.Hook{},
+			actual:       []api.Hook{},
+			expectedPlan: Plan{},
+		},
+		{
+			name: "hook already exists",
+			desired: []api.Hook{
+				hook(0, true, "https://openshift/ns/bc/secret", repo),
+			},
+			actual: []api.Hook{
+				hook(1, true, "https://openshift/ns/bc/secret", repo),
+			},
+			expectedPlan: Plan{},
+		},
+		{
+			name: "missing hook",
+			desired: []api.Hook{
+				hook(0, true, "https://openshift/ns/bc/secret", repo),
+			},
+			actual: []api.Hook{},
+			expectedPlan: Plan{
+				Create: []api.Hook{
+					hook(0, true, "https://openshift/ns/bc/secret", repo),
+				},
+			},
+		},
+		{
+			name:    "orphan hook",
+			desired: []api.Hook{},
+			actual: []api.Hook{
+				hook(1, true, "https://openshift/ns/bc/secret", repo),
+			},
+			expectedPlan: Plan{
+				Delete: []api.Hook{
+					hook(1, false, "https://openshift/ns/bc/secret", repo),
+				},
+			},
+		},
+		{
+			name:    "unmanaged hooks are ignored",
+			desired: []api.Hook{},
+			actual: []api.Hook{
+				hook(1, true, "https://jenkins/some/hook", repo),
+			},
+			expectedPlan: Plan{},
+		},
+		{
+			name: "secret changed",
+			desired: []api.Hook{
+				hook(0, true, "https://openshift/ns/bc/newsecret", repo),
+			},
+			actual: []api.Hook{
+				hook(1, true, "https://openshift/ns/bc/oldsecret", repo),
+			},
+			expectedPlan: Plan{
+				Update: []api.Hook{
+					hook(1, true, "https://openshift/ns/bc/newsecret", repo),
+				},
+			},
+		},
+		{
+			name: "repository changed",
+			desired: []api.Hook{
+				hook(0, true, "https://openshift/ns/bc/secret", repo),
+			},
+			actual: []api.Hook{
+				hook(1, true, "https://openshift/ns/bc/secret", otherRepo),
+			},
+			expectedPlan: Plan{
+				Create: []api.Hook{
+					hook(0, true, "https://openshift/ns/bc/secret", repo),
+				},
+				Delete: []api.Hook{
+					hook(1, false, "https://openshift/ns/bc/secret", otherRepo),
+				},
+			},
+		},
+		{
+			name: "duplicate hooks",
+			desired: []api.Hook{
+				hook(0, true, "https://openshift/ns/bc/secret", repo),
+			},
+			actual: []api.Hook{
+				hook(1, true, "https://openshift/ns/bc/oldsecret", repo),
+				hook(2, true, "https://openshift/ns/bc/secret", repo),
+				hook(3, true, "https://openshift/ns/bc/secret", repo),
+			},
+			expectedPlan: Plan{
+				Delete: []api.Hook{
+					hook(1, false, "https://openshift/ns/bc/oldsecret", repo),
+					hook(3, false, "https://openshift/ns/bc/secret", repo),
+				},
+			},
+		},
+		{
+			name: "multiple buildconfigs",
+			desired: []api.Hook{
+				hook(0, true, "https://openshift/ns/b/secret", repo),
+				hook(0, true, "https://openshift/ns/a/secret", repo),
+			},
+			actual: []api.Hook{
+				hook(1, true, "https://openshift/ns/c/secret", repo),
+				hook(2, true, "https://openshift/ns/a/secret", repo),
+			},
+			expectedPlan: Plan{
+				Create: []api.Hook{
+					hook(0, true, "https://openshift/ns/b/secret", repo),
+				},
+				Delete: []api.Hook{
+					hook(1, false, "https://openshift/ns/c/secret", repo),
+				},
+			},
+		},
+		{
+			name:    "hooks of an unmanaged BC",
+			desired: []api.Hook{},
+			actual: []api.Hook{
+				hook(1, true, "https://openshift/ns/ignored/secret", repo),
+				hook(2, true, "https://openshift/ns/ignored/other-secret", otherRepo),
+			},
+			unmanaged: map[string]bool{
+				"ns/ignored": true,
+			},
+			expectedPlan: Plan{},
+		},
+	}
+
+	for i, test := range tests {
+		plan := Diff(test.desired, test.actual, test.unmanaged, testKeyFunc)
+		if !reflect.DeepEqual(normalize(plan), normalize(test.expectedPlan)) {
+			t.Errorf("Test[%d] Failed (%s): Expected plan %+v but got %+v", i, test.name, test.expectedPlan, plan)
+		}
+	}
+}
+
+// normalize replaces nil slices by empty slices, to compare plans
+func normalize(plan Plan) Plan {
+	if plan.Create == nil {
+		plan.Create = []api.Hook{}
+	}
+	if plan.Update == nil {
+		plan.Update = []api.Hook{}
+	}
+	if plan.Delete == nil {
+		plan.Delete = []api.Hook{}
+	}
+	return plan
+}
@@ -0,0 +1,65 @@
+package reconciler
+
+import (
+	"fmt"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+)
+
+// DeletionLimits defines the maximum number of hooks that a single reconciliation can delete,
+// to protect us from deleting all the hooks when the desired hooks are wrong
+// (empty list of BuildConfigs because of an RBAC regression, wrong OpenShift public URL, ...)
+type DeletionLimits struct {
+
+	// MaxCount is the maximum number of hooks that can be deleted (0 means no limit)
+	MaxCount int
+
+	// MaxPercent is the maximum percentage of the managed hooks that can be deleted (0 means no limit).
+	// It only applies when more than one hook would be deleted.
+	MaxPercent int
+}
+
+// Check returns a MassDeletionError if deleting the given number of hooks
+// (out of the given number of managed hooks) exceeds the limits
+func (l DeletionLimits) Check(deletions, managedHooks int) error {
+	if deletions == 0 {
+		return nil
+	}
+	if l.MaxCount > 0 && deletions > l.MaxCount {
+		return &MassDeletionError{Deletions: deletions, ManagedHooks: managedHooks, Limits: l}
+	}
+	if l.MaxPercent > 0 && deletions > 1 && deletions*100 > l.MaxPercent*managedHooks {
+		return &MassDeletionError{Deletions: deletions, ManagedHooks: managedHooks, Limits: l}
+	}
+	return nil
+}
+
+// MassDeletionError is returned when a reconciliation would delete more hooks than allowed
+type MassDeletionError struct {
+	Deletions    int
+	ManagedHooks int
+	Limits       DeletionLimits
+}
+
+// Error is for the error implementation
+func (e *MassDeletionError) Error() string {
+	return fmt.Sprintf("Refusing to delete %d of the %d managed hooks (limits: %d hooks, %d%%)", e.Deletions, e.ManagedHooks, e.Limits.MaxCount, e.Limits.MaxPercent)
+}
+
+// IsMassDeletion returns true if the given error is a MassDeletionError
+func IsMassDeletion(err error) bool {
+	_, ok := err.(*MassDeletionError)
+	return ok
+}
+
+// countManagedHooks returns the number of hooks managed by us
+// (the ones for which the keyFunc returns a key)
+func countManagedHooks(hooks []api.Hook, keyFunc KeyFunc) int {
+	count := 0
+	for _, hook := range hooks {
+		if _, err := keyFunc(hook); err == nil {
+			count++
+		}
+	}
+	return count
+}
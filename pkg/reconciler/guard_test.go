@@ -0,0 +1,70 @@
+package reconciler
+
+import (
+	"testing"
+)
+
+func TestDeletionLimitsCheck(t *testing.T) {
+	tests := []struct {
+		limits          DeletionLimits
+		deletions       int
+		managedHooks    int
+		expectedBlocked bool
+	}{
+		{
+			limits:          DeletionLimits{},
+			deletions:       100,
+			managedHooks:    100,
+			expectedBlocked: false,
+		},
+		{
+			limits:          DeletionLimits{MaxCount: 10, MaxPercent: 50},
+			deletions:       0,
+			managedHooks:    0,
+			expectedBlocked: false,
+		},
+		{
+			limits:          DeletionLimits{MaxCount: 10},
+			deletions:       10,
+			managedHooks:    10,
+			expectedBlocked: false,
+		},
+		{
+			limits:          DeletionLimits{MaxCount: 10},
+			deletions:       11,
+			managedHooks:    100,
+			expectedBlocked: true,
+		},
+		{
+			limits:          DeletionLimits{MaxPercent: 50},
+			deletions:       5,
+			managedHooks:    10,
+			expectedBlocked: false,
+		},
+		{
+			limits:          DeletionLimits{MaxPercent: 50},
+			deletions:       6,
+			managedHooks:    10,
+			expectedBlocked: true,
+		},
+		{
+			limits:          DeletionLimits{MaxCount: 10, MaxPercent: 50},
+			deletions:       1,
+			managedHooks:    1,
+			expectedBlocked: false,
+		},
+		{
+			limits:          DeletionLimits{MaxCount: 10, MaxPercent: 50},
+			deletions:       2,
+			managedHooks:    2,
+			expectedBlocked: true,
+		},
+	}
+
+	for count, test := range tests {
+		err := test.limits.Check(test.deletions, test.managedHooks)
+		if test.expectedBlocked != IsMassDeletion(err) {
+			t.Errorf("Test[%d] Failed: Expected blocked %v for %d deletions out of %d hooks with limits %+v, but got %v", count, test.expectedBlocked, test.deletions, test.managedHooks, test.limits, err)
+		}
+	}
+}
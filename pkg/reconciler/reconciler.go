@@ -0,0 +1,263 @@
+package reconciler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/golang/glog"
+
+	utilerrors "k8s.io/kubernetes/pkg/util/errors"
+	"k8s.io/kubernetes/pkg/util/wait"
+)
+
+// Reconciler reconciles the desired hooks (from the BuildConfigs)
+// with the actual hooks (on GitHub), by creating, updating and deleting hooks
+type Reconciler struct {
+
+	// DesiredHooksFunc returns the hooks that should exist on GitHub,
+	// and the keys of the unmanaged hooks, that should be left untouched
+	DesiredHooksFunc func() ([]api.Hook, map[string]bool, error)
+
+	// ActualHooksFunc returns the hooks that currently exist on GitHub
+	// (only the ones that we manage)
+	ActualHooksFunc func() ([]api.Hook, error)
+
+	// KeyFunc is used to match the desired and actual hooks
+	KeyFunc KeyFunc
+
+	// HookHandlerFunc is the function that will apply each change:
+	// create (or update if the hook has an ID) enabled hooks, and delete disabled hooks
+	HookHandlerFunc func(api.Hook) (*api.Hook, bool, error)
+
+	// MaxRetries is the number of times a failed change will be retried
+	MaxRetries int
+
+	// RetryDelay is the delay before the first retry of a failed change
+	// (it is doubled on each retry)
+	RetryDelay time.Duration
+
+	// MaxRetryDelay is the maximum delay between 2 retries
+	// (0 or anything above maxApplyRetryDelay means maxApplyRetryDelay)
+	MaxRetryDelay time.Duration
+
+	// RetryableFunc returns true if a change that failed with the given error should be retried
+	// (optional - all the errors are retried if not set)
+	RetryableFunc func(err error) bool
+
+	// DeletionLimits defines the maximum number of hooks that a single reconciliation can delete
+	DeletionLimits DeletionLimits
+
+	// AllowMassDeletionFunc returns true if a mass deletion (that exceeds the DeletionLimits)
+	// has been explicitly allowed - it may consume a one-shot override (optional)
+	AllowMassDeletionFunc func(err error) bool
+
+	// MassDeletionBlockedFunc is called when a mass deletion has been blocked (optional)
+	MassDeletionBlockedFunc func(err error)
+
+	// triggers is used to request a reconciliation before the end of the period
+	triggers     chan struct{}
+	triggersOnce sync.Once
+
+	// stopChan interrupts the delays between the retries (nil when not running)
+	stopChan <-chan struct{}
+}
+
+// maxApplyRetryDelay is the maximum delay between 2 retries of a failed change:
+// the whole reconciliation waits for it, so the changes that keep failing
+// are better left to the next reconciliation
+const maxApplyRetryDelay = 10 * time.Second
+
+// errStopped is returned for a change that has not been applied because the reconciler has been stopped
+var errStopped = fmt.Errorf("Reconciler stopped")
+
+// blockedReconcileRetryPeriod is the interval at which a reconciliation blocked
+// by a mass deletion is retried, waiting for an explicit override
+const blockedReconcileRetryPeriod = 1 * time.Minute
+
+// RunUntil waits until readyFunc returns true, and then reconciles the hooks
+// every period (or just once if the period is 0), until stopChan is closed
+func (r *Reconciler) RunUntil(period time.Duration, readyFunc func() bool, stopChan <-chan struct{}) {
+	r.stopChan = stopChan
+
+	err := wait.PollInfinite(time.Second, func() (bool, error) {
+		select {
+		case <-stopChan:
+			return false, fmt.Errorf("stopped")
+		default:
+		}
+		return readyFunc(), nil
+	})
+	if err != nil {
+		return
+	}
+
+	for {
+		err := r.reconcileAndLog()
+		if period == 0 {
+			return
+		}
+
+		// a blocked reconciliation is retried sooner, to apply the override as soon as possible
+		next := period
+		if IsMassDeletion(err) && blockedReconcileRetryPeriod < period {
+			next = blockedReconcileRetryPeriod
+		}
+		select {
+		case <-stopChan:
+			return
+		case <-r.triggerChan():
+			glog.V(2).Infof("Reconciliation triggered before the end of the period")
+		case <-time.After(next):
+		}
+	}
+}
+
+// Trigger requests a reconciliation as soon as possible, without waiting for the end of the period.
+// It does not block, and multiple triggers before the next reconciliation are merged.
+func (r *Reconciler) Trigger() {
+	select {
+	case r.triggerChan() <- struct{}{}:
+	default:
+	}
+}
+
+// triggerChan returns the channel used to trigger a reconciliation
+func (r *Reconciler) triggerChan() chan struct{} {
+	r.triggersOnce.Do(func() {
+		r.triggers = make(chan struct{}, 1)
+	})
+	return r.triggers
+}
+
+// reconcileAndLog reconciles the hooks and logs the result
+func (r *Reconciler) reconcileAndLog() error {
+	plan, err := r.Reconcile()
+	if err != nil {
+		glog.Errorf("Failed to reconcile hooks: %v", err)
+		return err
+	}
+	glog.V(1).Infof("Reconciled hooks: %d created, %d updated, %d deleted", len(plan.Create), len(plan.Update), len(plan.Delete))
+	return nil
+}
+
+// Reconcile computes the plan to go from the actual hooks to the desired hooks, and applies it.
+// It returns the plan, and an aggregated error of all the changes that failed.
+func (r *Reconciler) Reconcile() (Plan, error) {
+	glog.V(2).Infof("Reconciling hooks ...")
+
+	plan, actual, err := r.Plan()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	if err := r.CheckDeletions(plan, actual); err != nil {
+		if r.AllowMassDeletionFunc != nil && r.AllowMassDeletionFunc(err) {
+			glog.Warningf("Mass deletion explicitly allowed: %v", err)
+			return plan, r.Apply(plan)
+		}
+
+		glog.Errorf("MASS DELETION BLOCKED: %v - the hooks will NOT be deleted until the mass deletion is explicitly allowed. Please check that the BuildConfigs can be listed, and that the OpenShift public URL is right.", err)
+		if r.MassDeletionBlockedFunc != nil {
+			r.MassDeletionBlockedFunc(err)
+		}
+
+		// still create/update the hooks, but don't delete anything
+		blockedPlan := Plan{Create: plan.Create, Update: plan.Update}
+		if applyErr := r.Apply(blockedPlan); applyErr != nil {
+			glog.Errorf("Failed to apply hooks changes: %v", applyErr)
+		}
+		return blockedPlan, err
+	}
+
+	return plan, r.Apply(plan)
+}
+
+// Plan computes the plan to go from the actual hooks to the desired hooks, without applying it.
+// It returns the plan, and the actual hooks it has been computed from.
+func (r *Reconciler) Plan() (Plan, []api.Hook, error) {
+	desired, unmanaged, err := r.DesiredHooksFunc()
+	if err != nil {
+		return Plan{}, nil, fmt.Errorf("Failed to retrieve the desired hooks: %v", err)
+	}
+	actual, err := r.ActualHooksFunc()
+	if err != nil {
+		return Plan{}, nil, fmt.Errorf("Failed to retrieve the actual hooks: %v", err)
+	}
+
+	plan := Diff(desired, actual, unmanaged, r.KeyFunc)
+	glog.V(3).Infof("Reconciliation plan for %d desired and %d actual hooks: %d to create, %d to update, %d to delete", len(desired), len(actual), len(plan.Create), len(plan.Update), len(plan.Delete))
+	return plan, actual, nil
+}
+
+// CheckDeletions returns a MassDeletionError if the given plan deletes more hooks than the DeletionLimits
+func (r *Reconciler) CheckDeletions(plan Plan, actual []api.Hook) error {
+	return r.DeletionLimits.Check(len(plan.Delete), countManagedHooks(actual, r.KeyFunc))
+}
+
+// Apply applies the given plan, retrying each failed change.
+// If a change still fails after all its retries, GitHub is most likely unavailable:
+// the remaining changes are skipped until the next reconciliation.
+// It returns an aggregated error of all the changes that failed.
+func (r *Reconciler) Apply(plan Plan) error {
+	changes := []api.Hook{}
+	for _, hooks := range [][]api.Hook{plan.Delete, plan.Update, plan.Create} {
+		changes = append(changes, hooks...)
+	}
+
+	errs := []error{}
+	for i, hook := range changes {
+		retryable, err := r.applyWithRetries(hook)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		if retryable {
+			if remaining := len(changes) - i - 1; remaining > 0 {
+				glog.Warningf("Skipping the %d remaining hooks changes until the next reconciliation: %v", remaining, err)
+				errs = append(errs, fmt.Errorf("Skipped %d hooks changes after a failure", remaining))
+			}
+			break
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// applyWithRetries applies a single change, and retries it (with an exponential delay) if it fails.
+// It returns true if the change failed with a retryable error,
+// but the retries have been exhausted or the reconciler has been stopped, and the error of the change.
+func (r *Reconciler) applyWithRetries(hook api.Hook) (bool, error) {
+	maxDelay := r.MaxRetryDelay
+	if maxDelay <= 0 || maxDelay > maxApplyRetryDelay {
+		maxDelay = maxApplyRetryDelay
+	}
+	delay := r.RetryDelay
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	for retries := 0; ; retries++ {
+		_, _, err := r.HookHandlerFunc(hook)
+		if err == nil {
+			return false, nil
+		}
+		if r.RetryableFunc != nil && !r.RetryableFunc(err) {
+			return false, fmt.Errorf("Failed to apply hook %s on repository %s: %v", hook.TargetURL, hook.GithubRepository, err)
+		}
+		if retries >= r.MaxRetries {
+			return true, fmt.Errorf("Failed to apply hook %s on repository %s after %d retries: %v", hook.TargetURL, hook.GithubRepository, retries, err)
+		}
+		glog.V(2).Infof("Failed to apply hook %s on repository %s - retrying in %v: %v", hook.TargetURL, hook.GithubRepository, delay, err)
+		select {
+		case <-r.stopChan:
+			return true, fmt.Errorf("Failed to apply hook %s on repository %s: %v (%v)", hook.TargetURL, hook.GithubRepository, errStopped, err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package reconciler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+)
+
+func TestReconcilerReconcile(t *testing.T) {
+	repo := api.GithubRepository{Owner: "owner", Name: "repo"}
+	handled := map[string]int{}
+
+	reconciler := &Reconciler{
+		KeyFunc:    testKeyFunc,
+		MaxRetries: 2,
+		RetryableFunc: func(err error) bool {
+			return err.Error() == "transient error"
+		},
+		DesiredHooksFunc: func() ([]api.Hook, map[string]bool, error) {
+			return []api.Hook{
+				{Enabled: true, TargetURL: "https://openshift/ns/ok/secret", GithubRepository: repo},
+				{Enabled: true, TargetURL: "https://openshift/ns/flaky/secret", GithubRepository: repo},
+				{Enabled: true, TargetURL: "https://openshift/ns/broken/secret", GithubRepository: repo},
+			}, nil, nil
+		},
+		ActualHooksFunc: func() ([]api.Hook, error) {
+			return []api.Hook{
+				{ID: 1, Enabled: true, TargetURL: "https://openshift/ns/orphan/secret", GithubRepository: repo},
+			}, nil
+		},
+		HookHandlerFunc: func(hook api.Hook) (*api.Hook, bool, error) {
+			handled[hook.TargetURL]++
+			switch {
+			case hook.TargetURL == "https://openshift/ns/flaky/secret" && handled[hook.TargetURL] < 2:
+				return nil, false, fmt.Errorf("transient error")
+			case hook.TargetURL == "https://openshift/ns/broken/secret":
+				return nil, false, fmt.Errorf("permanent error")
+			}
+			return &hook, true, nil
+		},
+	}
+
+	plan, err := reconciler.Reconcile()
+	if err == nil {
+		t.Errorf("Expected an error for the broken hook but got none")
+	}
+	if len(plan.Create) != 3 || len(plan.Delete) != 1 {
+		t.Errorf("Expected 3 hooks to create and 1 to delete, but got %+v", plan)
+	}
+
+	expectedHandled := map[string]int{
+		"https://openshift/ns/ok/secret":     1,
+		"https://openshift/ns/flaky/secret":  2,
+		"https://openshift/ns/broken/secret": 1,
+		"https://openshift/ns/orphan/secret": 1,
+	}
+	for url, expectedCount := range expectedHandled {
+		if handled[url] != expectedCount {
+			t.Errorf("Expected hook %s to be handled %d times, but got %d", url, expectedCount, handled[url])
+		}
+	}
+}
+
+func TestReconcilerReconcileFailsWithoutActualHooks(t *testing.T) {
+	reconciler := &Reconciler{
+		KeyFunc: testKeyFunc,
+		DesiredHooksFunc: func() ([]api.Hook, map[string]bool, error) {
+			return []api.Hook{}, nil, nil
+		},
+		ActualHooksFunc: func() ([]api.Hook, error) {
+			return nil, fmt.Errorf("github is down")
+		},
+		HookHandlerFunc: func(hook api.Hook) (*api.Hook, bool, error) {
+			t.Errorf("Unexpected call to the hook handler for %+v", hook)
+			return nil, false, nil
+		},
+	}
+
+	if _, err := reconciler.Reconcile(); err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+}
+
+func TestReconcilerReconcileLeavesUnmanagedHooks(t *testing.T) {
+	repo := api.GithubRepository{Owner: "owner", Name: "repo"}
+	handled := map[string]bool{}
+
+	reconciler := &Reconciler{
+		KeyFunc: testKeyFunc,
+		DesiredHooksFunc: func() ([]api.Hook, map[string]bool, error) {
+			// the "ns/ignored" BC exists, but is ignored
+			return []api.Hook{}, map[string]bool{"ns/ignored": true}, nil
+		},
+		ActualHooksFunc: func() ([]api.Hook, error) {
+			return []api.Hook{
+				{ID: 1, Enabled: true, TargetURL: "https://openshift/ns/ignored/secret", GithubRepository: repo},
+				{ID: 2, Enabled: true, TargetURL: "https://openshift/ns/deleted/secret", GithubRepository: repo},
+			}, nil
+		},
+		HookHandlerFunc: func(hook api.Hook) (*api.Hook, bool, error) {
+			handled[hook.TargetURL] = true
+			return nil, true, nil
+		},
+	}
+
+	plan, err := reconciler.Reconcile()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(plan.Create) != 0 || len(plan.Update) != 0 || len(plan.Delete) != 1 || plan.Delete[0].ID != 2 {
+		t.Errorf("Expected only the hook of the deleted BC to be deleted, but got %+v", plan)
+	}
+	if handled["https://openshift/ns/ignored/secret"] {
+		t.Errorf("Expected the hook of the ignored BC to be left untouched")
+	}
+	if !handled["https://openshift/ns/deleted/secret"] {
+		t.Errorf("Expected the hook of the deleted BC to be deleted")
+	}
+}
+
+func TestReconcilerReconcileBlocksMassDeletion(t *testing.T) {
+	repo := api.GithubRepository{Owner: "owner", Name: "repo"}
+
+	for _, allowed := range []bool{false, true} {
+		handled := map[string]bool{}
+		blocked := false
+
+		reconciler := &Reconciler{
+			KeyFunc:        testKeyFunc,
+			DeletionLimits: DeletionLimits{MaxCount: 1},
+			DesiredHooksFunc: func() ([]api.Hook, map[string]bool, error) {
+				return []api.Hook{
+					{Enabled: true, TargetURL: "https://openshift/ns/new/secret", GithubRepository: repo},
+				}, nil, nil
+			},
+			ActualHooksFunc: func() ([]api.Hook, error) {
+				return []api.Hook{
+					{ID: 1, Enabled: true, TargetURL: "https://openshift/ns/a/secret", GithubRepository: repo},
+					{ID: 2, Enabled: true, TargetURL: "https://openshift/ns/b/secret", GithubRepository: repo},
+				}, nil
+			},
+			HookHandlerFunc: func(hook api.Hook) (*api.Hook, bool, error) {
+				handled[hook.TargetURL] = true
+				return &hook, true, nil
+			},
+			AllowMassDeletionFunc: func(err error) bool {
+				return allowed
+			},
+			MassDeletionBlockedFunc: func(err error) {
+				blocked = true
+			},
+		}
+
+		_, err := reconciler.Reconcile()
+		if allowed == IsMassDeletion(err) {
+			t.Errorf("Expected mass deletion error %v when allowed is %v, but got %v", !allowed, allowed, err)
+		}
+		if allowed == blocked {
+			t.Errorf("Expected blocked %v when allowed is %v", !allowed, allowed)
+		}
+		if !handled["https://openshift/ns/new/secret"] {
+			t.Errorf("Expected the new hook to be created when allowed is %v", allowed)
+		}
+		if allowed != handled["https://openshift/ns/a/secret"] || allowed != handled["https://openshift/ns/b/secret"] {
+			t.Errorf("Expected the orphan hooks to be deleted only when allowed, but got %+v when allowed is %v", handled, allowed)
+		}
+	}
+}
+
+func TestReconcilerApplyStopsAfterExhaustedRetries(t *testing.T) {
+	repo := api.GithubRepository{Owner: "owner", Name: "repo"}
+	handled := map[string]int{}
+
+	reconciler := &Reconciler{
+		KeyFunc:    testKeyFunc,
+		MaxRetries: 1,
+		RetryableFunc: func(err error) bool {
+			return err.Error() == "github is down"
+		},
+		HookHandlerFunc: func(hook api.Hook) (*api.Hook, bool, error) {
+			handled[hook.TargetURL]++
+			switch hook.TargetURL {
+			case "https://openshift/ns/rejected/secret":
+				return nil, false, fmt.Errorf("hook rejected")
+			case "https://openshift/ns/down/secret":
+				return nil, false, fmt.Errorf("github is down")
+			}
+			return &hook, true, nil
+		},
+	}
+
+	err := reconciler.Apply(Plan{Create: []api.Hook{
+		{Enabled: true, TargetURL: "https://openshift/ns/rejected/secret", GithubRepository: repo},
+		{Enabled: true, TargetURL: "https://openshift/ns/ok/secret", GithubRepository: repo},
+		{Enabled: true, TargetURL: "https://openshift/ns/down/secret", GithubRepository: repo},
+		{Enabled: true, TargetURL: "https://openshift/ns/skipped/secret", GithubRepository: repo},
+	}})
+	if err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+
+	expectedHandled := map[string]int{
+		"https://openshift/ns/rejected/secret": 1,
+		"https://openshift/ns/ok/secret":       1,
+		"https://openshift/ns/down/secret":     2,
+		"https://openshift/ns/skipped/secret":  0,
+	}
+	for url, expectedCount := range expectedHandled {
+		if handled[url] != expectedCount {
+			t.Errorf("Expected hook %s to be handled %d times, but got %d", url, expectedCount, handled[url])
+		}
+	}
+}
+
+func TestReconcilerApplyRetriesHonourStop(t *testing.T) {
+	stopChan := make(chan struct{})
+	close(stopChan)
+	handled := 0
+
+	reconciler := &Reconciler{
+		KeyFunc:    testKeyFunc,
+		MaxRetries: 5,
+		RetryDelay: time.Hour,
+		HookHandlerFunc: func(hook api.Hook) (*api.Hook, bool, error) {
+			handled++
+			return nil, false, fmt.Errorf("github is down")
+		},
+		stopChan: stopChan,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- reconciler.Apply(Plan{Create: []api.Hook{
+			{Enabled: true, TargetURL: "https://openshift/ns/a/secret"},
+			{Enabled: true, TargetURL: "https://openshift/ns/b/secret"},
+		}})
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Expected an error but got none")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the retries to be interrupted by the stop channel")
+	}
+	if handled != 1 {
+		t.Errorf("Expected a single attempt before the stop, but got %d", handled)
+	}
+}
+
+func TestReconcilerTrigger(t *testing.T) {
+	reconciled := make(chan struct{}, 10)
+	reconciler := &Reconciler{
+		KeyFunc: testKeyFunc,
+		DesiredHooksFunc: func() ([]api.Hook, map[string]bool, error) {
+			reconciled <- struct{}{}
+			return []api.Hook{}, nil, nil
+		},
+		ActualHooksFunc: func() ([]api.Hook, error) {
+			return []api.Hook{}, nil
+		},
+	}
+
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	go reconciler.RunUntil(time.Hour, func() bool { return true }, stopChan)
+
+	for count := 0; count < 2; count++ {
+		select {
+		case <-reconciled:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Test[%d] Failed: Expected a reconciliation", count)
+		}
+		reconciler.Trigger()
+	}
+}
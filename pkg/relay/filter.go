@@ -0,0 +1,125 @@
+package relay
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	"github.com/golang/glog"
+	buildapi "github.com/openshift/origin/pkg/build/api"
+)
+
+// pushPayload is the part of a GitHub push event payload used to filter the pushes
+// see https://developer.github.com/v3/activity/events/types/#pushevent
+type pushPayload struct {
+	// Size is the number of commits in the push (the Commits list is capped at 20 commits)
+	Size    *int         `json:"size"`
+	Commits []pushCommit `json:"commits"`
+}
+
+// pushCommit is a commit of a GitHub push event payload
+type pushCommit struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// pathFilter returns the path globs used to filter the pushes for the given BC,
+// and false if the BC did not opt in for the filtering.
+// The globs come from the paths annotation, or from the contextDir if the filter-paths annotation is set.
+func pathFilter(bc *buildapi.BuildConfig) ([]string, bool) {
+	if paths := strings.TrimSpace(bc.Annotations[api.PathsAnnotation]); len(paths) > 0 {
+		globs := []string{}
+		for _, glob := range strings.Split(paths, ",") {
+			if glob = strings.TrimSpace(glob); len(glob) > 0 {
+				globs = append(globs, glob)
+			}
+		}
+		return globs, len(globs) > 0
+	}
+
+	filterStr, found := bc.Annotations[api.FilterPathsAnnotation]
+	if !found {
+		return nil, false
+	}
+	filter, err := strconv.ParseBool(filterStr)
+	if err != nil {
+		glog.Errorf("Failed to parse annotation value '%v' for %s on BC %s/%s: %v", filterStr, api.FilterPathsAnnotation, bc.Namespace, bc.Name, err)
+		return nil, false
+	}
+	contextDir := strings.Trim(bc.Spec.Source.ContextDir, "/")
+	if !filter || len(contextDir) == 0 {
+		return nil, false
+	}
+	return []string{contextDir}, true
+}
+
+// changedFiles returns the files changed by the given push payload,
+// and false if they can't be determined (the payload can't be parsed, or the commits list is truncated)
+func changedFiles(payload []byte) ([]string, bool) {
+	push := &pushPayload{}
+	if err := json.Unmarshal(payload, push); err != nil {
+		glog.V(3).Infof("Failed to parse the push payload: %v", err)
+		return nil, false
+	}
+	if len(push.Commits) == 0 || (push.Size != nil && *push.Size > len(push.Commits)) {
+		return nil, false
+	}
+
+	files := []string{}
+	for _, commit := range push.Commits {
+		files = append(files, commit.Added...)
+		files = append(files, commit.Removed...)
+		files = append(files, commit.Modified...)
+	}
+	return files, true
+}
+
+// matchesAny checks if any of the given files matches any of the given globs
+func matchesAny(files []string, globs []string) bool {
+	for _, glob := range globs {
+		for _, file := range files {
+			if matchGlob(glob, file) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlob checks if the given file matches the given glob:
+// a glob without wildcards matches the file itself, or the files in the directory;
+// "*" matches any sequence of characters except "/", "**" matches any sequence of characters,
+// and "?" matches any single character except "/"
+func matchGlob(glob string, file string) bool {
+	glob = strings.Trim(glob, "/")
+	if !strings.ContainsAny(glob, "*?") {
+		return file == glob || strings.HasPrefix(file, glob+"/")
+	}
+
+	expr := "^"
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			expr += ".*"
+			i++
+		case glob[i] == '*':
+			expr += "[^/]*"
+		case glob[i] == '?':
+			expr += "[^/]"
+		default:
+			expr += regexp.QuoteMeta(string(glob[i]))
+		}
+	}
+	expr += "$"
+
+	matched, err := regexp.MatchString(expr, file)
+	if err != nil {
+		glog.Warningf("Invalid path glob %s: %v", glob, err)
+		return false
+	}
+	return matched
+}
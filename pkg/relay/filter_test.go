@@ -0,0 +1,142 @@
+package relay
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+
+	buildapi "github.com/openshift/origin/pkg/build/api"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+)
+
+func TestPathFilter(t *testing.T) {
+	tests := []struct {
+		annotations     map[string]string
+		contextDir      string
+		expectedGlobs   []string
+		expectedEnabled bool
+	}{
+		{
+			contextDir:      "app",
+			expectedEnabled: false,
+		},
+		{
+			annotations:     map[string]string{api.FilterPathsAnnotation: "true"},
+			contextDir:      "/services/app/",
+			expectedGlobs:   []string{"services/app"},
+			expectedEnabled: true,
+		},
+		{
+			annotations:     map[string]string{api.FilterPathsAnnotation: "false"},
+			contextDir:      "app",
+			expectedEnabled: false,
+		},
+		{
+			annotations:     map[string]string{api.FilterPathsAnnotation: "true"},
+			expectedEnabled: false,
+		},
+		{
+			annotations:     map[string]string{api.FilterPathsAnnotation: "invalid"},
+			contextDir:      "app",
+			expectedEnabled: false,
+		},
+		{
+			annotations:     map[string]string{api.PathsAnnotation: "app/**, lib/*.go,"},
+			contextDir:      "app",
+			expectedGlobs:   []string{"app/**", "lib/*.go"},
+			expectedEnabled: true,
+		},
+	}
+
+	for count, test := range tests {
+		bc := &buildapi.BuildConfig{
+			ObjectMeta: kapi.ObjectMeta{Annotations: test.annotations},
+			Spec: buildapi.BuildConfigSpec{
+				BuildSpec: buildapi.BuildSpec{
+					Source: buildapi.BuildSource{ContextDir: test.contextDir},
+				},
+			},
+		}
+		globs, enabled := pathFilter(bc)
+		if enabled != test.expectedEnabled {
+			t.Errorf("Test[%d] Failed: Expected enabled '%v' but got '%v'", count, test.expectedEnabled, enabled)
+		}
+		if enabled && !reflect.DeepEqual(globs, test.expectedGlobs) {
+			t.Errorf("Test[%d] Failed: Expected globs %v but got %v", count, test.expectedGlobs, globs)
+		}
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	tests := []struct {
+		payload       string
+		expectedFiles []string
+		expectedOK    bool
+	}{
+		{
+			payload:       `{"size": 2, "commits": [{"added": ["a"], "modified": ["b"]}, {"removed": ["c"]}]}`,
+			expectedFiles: []string{"a", "b", "c"},
+			expectedOK:    true,
+		},
+		{
+			payload:    `{"size": 25, "commits": [{"added": ["a"]}]}`,
+			expectedOK: false,
+		},
+		{
+			payload:    `{"size": 0, "commits": []}`,
+			expectedOK: false,
+		},
+		{
+			payload:    `payload=...`,
+			expectedOK: false,
+		},
+	}
+
+	for count, test := range tests {
+		files, ok := changedFiles([]byte(test.payload))
+		if ok != test.expectedOK {
+			t.Errorf("Test[%d] Failed: Expected '%v' but got '%v'", count, test.expectedOK, ok)
+		}
+		if ok && !reflect.DeepEqual(files, test.expectedFiles) {
+			t.Errorf("Test[%d] Failed: Expected files %v but got %v", count, test.expectedFiles, files)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob           string
+		file           string
+		expectedResult bool
+	}{
+		{glob: "app", file: "app/main.go", expectedResult: true},
+		{glob: "app/", file: "app/sub/main.go", expectedResult: true},
+		{glob: "app", file: "app", expectedResult: true},
+		{glob: "app", file: "application/main.go", expectedResult: false},
+		{glob: "app/*.go", file: "app/main.go", expectedResult: true},
+		{glob: "app/*.go", file: "app/sub/main.go", expectedResult: false},
+		{glob: "app/**", file: "app/sub/main.go", expectedResult: true},
+		{glob: "**/*.md", file: "docs/README.md", expectedResult: true},
+		{glob: "app/?.go", file: "app/a.go", expectedResult: true},
+		{glob: "app/?.go", file: "app/ab.go", expectedResult: false},
+		{glob: "lib/(x)*.go", file: "lib/(x)y.go", expectedResult: true},
+	}
+
+	for count, test := range tests {
+		result := matchGlob(test.glob, test.file)
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%v' for %s on %s but got '%v'", count, test.expectedResult, test.glob, test.file, result)
+		}
+	}
+}
+
+func TestMatchesAny(t *testing.T) {
+	if !matchesAny([]string{"README.md", "app/main.go"}, []string{"lib", "app"}) {
+		t.Errorf("Expected app/main.go to match")
+	}
+	if matchesAny([]string{"README.md", "docs/index.md"}, []string{"lib", "app"}) {
+		t.Errorf("Expected no match")
+	}
+}
@@ -0,0 +1,223 @@
+package relay
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+
+	"github.com/golang/glog"
+	buildapi "github.com/openshift/origin/pkg/build/api"
+	"github.com/openshift/origin/pkg/client"
+
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+)
+
+const (
+	// DefaultMaxPayloadSize is the maximum size of a GitHub delivery payload
+	// (GitHub caps the payloads at 25 MB)
+	DefaultMaxPayloadSize = 25 * 1024 * 1024
+
+	// DefaultForwardTimeout is the default timeout of a forwarded delivery
+	DefaultForwardTimeout = 30 * time.Second
+)
+
+// forwardedHeaders are the headers of the GitHub deliveries forwarded to OpenShift
+var forwardedHeaders = []string{"Content-Type", "User-Agent", "X-GitHub-Event", "X-GitHub-Delivery"}
+
+// Server is an http.Handler that relays the GitHub deliveries to the internal OpenShift webhooks.
+// The hooks target the relay with an URL that does not contain the BuildConfig trigger secret
+// (see openshift.RelayWebhookURL), and their deliveries are signed with a secret derived from the relay key
+// (see openshift.RelayHookSecret). The deliveries with an invalid signature are rejected,
+// the others are forwarded to the BuildConfig's webhook URL, built with the current trigger secret.
+// The pushes can also be filtered by path, for the BuildConfigs that opted in (see pathFilter):
+// a push that does not change any matching file is acknowledged, but not forwarded.
+type Server struct {
+	// Key is the relay key, used to derive the secret of each hook
+	Key []byte
+
+	// BuildConfigsNamespacer is used to get the BCs and build their internal webhook URL
+	BuildConfigsNamespacer client.BuildConfigsNamespacer
+
+	// SecretReferenceFunc returns the webhook secret referenced by the GitHub trigger of the given BC,
+	// when the trigger references a Secret object instead of setting its secret inline
+	// (optional - the BCs without an inline secret are not relayed if not set)
+	SecretReferenceFunc func(bc *buildapi.BuildConfig) (string, error)
+
+	// Transport is used to forward the deliveries to OpenShift (optional - default to http.DefaultTransport)
+	Transport http.RoundTripper
+
+	// ForwardTimeout is the timeout of a forwarded delivery (optional - default to DefaultForwardTimeout)
+	ForwardTimeout time.Duration
+
+	// MaxPayloadSize is the maximum size of a delivery payload (optional - default to DefaultMaxPayloadSize)
+	MaxPayloadSize int64
+}
+
+// ServeHTTP is for the http.Handler implementation
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Only POST requests are accepted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, name := openshift.ExplodeRelayWebhookURL(r.URL.Path)
+	if len(namespace) == 0 || len(name) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	maxPayloadSize := s.MaxPayloadSize
+	if maxPayloadSize <= 0 {
+		maxPayloadSize = DefaultMaxPayloadSize
+	}
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPayloadSize+1))
+	if err != nil {
+		glog.Warningf("Failed to read the delivery for BC %s/%s: %v", namespace, name, err)
+		http.Error(w, "Failed to read the payload", http.StatusBadRequest)
+		return
+	}
+	if int64(len(payload)) > maxPayloadSize {
+		http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	secret := openshift.RelayHookSecret(s.Key, namespace, name)
+	if !ValidSignature(r.Header, payload, secret) {
+		glog.Warningf("Rejecting delivery %s for BC %s/%s from %s: invalid signature", r.Header.Get("X-GitHub-Delivery"), namespace, name, r.RemoteAddr)
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+
+	bc, targetURL, err := s.webhook(namespace, name)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			glog.V(2).Infof("Rejecting delivery %s for BC %s/%s: %v", r.Header.Get("X-GitHub-Delivery"), namespace, name, err)
+			http.NotFound(w, r)
+			return
+		}
+		glog.Errorf("Failed to get the webhook URL of BC %s/%s: %v", namespace, name, err)
+		http.Error(w, "Failed to get the webhook URL", http.StatusBadGateway)
+		return
+	}
+
+	if r.Header.Get("X-GitHub-Event") == "push" {
+		if globs, enabled := pathFilter(bc); enabled {
+			if files, ok := changedFiles(payload); ok && !matchesAny(files, globs) {
+				glog.V(2).Infof("Skipping delivery %s for BC %s/%s: none of the %d changed files matches %v", r.Header.Get("X-GitHub-Delivery"), namespace, name, len(files), globs)
+				fmt.Fprintf(w, "Skipped: no changed files matching %s", strings.Join(globs, ","))
+				return
+			}
+		}
+	}
+
+	s.forward(w, r, targetURL, payload, namespace, name)
+}
+
+// webhook returns the given BC, and its internal webhook URL, built with its current trigger secret
+func (s *Server) webhook(namespace, name string) (*buildapi.BuildConfig, string, error) {
+	bc, err := s.BuildConfigsNamespacer.BuildConfigs(namespace).Get(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, trigger := range bc.Spec.Triggers {
+		if trigger.Type != buildapi.GitHubWebHookBuildTriggerType || trigger.GitHubWebHook == nil {
+			continue
+		}
+		if len(trigger.GitHubWebHook.Secret) == 0 {
+			if s.SecretReferenceFunc == nil {
+				continue
+			}
+			// the secret may be referenced from a Secret object, unknown to the OpenShift client
+			secret, err := s.SecretReferenceFunc(bc)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(secret) == 0 {
+				continue
+			}
+			trigger.GitHubWebHook = &buildapi.WebHookTrigger{Secret: secret}
+		}
+		webhookURL, err := s.BuildConfigsNamespacer.BuildConfigs(namespace).WebHookURL(name, &trigger)
+		if err != nil {
+			return nil, "", err
+		}
+		return bc, webhookURL.String(), nil
+	}
+	return nil, "", kerrors.NewNotFound(buildapi.Resource("buildconfigs/webhooks"), fmt.Sprintf("%s/github", name))
+}
+
+// forward forwards the given delivery to the given target URL,
+// and writes the OpenShift response
+func (s *Server) forward(w http.ResponseWriter, r *http.Request, targetURL string, payload []byte, namespace, name string) {
+	req, err := http.NewRequest("POST", targetURL, bytes.NewReader(payload))
+	if err != nil {
+		glog.Errorf("Failed to build the request for BC %s/%s: %v", namespace, name, err)
+		http.Error(w, "Failed to forward the delivery", http.StatusInternalServerError)
+		return
+	}
+	for _, header := range forwardedHeaders {
+		if value := r.Header.Get(header); len(value) > 0 {
+			req.Header.Set(header, value)
+		}
+	}
+
+	timeout := s.ForwardTimeout
+	if timeout <= 0 {
+		timeout = DefaultForwardTimeout
+	}
+	httpClient := &http.Client{
+		Transport: s.Transport,
+		Timeout:   timeout,
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		glog.Errorf("Failed to forward delivery %s to BC %s/%s: %v", r.Header.Get("X-GitHub-Delivery"), namespace, name, err)
+		http.Error(w, "Failed to forward the delivery", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	glog.V(2).Infof("Forwarded delivery %s (%s event) to BC %s/%s: %s", r.Header.Get("X-GitHub-Delivery"), r.Header.Get("X-GitHub-Event"), namespace, name, resp.Status)
+	if contentType := resp.Header.Get("Content-Type"); len(contentType) > 0 {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// ValidSignature checks the signature of the given payload, signed by GitHub with the given secret.
+// It uses the X-Hub-Signature-256 header (HMAC SHA-256) if present, or the X-Hub-Signature header (HMAC SHA-1).
+func ValidSignature(header http.Header, payload []byte, secret string) bool {
+	if signature := header.Get("X-Hub-Signature-256"); len(signature) > 0 {
+		return validSignature(signature, "sha256=", sha256.New, payload, secret)
+	}
+	return validSignature(header.Get("X-Hub-Signature"), "sha1=", sha1.New, payload, secret)
+}
+
+// validSignature checks the given signature (hex-encoded, with the given prefix)
+// against the HMAC of the payload
+func validSignature(signature, prefix string, hashFunc func() hash.Hash, payload []byte, secret string) bool {
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	actual, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(hashFunc, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(actual, mac.Sum(nil))
+}
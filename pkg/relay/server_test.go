@@ -0,0 +1,254 @@
+package relay
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/vbehar/openshift-github-hooks/pkg/api"
+	"github.com/vbehar/openshift-github-hooks/pkg/openshift"
+
+	buildapi "github.com/openshift/origin/pkg/build/api"
+	"github.com/openshift/origin/pkg/client"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrors "k8s.io/kubernetes/pkg/api/errors"
+)
+
+// fakeBuildConfigs is a client.BuildConfigsNamespacer that only knows how to get BCs
+// and build their webhook URLs (targeting the given base URL)
+type fakeBuildConfigs struct {
+	client.BuildConfigInterface
+	baseURL      string
+	namespace    string
+	buildConfigs map[string]*buildapi.BuildConfig
+}
+
+func (f *fakeBuildConfigs) BuildConfigs(namespace string) client.BuildConfigInterface {
+	namespaced := *f
+	namespaced.namespace = namespace
+	return &namespaced
+}
+
+func (f *fakeBuildConfigs) Get(name string) (*buildapi.BuildConfig, error) {
+	if bc, found := f.buildConfigs[f.namespace+"/"+name]; found {
+		return bc, nil
+	}
+	return nil, kerrors.NewNotFound(buildapi.Resource("buildconfigs"), name)
+}
+
+func (f *fakeBuildConfigs) WebHookURL(name string, trigger *buildapi.BuildTriggerPolicy) (*url.URL, error) {
+	return url.Parse(f.baseURL + "/oapi/v1/namespaces/" + f.namespace + "/buildconfigs/" + name + "/webhooks/" + trigger.GitHubWebHook.Secret + "/github")
+}
+
+func TestServer(t *testing.T) {
+	var forwardedPath, forwardedEvent string
+	openshiftServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedPath = r.URL.Path
+		forwardedEvent = r.Header.Get("X-GitHub-Event")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer openshiftServer.Close()
+
+	key := []byte("relay-key")
+	server := &Server{
+		Key: key,
+		BuildConfigsNamespacer: &fakeBuildConfigs{
+			baseURL: openshiftServer.URL,
+			buildConfigs: map[string]*buildapi.BuildConfig{
+				"ns/bc": {
+					ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "bc"},
+					Spec: buildapi.BuildConfigSpec{
+						Triggers: []buildapi.BuildTriggerPolicy{
+							{
+								Type:          buildapi.GitHubWebHookBuildTriggerType,
+								GitHubWebHook: &buildapi.WebHookTrigger{Secret: "trigger-secret"},
+							},
+						},
+					},
+				},
+				"ns/filtered": {
+					ObjectMeta: kapi.ObjectMeta{
+						Namespace:   "ns",
+						Name:        "filtered",
+						Annotations: map[string]string{api.FilterPathsAnnotation: "true"},
+					},
+					Spec: buildapi.BuildConfigSpec{
+						BuildSpec: buildapi.BuildSpec{
+							Source: buildapi.BuildSource{ContextDir: "other"},
+						},
+						Triggers: []buildapi.BuildTriggerPolicy{
+							{
+								Type:          buildapi.GitHubWebHookBuildTriggerType,
+								GitHubWebHook: &buildapi.WebHookTrigger{Secret: "trigger-secret"},
+							},
+						},
+					},
+				},
+				"ns/nosecret": {
+					ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "nosecret"},
+				},
+				"ns/referenced": {
+					ObjectMeta: kapi.ObjectMeta{Namespace: "ns", Name: "referenced"},
+					Spec: buildapi.BuildConfigSpec{
+						Triggers: []buildapi.BuildTriggerPolicy{
+							{
+								Type:          buildapi.GitHubWebHookBuildTriggerType,
+								GitHubWebHook: &buildapi.WebHookTrigger{},
+							},
+						},
+					},
+				},
+			},
+		},
+		SecretReferenceFunc: func(bc *buildapi.BuildConfig) (string, error) {
+			if bc.Name == "referenced" {
+				return "referenced-secret", nil
+			}
+			return "", nil
+		},
+	}
+
+	payload := []byte(`{"ref": "refs/heads/master", "size": 1, "commits": [{"modified": ["app/main.go"]}]}`)
+	signature := func(namespace, name string) string {
+		mac := hmac.New(sha1.New, []byte(openshift.RelayHookSecret(key, namespace, name)))
+		mac.Write(payload)
+		return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		method               string
+		path                 string
+		signature            string
+		expectedStatus       int
+		expectedForwardedURL string
+	}{
+		{
+			method:               "POST",
+			path:                 "/namespaces/ns/buildconfigs/bc/github",
+			signature:            signature("ns", "bc"),
+			expectedStatus:       http.StatusOK,
+			expectedForwardedURL: "/oapi/v1/namespaces/ns/buildconfigs/bc/webhooks/trigger-secret/github",
+		},
+		{
+			method:               "POST",
+			path:                 "/namespaces/ns/buildconfigs/referenced/github",
+			signature:            signature("ns", "referenced"),
+			expectedStatus:       http.StatusOK,
+			expectedForwardedURL: "/oapi/v1/namespaces/ns/buildconfigs/referenced/webhooks/referenced-secret/github",
+		},
+		{
+			method:         "POST",
+			path:           "/namespaces/ns/buildconfigs/filtered/github",
+			signature:      signature("ns", "filtered"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			method:         "GET",
+			path:           "/namespaces/ns/buildconfigs/bc/github",
+			signature:      signature("ns", "bc"),
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			method:         "POST",
+			path:           "/some/path",
+			signature:      signature("ns", "bc"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			method:         "POST",
+			path:           "/namespaces/ns/buildconfigs/bc/github",
+			signature:      signature("ns", "other"),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			method:         "POST",
+			path:           "/namespaces/ns/buildconfigs/bc/github",
+			signature:      "",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			method:         "POST",
+			path:           "/namespaces/ns/buildconfigs/unknown/github",
+			signature:      signature("ns", "unknown"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			method:         "POST",
+			path:           "/namespaces/ns/buildconfigs/nosecret/github",
+			signature:      signature("ns", "nosecret"),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for count, test := range tests {
+		forwardedPath, forwardedEvent = "", ""
+		req, err := http.NewRequest(test.method, "http://relay"+test.path, bytes.NewReader(payload))
+		if err != nil {
+			t.Fatalf("Test[%d] Failed: got unexpected error %v", count, err)
+		}
+		req.Header.Set("X-Hub-Signature", test.signature)
+		req.Header.Set("X-GitHub-Event", "push")
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != test.expectedStatus {
+			body, _ := ioutil.ReadAll(w.Body)
+			t.Errorf("Test[%d] Failed: Expected status %d but got %d (%s)", count, test.expectedStatus, w.Code, body)
+		}
+		if forwardedPath != test.expectedForwardedURL {
+			t.Errorf("Test[%d] Failed: Expected the delivery to be forwarded to '%s' but got '%s'", count, test.expectedForwardedURL, forwardedPath)
+		}
+		if len(test.expectedForwardedURL) > 0 && forwardedEvent != "push" {
+			t.Errorf("Test[%d] Failed: Expected the event header to be forwarded, but got '%s'", count, forwardedEvent)
+		}
+	}
+}
+
+func TestValidSignature(t *testing.T) {
+	payload := []byte("payload")
+	sha1Mac := hmac.New(sha1.New, []byte("secret"))
+	sha1Mac.Write(payload)
+	sha256Mac := hmac.New(sha256.New, []byte("secret"))
+	sha256Mac.Write(payload)
+
+	tests := []struct {
+		header         http.Header
+		expectedResult bool
+	}{
+		{
+			header:         http.Header{"X-Hub-Signature": {"sha1=" + hex.EncodeToString(sha1Mac.Sum(nil))}},
+			expectedResult: true,
+		},
+		{
+			header:         http.Header{"X-Hub-Signature-256": {"sha256=" + hex.EncodeToString(sha256Mac.Sum(nil))}},
+			expectedResult: true,
+		},
+		{
+			header:         http.Header{"X-Hub-Signature": {"sha256=" + hex.EncodeToString(sha256Mac.Sum(nil))}},
+			expectedResult: false,
+		},
+		{
+			header:         http.Header{"X-Hub-Signature": {"sha1=zz"}},
+			expectedResult: false,
+		},
+		{
+			header:         http.Header{},
+			expectedResult: false,
+		},
+	}
+
+	for count, test := range tests {
+		result := ValidSignature(test.header, payload, "secret")
+		if result != test.expectedResult {
+			t.Errorf("Test[%d] Failed: Expected '%v' but got '%v'", count, test.expectedResult, result)
+		}
+	}
+}